@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// errAvroValue is returned by transform when, with -strict, a value does
+// not fit the type of its -avro field. The value has already been
+// reported.
+var errAvroValue = errors.New("value does not fit the avro schema")
+
+// avroBlockRows is the number of records written per Object Container File
+// block.
+const avroBlockRows = 1000
+
+// avroField is a field of an -avro record schema, with the primitive type
+// that values of its column are converted to.
+type avroField struct {
+	name     string
+	typ      string
+	nullable bool
+}
+
+// avroWriter encodes rows as records of an Avro schema into an Object
+// Container File. Fields are filled from the columns of the same name, or
+// in order without a header.
+type avroWriter struct {
+	codec   *goavro.Codec
+	fields  []avroField
+	index   []int
+	ocf     *goavro.OCFWriter
+	pending []interface{}
+	skipped int
+}
+
+func newAvroWriter(schemaFile string) (*avroWriter, error) {
+	b, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodec(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", schemaFile, err)
+	}
+	var schema struct {
+		Type   interface{} `json:"type"`
+		Fields []struct {
+			Name string      `json:"name"`
+			Type interface{} `json:"type"`
+		} `json:"fields"`
+	}
+	// the codec has checked the schema, so it is valid JSON
+	if err := json.Unmarshal(b, &schema); err != nil || schema.Type != "record" {
+		return nil, fmt.Errorf("%s: the schema must be a record", schemaFile)
+	}
+
+	a := &avroWriter{codec: codec}
+	for _, f := range schema.Fields {
+		field, ok := parseAvroType(f.Name, f.Type)
+		if !ok {
+			return nil, fmt.Errorf("%s: field %q: only primitive types and their union with null are supported", schemaFile, f.Name)
+		}
+		a.fields = append(a.fields, field)
+		a.index = append(a.index, len(a.index))
+	}
+	return a, nil
+}
+
+// parseAvroType reads a primitive type, possibly with a logical type, or a
+// union of null and a primitive type.
+func parseAvroType(name string, t interface{}) (avroField, bool) {
+	switch t := t.(type) {
+	case string:
+		switch t {
+		case "boolean", "int", "long", "float", "double", "string", "bytes":
+			return avroField{name: name, typ: t}, true
+		}
+	case map[string]interface{}:
+		return parseAvroType(name, t["type"])
+	case []interface{}:
+		if len(t) != 2 {
+			break
+		}
+		for i, branch := range t {
+			if branch == "null" {
+				f, ok := parseAvroType(name, t[1-i])
+				f.nullable = true
+				return f, ok
+			}
+		}
+	}
+	return avroField{}, false
+}
+
+// start writes the container file header to w.
+func (a *avroWriter) start(w io.Writer) error {
+	var err error
+	a.ocf, err = goavro.NewOCFWriter(goavro.OCFConfig{W: w, Codec: a.codec})
+	return err
+}
+
+// add converts a row into a record. A value that does not fit its field is
+// reported and the row skipped, or with strict the input stopped.
+func (a *avroWriter) add(name string, record []string, isHeader bool, line int, diag *diagnostics, strict bool) error {
+	if isHeader {
+		index := headerIndex(record)
+		for i, f := range a.fields {
+			n, ok := index[f.name]
+			if !ok {
+				return fmt.Errorf("avro field %q is not a column", f.name)
+			}
+			a.index[i] = n
+		}
+		return nil
+	}
+
+	datum := make(map[string]interface{}, len(a.fields))
+	for i, f := range a.fields {
+		v := ""
+		if a.index[i] < len(record) {
+			v = record[a.index[i]]
+		}
+		value, err := avroValue(v, f)
+		if err != nil {
+			diag.report(Diagnostic{File: name, Line: line, Column: a.index[i] + 1, Rule: "avro", Message: fmt.Sprintf("%s: %q is not a valid %s", f.name, v, f.typ)})
+			if strict {
+				return errAvroValue
+			}
+			a.skipped++
+			return nil
+		}
+		datum[f.name] = value
+	}
+	a.pending = append(a.pending, datum)
+	if len(a.pending) == avroBlockRows {
+		return a.flush()
+	}
+	return nil
+}
+
+// avroValue converts v to the type of f. Empty values of nullable fields
+// are null.
+func avroValue(v string, f avroField) (interface{}, error) {
+	if f.nullable && v == "" {
+		return nil, nil
+	}
+	var value interface{}
+	var err error
+	switch f.typ {
+	case "boolean":
+		value, err = strconv.ParseBool(v)
+	case "int":
+		var n int64
+		n, err = strconv.ParseInt(v, 10, 32)
+		value = int32(n)
+	case "long":
+		value, err = strconv.ParseInt(v, 10, 64)
+	case "float":
+		var x float64
+		x, err = strconv.ParseFloat(v, 32)
+		value = float32(x)
+	case "double":
+		value, err = strconv.ParseFloat(v, 64)
+	case "bytes":
+		value = []byte(v)
+	default:
+		value = v
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.nullable {
+		return goavro.Union(f.typ, value), nil
+	}
+	return value, nil
+}
+
+// flush writes the pending records as a block.
+func (a *avroWriter) flush() error {
+	if len(a.pending) == 0 {
+		return nil
+	}
+	err := a.ocf.Append(a.pending)
+	a.pending = a.pending[:0]
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+const testAvroSchema = `{
+  "type": "record",
+  "name": "person",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "score", "type": ["null", "double"]},
+    {"name": "active", "type": "boolean"}
+  ]
+}`
+
+func readOCF(t *testing.T, b []byte) []interface{} {
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []interface{}
+	for r.Scan() {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRun_avroFlag(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "person.avsc")
+	if err := os.WriteFile(schema, []byte(testAvroSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := "active,name,id,score,extra\ntrue,Ada,1,9.5,x\nno,Bob,2,,y\nfalse,Cy,3,,z\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-avro", schema}); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": int64(1), "name": "Ada", "score": map[string]interface{}{"double": 9.5}, "active": true},
+		map[string]interface{}{"id": int64(3), "name": "Cy", "score": nil, "active": false},
+	}
+	if records := readOCF(t, outStream.Bytes()); !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %v to eq %v", records, expected)
+	}
+	if expected := "line 3 column 1: active: \"no\" is not a valid boolean\nskipped rows: 1\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+
+	outStream, errStream = new(bytes.Buffer), new(bytes.Buffer)
+	cli = &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-avro", schema, "-strict"}); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if expected := "line 3 column 1: active: \"no\" is not a valid boolean\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_avroFlag_invalid(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		name = filepath.Join(dir, name)
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	tests := []struct {
+		schema string
+		errors string
+	}{
+		{write("enum.avsc", `{"type":"record","name":"r","fields":[{"name":"e","type":{"type":"enum","name":"E","symbols":["A"]}}]}`), `field "e": only primitive types and their union with null are supported`},
+		{write("string.avsc", `"string"`), "the schema must be a record"},
+		{write("missing.avsc", `{"type":"record","name":"r","fields":[{"name":"nope","type":"string"}]}`), `avro field "nope" is not a column`},
+	}
+	for _, test := range tests {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("a\n1\n"), outStream: new(bytes.Buffer), errStream: errStream}
+		if status := cli.Run([]string{"./csvlint", "-avro", test.schema}); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", test.schema, status, ExitCodeError)
+		}
+		if !strings.Contains(errStream.String(), test.errors) {
+			t.Errorf("expected %q to contain %q", errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// utf8BOM is the UTF-8 encoding of U+FEFF, the byte order mark.
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// checkBOM looks for a UTF-8 byte order mark at the start of r, for
+// -check-bom and -strip-bom. A file with one is reported with -check-bom,
+// and counted, and the mark is removed with -strip-bom; otherwise it ends
+// up in the first field of the file. The inputs of a UTF-16 encoding
+// are left to their decoder.
+func checkBOM(name string, r io.Reader, diag *diagnostics, opts *Options) io.Reader {
+	switch opts.Encoding {
+	case "", "utf8", "auto":
+	default:
+		return r
+	}
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(utf8BOM)); !bytes.Equal(head, utf8BOM) {
+		return br
+	}
+	diag.count("files with a byte order mark", 1)
+	if opts.CheckBOM {
+		diag.report(Diagnostic{File: name, Line: 1, Rule: "bom", Message: "the file starts with a UTF-8 byte order mark"})
+	}
+	if opts.StripBOM {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_bomFlags(t *testing.T) {
+	files := writeFiles(t, "\uFEFFid,name\n1,a\n", "id,name\n2,b\n", "\uFEFFid,name\n3,c\n")
+	tests := []struct {
+		args     string
+		expected string
+		errors   string
+	}{
+		{"-check-bom", "\uFEFFid,name\n1,a\n2,b\n3,c\n", files[0] + ": line 1: the file starts with a UTF-8 byte order mark\n" + files[2] + ": line 1: the file starts with a UTF-8 byte order mark\nfiles with a byte order mark: 2\n"},
+		{"-strip-bom", "id,name\n1,a\n2,b\n3,c\n", "files with a byte order mark: 2\n"},
+		{"-strip-bom -file-workers 2", "id,name\n1,a\n2,b\n3,c\n", "files with a byte order mark: 2\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := append(strings.Split("./csvlint -quote minimal "+tt.args, " "), files...)
+
+		if status := cli.Run(args); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
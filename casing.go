@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// caseCols are the columns whose values -upper, -lower and -title change
+// the case of.
+type caseCols struct {
+	upper, lower, title []string
+	locale              language.Tag
+}
+
+func (c caseCols) empty() bool {
+	return len(c.upper) == 0 && len(c.lower) == 0 && len(c.title) == 0
+}
+
+// resolveCaseCols maps the columns to the caser of their case. Casers keep
+// state, so every input gets its own.
+func resolveCaseCols(c caseCols, header []string, noHeader bool) (map[int]cases.Caser, error) {
+	index := headerIndex(header)
+	casers := map[int]cases.Caser{}
+	flags := map[int]string{}
+	for _, list := range []struct {
+		flag  string
+		names []string
+		caser cases.Caser
+	}{
+		{"-upper", c.upper, cases.Upper(c.locale)},
+		{"-lower", c.lower, cases.Lower(c.locale)},
+		{"-title", c.title, cases.Title(c.locale)},
+	} {
+		for _, name := range list.names {
+			n, err := columnIndex(name, index, noHeader)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", list.flag, err)
+			}
+			if prev, ok := flags[n]; ok && prev != list.flag {
+				return nil, fmt.Errorf("column %q is in both %s and %s", name, prev, list.flag)
+			}
+			flags[n] = list.flag
+			casers[n] = list.caser
+		}
+	}
+	return casers, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_caseFlags(t *testing.T) {
+	input := "city,code,name\n\"  istanbul \",ab,jean-luc picard\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -quote minimal -upper code", "city,code,name\n\"  istanbul \",AB,jean-luc picard\n"},
+		{"./csvlint -quote minimal -title name -lower code", "city,code,name\n\"  istanbul \",ab,Jean-Luc Picard\n"},
+		{"./csvlint -quote minimal -upper city -trim-cols city", "city,code,name\nISTANBUL,ab,jean-luc picard\n"},
+		{"./csvlint -quote minimal -upper city -trim-cols city -case-locale tr", "city,code,name\nİSTANBUL,ab,jean-luc picard\n"},
+		{"./csvlint -quote minimal -no-header -upper 2", "city,CODE,name\n\"  istanbul \",AB,jean-luc picard\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_caseFlagsErrors(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -upper code -lower code", `column "code" is in both -upper and -lower`},
+		{"./csvlint -title missing", `-title: `},
+		{"./csvlint -upper code -case-locale 12345678901", `invalid -case-locale "12345678901"`},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("city,code\nx,y\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if !strings.Contains(errStream.String(), tt.expected) {
+			t.Errorf("%s: expected %q to contain %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
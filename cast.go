@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of -cast.
+const (
+	CastInt   = "int"
+	CastFloat = "float"
+	CastBool  = "bool"
+	CastDate  = "date"
+)
+
+// errCastValue is returned by transform when, with -strict, a value does
+// not parse as the type of its -cast. The value has already been
+// reported.
+var errCastValue = errors.New("value does not parse as its -cast type")
+
+// castDateLayouts are the layouts a date is read in when -cast gives none.
+var castDateLayouts = []string{"2006-01-02", "2006/01/02", time.RFC3339, "2006-01-02 15:04:05"}
+
+// castBools are the spellings of the booleans a bool column may hold,
+// lower cased.
+var castBools = map[string]bool{
+	"true": true, "t": true, "yes": true, "y": true, "on": true, "1": true,
+	"false": false, "f": false, "no": false, "n": false, "off": false, "0": false,
+}
+
+// cast parses the values of a column as a type to write them in the
+// canonical form of the type: integers and floats in decimal, booleans as
+// true or false and dates as 2006-01-02.
+type cast struct {
+	column string
+	typ    string
+	// layout, for a date, is the one the values are read in
+	layout string
+}
+
+// parseCast parses a col=TYPE spec, where a date may be followed by the
+// layout of the values, as in day=date:02/01/2006.
+func parseCast(spec string) (cast, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 || i == len(spec)-1 {
+		return cast{}, fmt.Errorf("invalid -cast %q: expected col=TYPE", spec)
+	}
+	c := cast{column: spec[:i], typ: spec[i+1:]}
+	if strings.HasPrefix(c.typ, CastDate+":") {
+		c.typ, c.layout = CastDate, strings.TrimPrefix(c.typ, CastDate+":")
+	}
+	switch c.typ {
+	case CastInt, CastFloat, CastBool, CastDate:
+	default:
+		return cast{}, fmt.Errorf("invalid -cast %q: the type must be int, float, bool or date", spec)
+	}
+	return c, nil
+}
+
+// convert returns v in the canonical form of the type, with precision
+// digits after the point for a float, or the shortest that reads back as
+// the same number when it is negative.
+func (c cast) convert(v string, precision int) (string, bool) {
+	s := strings.TrimSpace(v)
+	switch c.typ {
+	case CastInt:
+		if n, ok := parseInt(s); ok {
+			return strconv.FormatInt(n, 10), true
+		}
+		// 3.0 is still an integer
+		if f, ok := parseFloat(s); ok && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return strconv.FormatInt(int64(f), 10), true
+		}
+	case CastFloat:
+		if f, ok := parseFloat(s); ok {
+			return strconv.FormatFloat(f, 'f', precision, 64), true
+		}
+	case CastBool:
+		if b, ok := parseBool(s); ok {
+			return strconv.FormatBool(b), true
+		}
+	case CastDate:
+		layouts := castDateLayouts
+		if c.layout != "" {
+			layouts = []string{c.layout}
+		}
+		if t, ok := parseDate(s, layouts); ok {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return v, false
+}
+
+// reNumeric matches decimal numbers, unlike strconv.ParseFloat which also
+// takes hex, "inf" and "NaN".
+var reNumeric = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+// parseInt parses s as a decimal integer in the range of an int64.
+func parseInt(s string) (int64, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// parseFloat parses s as a decimal number in the range of a float64.
+func parseFloat(s string) (float64, bool) {
+	if !reNumeric.MatchString(s) {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// parseBool parses s as one of castBools, in any case.
+func parseBool(s string) (bool, bool) {
+	b, ok := castBools[strings.ToLower(s)]
+	return b, ok
+}
+
+// parseDate parses s in the first of layouts it is written in.
+func parseDate(s string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// bindCasts resolves the columns of casts.
+func bindCasts(casts []cast, header []string, noHeader bool) ([]int, error) {
+	index := headerIndex(header)
+	indices := make([]int, len(casts))
+	for i, c := range casts {
+		n, err := columnIndex(c.column, index, noHeader)
+		if err != nil {
+			return nil, fmt.Errorf("-cast %q: %s", c.column, err)
+		}
+		indices[i] = n
+	}
+	return indices, nil
+}
+
+// applyCasts converts the fields of record at indices. A value that does
+// not parse is reported and left as it is, or stops the input with strict;
+// empty values stay empty. pos gives the input line and 1-based column of
+// a field.
+func applyCasts(name string, record []string, casts []cast, indices []int, precision int, strict bool, pos func(field int) (int, int), diag *diagnostics) error {
+	for i, c := range casts {
+		n := indices[i]
+		if n >= len(record) || record[n] == "" {
+			continue
+		}
+		v, ok := c.convert(record[n], precision)
+		if ok {
+			record[n] = v
+			continue
+		}
+		line, column := pos(n)
+		diag.report(Diagnostic{File: name, Line: line, Column: column, Rule: "cast", Message: fmt.Sprintf("%s: %q is not a valid %s", c.column, record[n], c.typ)})
+		if strict {
+			return errCastValue
+		}
+	}
+	return nil
+}
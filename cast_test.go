@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCast_convert(t *testing.T) {
+	tests := []struct {
+		spec     string
+		value    string
+		expected string
+		ok       bool
+	}{
+		{"n=int", "+007", "7", true},
+		{"n=int", "3.0", "3", true},
+		{"n=int", "3.5", "3.5", false},
+		{"n=int", "0x10", "0x10", false},
+		{"n=float", " 1.50 ", "1.5", true},
+		{"n=float", "1e3", "1000", true},
+		{"n=float", "NaN", "NaN", false},
+		{"n=bool", "Yes", "true", true},
+		{"n=bool", "0", "false", true},
+		{"n=bool", "maybe", "maybe", false},
+		{"n=date", "2024/03/05", "2024-03-05", true},
+		{"n=date", "2024-03-05T10:00:00Z", "2024-03-05", true},
+		{"n=date:02/01/2006", "05/03/2024", "2024-03-05", true},
+		{"n=date:02/01/2006", "2024-03-05", "2024-03-05", false},
+	}
+	for _, test := range tests {
+		c, err := parseCast(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := c.convert(test.value, -1)
+		if got != test.expected || ok != test.ok {
+			t.Errorf("%s %q: expected %q, %v to eq %q, %v", test.spec, test.value, got, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestRun_castFlag(t *testing.T) {
+	input := "id,amount,paid,day\n01,2.5,Y,2024/03/05\n2,x,no,\n3,1e2,?,2024-13-01\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -cast id=int -cast amount=float -cast paid=bool -cast day=date", ExitCodeOK, "id,amount,paid,day\n1,2.5,true,2024-03-05\n2,x,false,\n3,100,?,2024-13-01\n", "line 3 column 2: amount: \"x\" is not a valid float\nline 4 column 3: paid: \"?\" is not a valid bool\nline 4 column 4: day: \"2024-13-01\" is not a valid date\n"},
+		{"./csvlint -quote minimal -cast a=float -float-precision 2 -select amount:a,id", ExitCodeOK, "a,id\n2.50,01\nx,2\n100.00,3\n", "line 3 column 2: a: \"x\" is not a valid float\n"},
+		{"./csvlint -quote minimal -cast amount=float -strict", ExitCodeError, "id,amount,paid,day\n01,2.5,Y,2024/03/05\n", "line 3 column 2: amount: \"x\" is not a valid float\n"},
+		{"./csvlint -cast amount=money", ExitCodeError, "", "invalid -cast \"amount=money\": the type must be int, float, bool or date\n"},
+		{"./csvlint -cast id=int -float-precision 2", ExitCodeError, "", "-float-precision needs a -cast to float and must not be negative\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
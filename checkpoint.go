@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// defaultCheckpointEvery is how many input records -checkpoint reads
+// between two saves.
+const defaultCheckpointEvery = 10000
+
+// Checkpoint is what -checkpoint saves: how many records of the input were
+// read and processed, and the size of the output they make. -resume skips
+// as many records and cuts the output back to that size before appending
+// to it, so rows written after the last save are not repeated.
+type Checkpoint struct {
+	Input       string `json:"input"`
+	Records     int    `json:"records"`
+	OutputBytes int64  `json:"output_bytes"`
+}
+
+// checkpoint saves a Checkpoint every so many records while the input is
+// transformed.
+type checkpoint struct {
+	file   string
+	every  int
+	input  string
+	output *os.File
+	// skip is the number of records a resumed run reads without
+	// processing them; read counts the records read, and saved is read at
+	// the last save.
+	skip  int
+	read  int
+	saved int
+}
+
+// due tells whether enough records were read since the last save.
+func (c *checkpoint) due() bool {
+	return c.read-c.saved >= c.every
+}
+
+// save records that read records are processed. The output must be
+// flushed to the file already.
+func (c *checkpoint) save() error {
+	if err := c.output.Sync(); err != nil {
+		return err
+	}
+	info, err := c.output.Stat()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(Checkpoint{Input: c.input, Records: c.read, OutputBytes: info.Size()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// renamed into place, so that an interrupted save leaves the last one
+	tmp := c.file + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.file); err != nil {
+		return err
+	}
+	c.saved = c.read
+	return nil
+}
+
+// loadCheckpoint reads the checkpoint -resume starts from, or returns nil
+// when there is none yet.
+func loadCheckpoint(name, input string) (*Checkpoint, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var c Checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	if c.Input != input {
+		return nil, fmt.Errorf("%s: the checkpoint is of %s, not %s", name, c.Input, input)
+	}
+	return &c, nil
+}
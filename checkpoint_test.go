@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_checkpointFlag(t *testing.T) {
+	files := writeFiles(t, "id,v\n1,a\n2,b\n3,c\n4,d,extra\n5,e\n")
+	dir := t.TempDir()
+	out, cp := filepath.Join(dir, "out.csv"), filepath.Join(dir, "run.checkpoint")
+	args := []string{"./csvlint", "-quote", "minimal", "-max-columns", "2", "-checkpoint", cp, "-checkpoint-every", "2", "-o", out, files[0]}
+
+	// the run stops at the row with three fields, after two saves
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Fatalf("expected %d to eq %d", status, ExitCodeError)
+	}
+	saved, err := loadCheckpoint(cp, files[0])
+	if err != nil || saved == nil {
+		t.Fatalf("expected a checkpoint, got %v, %v", saved, err)
+	}
+	if saved.Records != 4 || saved.OutputBytes != int64(len("id,v\n1,a\n2,b\n3,c\n")) {
+		t.Errorf("expected 4 records and the bytes of 4 lines, got %+v", saved)
+	}
+
+	// a row written after the last save is cut before appending
+	if err := os.WriteFile(out, []byte("id,v\n1,a\n2,b\n3,c\n4,d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(files[0], []byte("id,v\n1,a\n2,b\n3,c\n4,d\n5,e\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outStream, errStream = new(bytes.Buffer), new(bytes.Buffer)
+	cli = &CLI{outStream: outStream, errStream: errStream}
+	if status := cli.Run(append(args[:len(args)-1], "-resume", files[0])); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	b, _ := os.ReadFile(out)
+	if expected := "id,v\n1,a\n2,b\n3,c\n4,d\n5,e\n"; string(b) != expected {
+		t.Errorf("expected %q to eq %q", b, expected)
+	}
+	if _, err := os.Stat(cp); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed once done, got %v", err)
+	}
+}
+
+func TestRun_checkpointFlagErrors(t *testing.T) {
+	files := writeFiles(t, "id\n1\n", "id\n2\n")
+	dir := t.TempDir()
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -checkpoint cp -o out " + files[0] + " " + files[1], "-checkpoint needs a single input file, as stdin cannot be read again to resume\n"},
+		{"./csvlint -checkpoint cp " + files[0], "-checkpoint needs -output, which -resume appends to\n"},
+		{"./csvlint -checkpoint cp -o out -gzip-out " + files[0], "-checkpoint cannot be combined with -gzip-out, -manifest, -verify, -no-trailing-newline, -sort, -sample, -rows, -diff, -split-rows, -split-bytes, -partition-by, -in-place, -check-idempotent or outputs other than csv and tsv\n"},
+		{"./csvlint -checkpoint cp -o out -add-index " + files[0], "-checkpoint does not save the state of -add-index, -unique-key, -quarantine, -errors-csv or a random -pseudonymize salt and cannot be combined with them\n"},
+		{"./csvlint -checkpoint cp -o out -unique-key id " + files[0], "-checkpoint does not save the state of -add-index, -unique-key, -quarantine, -errors-csv or a random -pseudonymize salt and cannot be combined with them\n"},
+		{"./csvlint -checkpoint cp -o out -quarantine q.csv " + files[0], "-checkpoint does not save the state of -add-index, -unique-key, -quarantine, -errors-csv or a random -pseudonymize salt and cannot be combined with them\n"},
+		{"./csvlint -checkpoint cp -o out -errors-csv e.csv " + files[0], "-checkpoint does not save the state of -add-index, -unique-key, -quarantine, -errors-csv or a random -pseudonymize salt and cannot be combined with them\n"},
+		{"./csvlint -checkpoint cp -o out -pseudonymize id " + files[0], "-checkpoint does not save the state of -add-index, -unique-key, -quarantine, -errors-csv or a random -pseudonymize salt and cannot be combined with them\n"},
+		{"./csvlint -checkpoint cp -o out -checkpoint-every 0 " + files[0], "-checkpoint-every must be a positive number of records\n"},
+		{"./csvlint -resume " + files[0], "-resume and -checkpoint-every need -checkpoint\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := strings.Split(strings.NewReplacer(" cp", " "+filepath.Join(dir, "cp"), " out", " "+filepath.Join(dir, "out")).Replace(tt.args), " ")
+
+		if status := cli.Run(args); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+// A resumed run counts the rows it skips and hashes as the first run did.
+func TestRun_checkpointResumeState(t *testing.T) {
+	files := writeFiles(t, "id,v\n1,a\n2,b\n1,c\n")
+	dir := t.TempDir()
+	out, cp := filepath.Join(dir, "out.csv"), filepath.Join(dir, "run.checkpoint")
+	args := []string{"./csvlint", "-quote", "minimal", "-pseudonymize", "id", "-pseudonymize-salt", "s", "-abort-on-empty", "-checkpoint", cp, "-o", out}
+
+	whole := new(bytes.Buffer)
+	cli := &CLI{outStream: whole, errStream: new(bytes.Buffer)}
+	if status := cli.Run([]string{"./csvlint", "-quote", "minimal", "-pseudonymize", "id", "-pseudonymize-salt", "s", files[0]}); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d", status, ExitCodeOK)
+	}
+
+	// as if the run was interrupted once all the rows were saved, so that
+	// it has no data row left to process
+	head := whole.String()
+	if err := os.WriteFile(out, []byte(head), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(Checkpoint{Input: files[0], Records: 4, OutputBytes: int64(len(head))})
+	if err := os.WriteFile(cp, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	errStream := new(bytes.Buffer)
+	cli = &CLI{outStream: new(bytes.Buffer), errStream: errStream}
+	if status := cli.Run(append(args, "-resume", files[0])); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	got, _ := os.ReadFile(out)
+	if string(got) != whole.String() {
+		t.Errorf("expected %q to eq %q", got, whole.String())
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// zeroWidth removes the invisible characters that text pasted from web
+// pages and word processors carries: zero-width spaces and joiners, the
+// word joiner and byte order marks.
+var zeroWidth = strings.NewReplacer(
+	"\u200B", "", // zero width space
+	"\u200C", "", // zero width non-joiner
+	"\u200D", "", // zero width joiner
+	"\u2060", "", // word joiner
+	"\uFEFF", "", // zero width no-break space, or byte order mark
+)
+
+// cleanText is the part of -clean-text done before the other transforms:
+// invalid UTF-8 sequences become U+FFFD, the text is normalized to NFC,
+// zero-width characters are removed and smart quotes, dashes and ellipses
+// are replaced with ASCII. It reports whether v held invalid UTF-8. No-break
+// spaces are replaced and the fields trimmed afterwards, as usual.
+func cleanText(v string) (string, bool) {
+	invalid := !utf8.ValidString(v)
+	if invalid {
+		v = strings.ToValidUTF8(v, "\uFFFD")
+	}
+	v = norm.NFC.String(v)
+	v = zeroWidth.Replace(v)
+	return smartCharReplacer.Replace(v), invalid
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		value, expected string
+		invalid         bool
+	}{
+		{"Cafe\u0301", "Caf\u00E9", false},
+		{"ab\u200Bc\u2060d\uFEFF", "abcd", false},
+		{"\u201Chi\u201D \u2013 ok\u2026", "\"hi\" - ok...", false},
+		{"a\xffb", "a\uFFFDb", true},
+		{"x\u00A0y", "x\u00A0y", false},
+	}
+	for _, test := range tests {
+		got, invalid := cleanText(test.value)
+		if got != test.expected || invalid != test.invalid {
+			t.Errorf("%q: expected %q, %v to eq %q, %v", test.value, got, invalid, test.expected, test.invalid)
+		}
+	}
+}
+
+func TestRun_cleanTextFlag(t *testing.T) {
+	input := " name\u200B ,note\nCafe\u0301 ,\u201Cgood\u201D\u00A0\n\u00A0a\xffb,ok\n"
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+	if status := cli.Run([]string{"./csvlint", "-quote", "minimal", "-clean-text"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+	expected := "name,note\nCaf\u00E9,\"\"\"good\"\"\"\na\uFFFDb,ok\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	if expected := "line 3 column 1: invalid UTF-8 replaced with U+FFFD\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
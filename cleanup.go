@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// signalCleanup removes the temporary files of a run, the runs of an
+// external sort and the -in-place output, when the process is interrupted
+// or terminated, before exiting. Every cleanup registered is run, as the
+// deferred ones of Run are not.
+type signalCleanup struct {
+	mu   sync.Mutex
+	fns  []func()
+	ch   chan os.Signal
+	done chan struct{}
+}
+
+// add registers fn, and starts watching for the signals the first time.
+func (c *signalCleanup) add(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fns = append(c.fns, fn)
+	if c.ch != nil {
+		return
+	}
+	c.ch, c.done = make(chan os.Signal, 1), make(chan struct{})
+	signal.Notify(c.ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-c.ch:
+			c.mu.Lock()
+			for _, fn := range c.fns {
+				fn()
+			}
+			os.Exit(ExitCodeError)
+		case <-c.done:
+		}
+	}()
+}
+
+// stop stops watching.
+func (c *signalCleanup) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ch != nil {
+		signal.Stop(c.ch)
+		close(c.done)
+		c.ch = nil
+	}
+}
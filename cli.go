@@ -1,177 +1,2834 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
-	"strings"
 	"os"
-	"encoding/csv"
-	"bufio"
 	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 // Exit codes are int values that represent an exit code for a particular error.
 const (
 	ExitCodeOK    int = 0
 	ExitCodeError int = 1 + iota
+	// ExitCodeTimeout is returned when stdin gives no data for
+	// -stdin-timeout.
+	ExitCodeTimeout
 )
 
 // CLI is the command line object
 type CLI struct {
+	// inStream is read when no file is given.
+	inStream io.Reader
 	// outStream and errStream are the stdout and stderr
 	// to write message from the CLI.
 	outStream, errStream io.Writer
 }
 
-func printCsv(w io.Writer, row []string) (e error) {
+// unescape decodes Go-style escape sequences such as \t or \u3000 in s.
+func unescape(s string) (string, error) {
+	return strconv.Unquote(`"` + strings.Replace(s, `"`, `\"`, -1) + `"`)
+}
+
+// needsQuotes reports whether field must be quoted to survive a csv reader
+// using delim.
+func needsQuotes(field, delim string) bool {
+	if field == "" {
+		return false
+	}
+	if strings.Contains(field, delim) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	return field[0] == ' ' || field[0] == '\t'
+}
+
+// escapeControl renders control characters and invalid UTF-8 bytes in s as
+// \xNN or \uNNNN escapes.
+func escapeControl(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, "\\x%02X", s[i])
+		case r < 0x80 && unicode.IsControl(r):
+			fmt.Fprintf(&b, "\\x%02X", r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// newlineArgs returns the strings.Replacer arguments that render carriage
+// returns and line feeds inside fields as opts asks.
+func newlineArgs(opts *Options) []string {
+	cr, lf := opts.newlineHandling()
+	render := func(handling, c, escaped string) string {
+		switch handling {
+		case NewlineRemove:
+			return ""
+		case NewlineSpace:
+			return " "
+		case NewlineKeep:
+			return c
+		}
+		return escaped
+	}
+	args := []string{"\n", render(lf, "\n", "\\n"), "\r", render(cr, "\r", "\\r")}
+	if cr == NewlineSpace && lf == NewlineSpace {
+		args = append([]string{"\r\n", " "}, args...)
+	}
+	return args
+}
+
+func printCsv(w io.Writer, row []string, opts *Options) error {
 	r := strings.NewReplacer(
 		`\"`, `""`, // \" is not genuine escape in csv format, so convert manually
 		`"`, `""`,
 	)
 
 	sep := ""
+	delim := opts.outputDelimiter()
+
+	// Without quotes, the escape character keeps delimiters in fields from
+	// splitting them.
+	var esc *strings.Replacer
+	if opts.Quote == QuoteNone && opts.EscapeDelimiter != "" {
+		esc = strings.NewReplacer(
+			opts.EscapeDelimiter, opts.EscapeDelimiter+opts.EscapeDelimiter,
+			delim, opts.EscapeDelimiter+delim,
+		)
+	}
 
 	for _, cell := range row {
-		_, err := io.WriteString(w, sep + `"` + r.Replace(cell) + `"`)
-		if err != nil {
-			fmt.Errorf(err.Error())
-			continue
+		switch {
+		case cell == "" && opts.NullToken != "":
+			cell = opts.NullToken
+		case esc != nil:
+			cell = esc.Replace(cell)
+		case opts.Quote == QuoteNone:
+		case opts.Quote == QuoteMinimal && !needsQuotes(cell, delim) && (opts.NullToken == "" || cell != opts.NullToken):
+		default:
+			cell = `"` + r.Replace(cell) + `"`
 		}
-		sep = ","
-	}
-	_, err := io.WriteString(w, "\n")
-	if err != nil {
-		fmt.Errorf(err.Error())
+		if _, err := io.WriteString(w, sep+cell); err != nil {
+			return err
+		}
+		sep = delim
 	}
-	return
+	_, err := io.WriteString(w, opts.lineEnding())
+	return err
 }
 
-func printTsv(w io.Writer, row []string) (e error) {
-	r := strings.NewReplacer(
+func printTsv(w io.Writer, row []string, opts *Options) error {
+	// Newlines can still come from -fill or -rule values, and no TSV
+	// reader copes with them.
+	r := strings.NewReplacer(append([]string{
 		"\t", "\\t",
-	)
+	}, newlineArgs(opts)...)...)
 
 	sep := ""
 
 	for _, cell := range row {
-		_, err := io.WriteString(w, sep + r.Replace(cell))
-		if err != nil {
-			fmt.Errorf(err.Error())
-			continue
+		if cell == "" {
+			cell = opts.NullToken
+		}
+		if _, err := io.WriteString(w, sep+r.Replace(cell)); err != nil {
+			return err
 		}
 		sep = "\t"
 	}
-	_, err := io.WriteString(w, "\n")
-	if err != nil {
-		fmt.Errorf(err.Error())
-	}
-	return
+	_, err := io.WriteString(w, opts.lineEnding())
+	return err
 }
 
+// printerFor returns the function writing a record in the output format of
+// opts.
+func printerFor(opts *Options) func(io.Writer, []string, *Options) error {
+	if opts.PGCopy {
+		return printPgCopy
+	}
+	if opts.TSV {
+		return printTsv
+	}
+	return printCsv
+}
 
-// Run invokes the CLI with the given arguments.
-func (cli *CLI) Run(args []string) int {
-	var (
-		removeTab     bool
-		removeNewline bool
-		removeSpace   bool
-		tsv           bool
-		file          string
+// transform reads csv records from r, normalizes every field according to
+// opts and writes the result to w. Malformed records are reported to diag,
+// attributed to name when it is not empty, and skipped. It returns the
+// number of records written.
+func transform(name string, r io.Reader, w io.Writer, diag *diagnostics, opts *Options) (int, error) {
+	if opts.read != nil {
+		r = opts.read.wrap(r)
+	}
+	if opts.CheckBOM || opts.StripBOM {
+		r = checkBOM(name, r, diag, opts)
+	}
+	r, inputEncoding := decodeInput(r, opts.Encoding, func(format string, a ...interface{}) {
+		if !opts.Verbose {
+			return
+		}
+		if name != "" {
+			format = name + ": " + format
+		}
+		diag.logf(format, a...)
+	})
 
-		version bool
-	)
+	replacerArgs := []string{
+		"\u00A0", opts.NBSPReplacement, // another type space
+	}
 
-	// Define option flag parse
-	flags := flag.NewFlagSet(Name, flag.ContinueOnError)
-	flags.SetOutput(cli.errStream)
+	if opts.RemoveTab {
+		replacerArgs = append(replacerArgs, "\t", "")
+	}
 
-	flags.BoolVar(&removeTab, "remove-tab", false, "remove tab")
-	flags.BoolVar(&removeTab, "t", false, "remove tab(Short)")
-	flags.BoolVar(&removeNewline, "remove-newline", false, "remove newline in column")
-	flags.BoolVar(&removeNewline, "n", false, "remove newline in column(Short)")
-	flags.BoolVar(&removeSpace, "remove-space", false, "remove sparse spaces")
-	flags.BoolVar(&removeSpace, "s", false, "remove sparse spaces(Short)")
-	flags.BoolVar(&tsv, "tsv", false, "output tsv")
-	flags.BoolVar(&tsv, "T", false, "output tsv(Short)")
-	flags.StringVar(&file, "file", "", "file")
-	flags.StringVar(&file, "f", "", "file(Short)")
+	replacerArgs = append(replacerArgs, newlineArgs(opts)...)
 
-	flags.BoolVar(&version, "version", false, "Print version information and quit.")
+	reTrS := regexp.MustCompile(`\s{2,}`)
 
-	// Parse commandline flag
-	if err := flags.Parse(args[1:]); err != nil {
-		return ExitCodeError
-	}
+	printFunc := printerFor(opts)
 
-	// Show version
-	if version {
-		fmt.Fprintf(cli.errStream, "%s version %s\n", Name, Version)
-		return ExitCodeOK
-	}
+	replacer := strings.NewReplacer(replacerArgs...)
 
-	var fp *os.File
-	if file == "" {
-		fp = os.Stdin
-	} else {
-		var err error
-		fp, err = os.Open(file)
-		if err != nil {
-			panic(err)
-		}
-		defer fp.Close()
+	var endings *lineEndings
+	if opts.CheckLineEndings {
+		endings = &lineEndings{r: r}
+		r = endings
 	}
 
-	replacerArgs := []string{
-		"\u00A0", "\x20", // another type space
+	swapQuote := opts.QuoteChar != 0 && opts.QuoteChar != '"'
+	if swapQuote {
+		r = &swapReader{r: r, a: byte(opts.QuoteChar), b: '"'}
 	}
 
-	if removeTab {
-		replacerArgs = append(replacerArgs, "\t", "")
+	if opts.FlattenMultiline > 0 {
+		r = newFlattenReader(r, opts.Comma, opts.FlattenMultiline, name, diag)
 	}
 
-	if removeNewline {
-		replacerArgs = append(replacerArgs, "\n", "", "\r", "")
-	} else {
-		replacerArgs = append(replacerArgs, "\n", "\\n", "\r", "\\r")
+	if opts.ExplodeJSON != "" {
+		var err error
+		if r, err = explodeJSON(name, r, diag, opts); err != nil {
+			return 0, err
+		}
 	}
 
-	reTrS := regexp.MustCompile(`\s{2,}`)
+	writer := bufio.NewWriter(w)
+	written := 0
 
-	var printFunc func(io.Writer, []string) error
-	if tsv {
-		printFunc = printTsv
+	var reader recordReader
+	var raw *commentReader
+	if opts.PreserveComments {
+		raw = &commentReader{
+			raw:  newRawReader(r, opts.Comma, '#'),
+			lazy: true,
+			onComment: func(line []byte) error {
+				if opts.CommentPrefix == "" {
+					return nil
+				}
+				line = bytes.TrimRight(line[1:], "\r\n")
+				_, err := fmt.Fprintf(writer, "%s%s\n", opts.CommentPrefix, line)
+				return err
+			},
+		}
+		reader = raw
+	} else if opts.quarantine != nil {
+		// The raw bytes of every record are needed to quarantine it.
+		raw = &commentReader{raw: newRawReader(r, opts.Comma, 0), lazy: true}
+		reader = raw
 	} else {
-		printFunc = printCsv
+		cr := csv.NewReader(r)
+		cr.LazyQuotes = true
+		cr.FieldsPerRecord = -1
+		if opts.Comma != 0 {
+			cr.Comma = opts.Comma
+		}
+		reader = cr
+	}
+	// lineOf gives the input line of the last record read, or 0 before
+	// any is.
+	lineOf := func(seen int) int {
+		if seen == 0 {
+			return 0
+		}
+		line, _ := reader.FieldPos(0)
+		return line
+	}
+	partIdx, seen := 0, 0
+	write := func(record []string, isHeader bool) error {
+		if opts.collect != nil {
+			line := 0
+			if !isHeader {
+				line, _ = reader.FieldPos(0)
+			}
+			return opts.collect(record, isHeader, line)
+		}
+		if opts.diff != nil {
+			var err error
+			if isHeader {
+				record, err = opts.diff.bind(record)
+			} else {
+				line, _ := reader.FieldPos(0)
+				record, err = opts.diff.compare(name, record, line, diag)
+			}
+			if err != nil {
+				return err
+			} else if record == nil {
+				return nil
+			}
+		}
+		if opts.schema != nil {
+			if err := opts.schema.add(record, isHeader); err != nil {
+				return err
+			}
+			if opts.schema.only {
+				return nil
+			}
+		}
+		if opts.sorter != nil {
+			if !isHeader {
+				// written by Run once the input is done
+				return opts.sorter.add(record)
+			}
+			if err := opts.sorter.bind(record); err != nil {
+				return err
+			}
+		}
+		if isHeader && opts.SkipHeader {
+			// left out of the output once it bound the columns above
+			return nil
+		}
+		if opts.parquet != nil {
+			line := 0
+			if !isHeader {
+				line, _ = reader.FieldPos(0)
+			}
+			if err := opts.parquet.add(name, record, isHeader, line, diag, opts.Strict); err != nil {
+				return err
+			}
+		} else if opts.avro != nil {
+			line := 0
+			if !isHeader {
+				line, _ = reader.FieldPos(0)
+			}
+			if err := opts.avro.add(name, record, isHeader, line, diag, opts.Strict); err != nil {
+				return err
+			}
+		} else if opts.yaml != nil {
+			line := 0
+			if !isHeader {
+				line, _ = reader.FieldPos(0)
+			}
+			if err := opts.yaml.write(writer, name, record, isHeader, line, diag); err != nil {
+				return err
+			}
+		} else if opts.keyValue != nil {
+			line := 0
+			if !isHeader {
+				line, _ = reader.FieldPos(0)
+			}
+			if err := opts.keyValue.write(writer, name, record, isHeader, line, diag, opts); err != nil {
+				return err
+			}
+		} else if opts.values != nil {
+			if err := opts.values.add(record, isHeader); err != nil {
+				return err
+			}
+		} else if opts.counts != nil {
+			if err := opts.counts.add(record, isHeader); err != nil {
+				return err
+			}
+		} else if opts.concat != nil {
+			if err := opts.concat.add(record, isHeader); err != nil {
+				return err
+			}
+		} else if opts.types != nil {
+			opts.types.add(record, isHeader)
+		} else if opts.density != nil {
+			opts.density.add(record, isHeader)
+		} else if opts.pretty != nil {
+			if opts.LineNumbers {
+				record = numberLine(record, lineOf(seen))
+			}
+			if err := opts.pretty.add(record); err != nil {
+				return err
+			}
+		} else if opts.record != nil {
+			opts.record.add(record, isHeader)
+		} else if opts.aligned != nil {
+			if opts.LineNumbers {
+				record = numberLine(record, lineOf(seen))
+			}
+			if err := opts.aligned.add(record, opts); err != nil {
+				return err
+			}
+		} else if isHeader && opts.PGCopy {
+			// COPY in text format reads every line as a row; the header
+			// was still needed to bind the columns
+			return nil
+		} else if opts.partitions != nil {
+			var line bytes.Buffer
+			if err := printFunc(&line, record, opts); err != nil {
+				return err
+			}
+			if isHeader {
+				opts.partitions.setHeader(line.Bytes())
+				return nil
+			}
+			value := ""
+			if partIdx < len(record) {
+				value = record[partIdx]
+			}
+			if err := opts.partitions.write(value, line.Bytes()); err != nil {
+				return err
+			}
+		} else if err := printFunc(writer, record, opts); err != nil {
+			return err
+		}
+		written++
+		return nil
 	}
 
-	replacer := strings.NewReplacer(replacerArgs...)
+	var (
+		indices   []int
+		hashIdx   []int
+		rangeIdx  []int
+		numIdx    []int
+		fmtIdx    [][]int
+		dateIdx   int
+		rules     []boundRule
+		splits    []boundSplit
+		merges    []boundMerge
+		coalesce  []boundMerge
+		lookups   []int
+		castIdx   []int
+		pseudoIdx []int
+		keep      map[int]bool
+		excluded  map[int]bool
+		excludeW  int
+		spaces    map[int]spaceOps
+		casers    map[int]cases.Caser
+		replaced  map[int]bool
+		width     int
+		defaults  map[int]string
+		padded    int
+		cut       int
+		sample    *reservoir
+		index     = opts.index
+		invisible *invisibleCounts
+		unique    *uniqueKeys
+	)
+	if len(opts.UniqueKey) > 0 {
+		unique = newUniqueKeys(opts.UniqueKey, opts.keys, opts.memory)
+	}
+	if opts.StripInvisible {
+		invisible = new(invisibleCounts)
+	}
+	if opts.Sample > 0 {
+		sample = newReservoir(opts.Sample, opts.Seed)
+	}
+	var headerRow []string
+	headerRows := 0
+	quarantined, passed, outside, checked := 0, 0, 0, 0
+	quarantine := func() error {
+		b := raw.last
+		if swapQuote {
+			b = []byte(swapRunes(string(b), opts.QuoteChar, '"'))
+		}
+		quarantined++
+		// the record as it was in the input, not decoded
+		return opts.quarantine.write(encodeRaw(b, inputEncoding))
+	}
+	defer func() {
+		if opts.rules != nil {
+			opts.rules.add(checked)
+		}
+		if padded > 0 {
+			diag.count("padded rows", padded)
+		}
+		if cut > 0 {
+			diag.count("truncated rows", cut)
+		}
+		if invisible != nil {
+			invisible.report(diag)
+		}
+		if outside > 0 {
+			diag.count("rows outside the dates", outside)
+		}
+		if opts.DedupHeaderRows {
+			diag.count("dropped header rows", headerRows)
+		}
+		if opts.quarantine != nil {
+			diag.count("quarantined rows", quarantined)
+			diag.count("passed rows", passed)
+		}
+	}()
 
-	reader := csv.NewReader(fp)
-	reader.LazyQuotes = true
+	if opts.PartitionBy != "" && opts.NoHeader {
+		var err error
+		if partIdx, err = columnIndex(opts.PartitionBy, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Ranges) > 0 && opts.NoHeader {
+		var err error
+		if rangeIdx, err = resolveRanges(opts.Ranges, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if unique != nil && opts.NoHeader {
+		if err := unique.bind(nil, true); err != nil {
+			return written, err
+		}
+	}
+	if opts.Numeric != nil && opts.NoHeader {
+		var err error
+		if numIdx, err = opts.Numeric.bind(nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Formats) > 0 && opts.NoHeader {
+		fmtIdx = make([][]int, len(opts.Formats))
+		for i, c := range opts.Formats {
+			var err error
+			if fmtIdx[i], err = c.bind(nil, true); err != nil {
+				return written, err
+			}
+		}
+	}
+	if opts.Dates != nil && opts.NoHeader {
+		var err error
+		if dateIdx, err = opts.Dates.bind(nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.NoTransformCols) > 0 && opts.NoHeader {
+		var err error
+		if keep, err = resolveKeep(opts.NoTransformCols, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Exclude) > 0 && opts.NoHeader {
+		var err error
+		if excluded, err = resolveKeep(opts.Exclude, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Lookups) > 0 && opts.NoHeader {
+		var err error
+		if lookups, err = bindLookups(opts.Lookups, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if (len(opts.TrimCols) > 0 || len(opts.CollapseCols) > 0) && opts.NoHeader {
+		var err error
+		if spaces, err = resolveSpaceCols(opts.TrimCols, opts.CollapseCols, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if !opts.Case.empty() && opts.NoHeader {
+		var err error
+		if casers, err = resolveCaseCols(opts.Case, nil, true); err != nil {
+			return written, err
+		}
+	}
 
-	writer := bufio.NewWriter(os.Stdout)
+	if len(opts.ReplaceRegexCols) > 0 && opts.NoHeader {
+		var err error
+		if replaced, err = resolveKeep(opts.ReplaceRegexCols, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Rules) > 0 && opts.NoHeader {
+		var err error
+		if rules, err = bindRules(opts.Rules, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Casts) > 0 && opts.NoHeader {
+		var err error
+		if castIdx, err = bindCasts(opts.Casts, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Pseudonymize) > 0 && opts.NoHeader {
+		var err error
+		if pseudoIdx, err = bindPseudonymize(opts.Pseudonymize, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Splits) > 0 && opts.NoHeader {
+		var err error
+		if splits, _, err = bindSplits(opts.Splits, nil, true, opts.SplitKeep); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Merges) > 0 && opts.NoHeader {
+		var err error
+		if merges, _, err = bindMerges(opts.Merges, nil, true, opts.MergeDrop); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Coalesces) > 0 && opts.NoHeader {
+		var err error
+		if coalesce, _, err = bindCoalesces(opts.Coalesces, nil, true, opts.CoalesceDrop); err != nil {
+			return written, err
+		}
+	}
+	if opts.HashColumn != "" && opts.NoHeader {
+		var err error
+		if hashIdx, err = resolveHashCols(opts.HashCols, nil, true); err != nil {
+			return written, err
+		}
+	}
+	if len(opts.Select) > 0 && opts.NoHeader {
+		indices, _ = resolveSelect(opts.Select, nil)
+		header := selectHeader(opts.Select)
+		if opts.ProjectionOrder == ProjectionSource {
+			sourceOrder(indices, header)
+		}
+		if header != nil && !opts.SkipHeader {
+			for _, sp := range splits {
+				header, _ = sp.apply(header, "", true)
+			}
+			if merges != nil {
+				header = applyMerges(header, merges, opts.MergeSep, true)
+			}
+			if coalesce != nil {
+				header = applyMerges(header, coalesce, "", true)
+			}
+			if len(opts.RenameRegex) > 0 {
+				var err error
+				if header, err = renameRegex(header, opts.RenameRegex); err != nil {
+					return written, err
+				}
+			}
+			if opts.HeaderCase != "" {
+				var err error
+				if header, err = changeHeaderCase(header, opts.HeaderCase); err != nil {
+					return written, err
+				}
+			}
+			if opts.HashColumn != "" {
+				header = append(header, opts.HashColumn)
+			}
+			if index != nil {
+				header = index.prepend(header, true, 0)
+			}
+			if err := write(header, true); err != nil {
+				return written, err
+			}
+		}
+	}
 
+	// fieldPos gives the input line and column of a field after -select.
+	fieldPos := func(i int) (int, int) {
+		if indices != nil {
+			i = indices[i]
+		}
+		line, _ := reader.FieldPos(i)
+		return line, i + 1
+	}
+	var histogram fieldHistogram
+	if opts.FieldHistogram {
+		histogram = fieldHistogram{}
+	}
+	// errorRow is the last data row as read, whose problems, found by the
+	// checks and by the steps after them, -errors-csv writes before the
+	// next record is read
+	var errorRow []string
+	writeErrorRow := func() error {
+		row := errorRow
+		if row == nil {
+			return nil
+		}
+		errorRow = nil
+		if found := diag.since(); len(found) > 0 {
+			return opts.errorRows.write(row, found)
+		}
+		return nil
+	}
+	dataRows, firstWidth := 0, 0
+	selectChecked := false
+	cp := opts.checkpoint
 	for {
+		if err := writeErrorRow(); err != nil {
+			return written, err
+		}
+		if opts.Rows != nil && dataRows >= opts.Rows.max() {
+			break
+		}
+		if cp != nil && cp.due() {
+			if err := writer.Flush(); err != nil {
+				return written, err
+			}
+			if err := cp.save(); err != nil {
+				return written, err
+			}
+		}
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
+		}
+		if cp != nil {
+			cp.read++
+			if cp.read <= cp.skip && (cp.read > 1 || opts.NoHeader) {
+				// processed before the run was interrupted, and still
+				// counted for -abort-on-empty
+				if err == nil {
+					seen++
+					dataRows++
+				}
+				continue
+			}
+		}
+		if err == errStdinTimeout {
+			writer.Flush()
+			return written, errStdinTimeout
 		} else if err != nil {
-			fmt.Errorf(err.Error())
+			diag.reportError(name, "parse", err)
+			if _, ok := err.(*csv.ParseError); ok && opts.quarantine != nil {
+				if err := quarantine(); err != nil {
+					return written, err
+				}
+			}
+			continue
+		}
+		if opts.MaxColumns > 0 {
+			// the rows before it are still written
+			if err := checkMaxColumns(name, record, reader, opts.MaxColumns, diag); err != nil {
+				writer.Flush()
+				return written, err
+			}
+		}
+		if opts.AbortOnFieldCountChange {
+			if seen == 0 {
+				firstWidth = len(record)
+			} else if err := checkFieldCount(name, record, reader, firstWidth, diag); err != nil {
+				writer.Flush()
+				return written, err
+			}
+		}
+		seen++
+		isHeader := seen == 1 && !opts.NoHeader
+		if isHeader && opts.DedupHeaderRows {
+			headerRow = append([]string(nil), record...)
+		} else if headerRow != nil && equalRecords(record, headerRow) {
+			headerRows++
+			continue
+		}
+		if !isHeader {
+			dataRows++
+			if histogram != nil {
+				histogram[len(record)]++
+			}
+			if opts.Rows != nil && !opts.Rows.contains(dataRows) {
+				continue
+			}
+			if opts.record != nil && !opts.record.contains(reader, record) {
+				if opts.record.passed(reader) {
+					break
+				}
+				continue
+			}
 		}
 
-		for i, v := range record {
-			record[i] = replacer.Replace(v)
-			if removeSpace {
-				record[i] = strings.TrimSpace(reTrS.ReplaceAllString(record[i], " "))
+		if swapQuote {
+			for i, v := range record {
+				record[i] = swapRunes(v, opts.QuoteChar, '"')
 			}
 		}
+		if opts.Retab != "" && opts.Comma == '\t' {
+			retabTrim(record)
+		}
 
-		if err := printFunc(writer, record); err != nil {
-			fmt.Errorf(err.Error())
+		if seen == 1 && (opts.Pad || len(opts.Fill) > 0) {
+			width = len(record)
+			var header []string
+			if !opts.NoHeader {
+				header = record
+			}
+			if defaults, err = resolveFill(opts.Fill, header, opts.NoHeader); err != nil {
+				return written, err
+			}
 		}
-	}
-	writer.Flush()
 
+		if isHeader {
+			if opts.errorRows != nil {
+				if err := opts.errorRows.writeHeader(record); err != nil {
+					return written, err
+				}
+			}
+			if opts.PadTo > 0 {
+				record, _, _ = padTo(record, opts.PadTo, opts.PadTruncate)
+			}
+			if len(opts.RequireColumns) > 0 {
+				line, _ := reader.FieldPos(0)
+				if err := checkRequired(name, record, line, opts.RequireColumns, diag); err != nil {
+					return written, err
+				}
+			}
+			if opts.Numeric != nil {
+				if numIdx, err = opts.Numeric.bind(record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Formats) > 0 {
+				fmtIdx = make([][]int, len(opts.Formats))
+				for i, c := range opts.Formats {
+					if fmtIdx[i], err = c.bind(record, false); err != nil {
+						return written, err
+					}
+				}
+			}
+			if opts.Dates != nil {
+				if dateIdx, err = opts.Dates.bind(record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.NoTransformCols) > 0 {
+				if keep, err = resolveKeep(opts.NoTransformCols, record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.ReplaceRegexCols) > 0 {
+				if replaced, err = resolveKeep(opts.ReplaceRegexCols, record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.TrimCols) > 0 || len(opts.CollapseCols) > 0 {
+				if spaces, err = resolveSpaceCols(opts.TrimCols, opts.CollapseCols, record, false); err != nil {
+					return written, err
+				}
+			}
+			if !opts.Case.empty() {
+				if casers, err = resolveCaseCols(opts.Case, record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Ranges) > 0 {
+				if rangeIdx, err = resolveRanges(opts.Ranges, record, false); err != nil {
+					return written, err
+				}
+			}
+			if unique != nil {
+				if err = unique.bind(record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Select) > 0 || opts.ColumnsRegex != nil {
+				var names []string
+				if len(opts.Select) > 0 {
+					if indices, err = resolveSelect(opts.Select, record); err != nil {
+						return written, err
+					}
+					names = selectHeader(opts.Select)
+				}
+				if opts.ColumnsRegex != nil {
+					matched := matchColumns(record, opts.ColumnsRegex, indices)
+					if len(indices)+len(matched) == 0 {
+						return written, fmt.Errorf("no column matches -columns-regex %q", opts.ColumnsRegex)
+					}
+					for _, n := range matched {
+						names = append(names, record[n])
+					}
+					indices = append(indices, matched...)
+				}
+				if opts.ProjectionOrder == ProjectionSource {
+					sourceOrder(indices, names)
+				}
+				record = names
+			}
+			if len(opts.Exclude) > 0 {
+				if excluded, err = resolveKeep(opts.Exclude, record, false); err != nil {
+					return written, err
+				}
+				excludeW = len(record)
+				indices = keptColumns(excludeW, excluded)
+				record = project(record, indices)
+			}
+			// the columns of the output, after -split and -merge
+			merged := record
+			if len(opts.Splits) > 0 {
+				if splits, merged, err = bindSplits(opts.Splits, merged, false, opts.SplitKeep); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Merges) > 0 {
+				if merges, merged, err = bindMerges(opts.Merges, merged, false, opts.MergeDrop); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Coalesces) > 0 {
+				if coalesce, merged, err = bindCoalesces(opts.Coalesces, merged, false, opts.CoalesceDrop); err != nil {
+					return written, err
+				}
+			}
+			if opts.PartitionBy != "" {
+				if partIdx, err = columnIndex(opts.PartitionBy, headerIndex(merged), false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Lookups) > 0 {
+				if lookups, err = bindLookups(opts.Lookups, record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Rules) > 0 {
+				if rules, err = bindRules(opts.Rules, record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Casts) > 0 {
+				if castIdx, err = bindCasts(opts.Casts, record, false); err != nil {
+					return written, err
+				}
+			}
+			if len(opts.Pseudonymize) > 0 {
+				if pseudoIdx, err = bindPseudonymize(opts.Pseudonymize, record, false); err != nil {
+					return written, err
+				}
+			}
+			if opts.HashColumn != "" {
+				if hashIdx, err = resolveHashCols(opts.HashCols, merged, false); err != nil {
+					return written, err
+				}
+			}
+		} else {
+			before := diag.reportedCount()
+			if opts.errorRows != nil {
+				diag.watch()
+				errorRow = append([]string(nil), record...)
+			}
+			checked++
+			lintRecord(name, record, reader, diag, keep, opts)
+			if rangeIdx != nil {
+				checkRanges(name, record, reader, diag, rangeIdx, opts)
+			}
+			if unique != nil {
+				if err := unique.check(name, record, reader, diag); err != nil {
+					return written, err
+				}
+			}
+			if numIdx != nil {
+				opts.Numeric.check(name, record, reader, diag, numIdx)
+			}
+			for i, c := range opts.Formats {
+				c.check(name, record, reader, diag, fmtIdx[i])
+			}
+			inWindow := opts.Dates == nil || opts.Dates.contains(name, record, reader, diag, dateIdx)
+			if opts.quarantine != nil {
+				if diag.reportedCount() > before {
+					if err := quarantine(); err != nil {
+						return written, err
+					}
+					continue
+				}
+				passed++
+			}
+			if !inWindow {
+				outside++
+				continue
+			}
+			short := false
+			if width > 0 {
+				record, short = pad(record, width, defaults)
+			}
+			if opts.PadTo > 0 {
+				var extended, long bool
+				record, extended, long = padTo(record, opts.PadTo, opts.PadTruncate)
+				short = short || extended
+				if long {
+					cut++
+				}
+			}
+			if short {
+				padded++
+			}
+			if excluded != nil && len(record) != excludeW {
+				// rows wider or narrower than the header keep their
+				// other fields too
+				excludeW = len(record)
+				indices = keptColumns(excludeW, excluded)
+			}
+			if opts.NoHeader && len(opts.Select) > 0 && !selectChecked {
+				// the positions must be in the first record written
+				if _, err := resolveSelect(opts.Select, record); err != nil {
+					writer.Flush()
+					return written, err
+				}
+				selectChecked = true
+			}
+			if indices != nil {
+				record = projectInPlace(record, indices)
+			}
+		}
+
+		for i, v := range record {
+			src := i
+			if indices != nil {
+				src = indices[i]
+			}
+			if keep[src] {
+				continue
+			}
+			if invisible != nil {
+				var n int
+				if v, n = stripInvisible(v); n > 0 && !isHeader {
+					invisible.add(i, n)
+				}
+			}
+			if opts.CleanText {
+				var invalid bool
+				if v, invalid = cleanText(v); invalid {
+					line, column := fieldPos(i)
+					diag.report(Diagnostic{File: name, Line: line, Column: column, Rule: "utf8", Message: "invalid UTF-8 replaced with U+FFFD"})
+				}
+			}
+			// the field is stored once, however many steps change it
+			v = replacer.Replace(v)
+			ops := spaces[src]
+			if opts.RemoveSpace || ops.collapse {
+				v = reTrS.ReplaceAllString(v, " ")
+			}
+			if opts.RemoveSpace || ops.trim || opts.CleanText {
+				v = strings.TrimSpace(v)
+			}
+			if c, ok := casers[src]; ok && !isHeader {
+				v = c.String(v)
+			}
+			if len(opts.ReplaceRegex) > 0 && !isHeader && (replaced == nil || replaced[src]) {
+				v = replaceRegex(v, opts.ReplaceRegex)
+			}
+			if opts.EscapeControl {
+				v = escapeControl(v)
+			}
+			record[i] = v
+		}
+		if invisible != nil && isHeader {
+			invisible.names = append([]string(nil), record...)
+		}
+
+		if lookups != nil && !isHeader {
+			applyLookups(name, record, opts.Lookups, lookups, opts.LookupMissing, fieldPos, diag)
+		}
+		if rules != nil && !isHeader {
+			record = applyRules(record, rules)
+		}
+		if castIdx != nil && !isHeader {
+			if err := applyCasts(name, record, opts.Casts, castIdx, opts.FloatPrecision, opts.Strict, fieldPos, diag); err != nil {
+				writer.Flush()
+				return written, err
+			}
+		}
+		if pseudoIdx != nil && !isHeader {
+			pseudonymize(record, pseudoIdx, opts.PseudonymizeSalt, opts.PseudonymizeEncoding)
+		}
+		for _, sp := range splits {
+			var parts int
+			if record, parts = sp.apply(record, opts.SplitSep, isHeader); parts > 0 {
+				line, _ := reader.FieldPos(0)
+				diag.report(Diagnostic{File: name, Line: line, Rule: "split", Message: fmt.Sprintf("%s: %d parts for %d columns, the last one holds the rest", sp.column, parts, len(sp.names))})
+			}
+		}
+		if merges != nil {
+			record = applyMerges(record, merges, opts.MergeSep, isHeader)
+		}
+		if coalesce != nil {
+			record = applyMerges(record, coalesce, "", isHeader)
+		}
+
+		if isHeader && len(opts.RenameRegex) > 0 {
+			if record, err = renameRegex(record, opts.RenameRegex); err != nil {
+				return written, err
+			}
+		}
+		if isHeader && opts.HeaderCase != "" {
+			if record, err = changeHeaderCase(record, opts.HeaderCase); err != nil {
+				return written, err
+			}
+		}
+
+		if opts.HashColumn != "" {
+			if isHeader {
+				record = append(record, opts.HashColumn)
+			} else {
+				record = append(record, rowHash(record, hashIdx))
+			}
+		}
+
+		if index != nil && (sample == nil || isHeader || opts.IndexOriginal) {
+			if isHeader && containsString(record, opts.AddIndex) {
+				return written, fmt.Errorf("-add-index: the header already has a column %q", opts.AddIndex)
+			}
+			record = index.prepend(record, isHeader, dataRows)
+		}
+
+		if sample != nil && !isHeader {
+			sample.add(record)
+			continue
+		}
+
+		if err := write(record, isHeader); err != nil {
+			return written, err
+		}
+		if opts.record != nil && opts.record.found || opts.schema != nil && opts.schema.only && opts.schema.finished() {
+			// the rest of the input is not read
+			break
+		}
+	}
+
+	if opts.AbortOnEmpty != "" {
+		if err := checkEmpty(name, opts.AbortOnEmpty, seen, dataRows, diag); err != nil {
+			writer.Flush()
+			return written, err
+		}
+	}
+	if err := writeErrorRow(); err != nil {
+		return written, err
+	}
+	if endings != nil {
+		endings.report(name, diag)
+	}
+	if histogram != nil {
+		histogram.report(diag)
+	}
+	if opts.Rows != nil && opts.Strict && dataRows < opts.Rows.max() {
+		diag.report(Diagnostic{File: name, Rule: "rows", Message: fmt.Sprintf("row %d was requested but there are only %d data rows", opts.Rows.max(), dataRows)})
+	}
+
+	if sample != nil {
+		for _, record := range sample.records() {
+			if index != nil && !opts.IndexOriginal {
+				record = index.prepend(record, false, 0)
+			}
+			if err := write(record, false); err != nil {
+				return written, err
+			}
+		}
+	}
+	if index != nil {
+		index.offset += dataRows
+	}
+
+	return written, writer.Flush()
+}
+
+// checkIdempotent transforms the output of a first pass once more and
+// reports the first line where the two passes disagree.
+func (cli *CLI) checkIdempotent(first []byte, diag *diagnostics, opts *Options) (bool, error) {
+	var second bytes.Buffer
+
+	again := *opts
+	again.SkipHeader = false
+	again.Select = nil
+	again.ColumnsRegex = nil
+	again.Exclude = nil
+	again.Encoding = ""
+	again.QuoteChar = 0
+	again.Ranges = nil
+	again.UniqueKey = nil
+	again.Numeric = nil
+	again.Formats = nil
+	again.Dates = nil
+	again.CheckLineEndings = false
+	again.quarantine = nil
+	again.errorRows = nil
+	again.rules = nil
+	again.schema = nil
+	again.aligned = nil
+	again.ExplodeJSON = ""
+	again.FlattenMultiline = 0
+	again.Rows = nil
+	again.Lookups = nil
+	again.Splits = nil
+	again.Merges = nil
+	again.Coalesces = nil
+	again.Casts = nil
+	again.Pseudonymize = nil
+	again.AddIndex = ""
+	again.index = nil
+	again.HashColumn = ""
+	again.Comma, _ = utf8.DecodeRuneInString(opts.outputDelimiter())
+	input := first
+	if !opts.TSV && !opts.PGCopy && opts.NullToken != "" && opts.Quote != QuoteNone {
+		input = emptyNullTokens(first, opts.outputDelimiter(), opts.NullToken)
+	}
+	if _, err := transform("", bytes.NewReader(input), &second, diag, &again); err != nil {
+		return false, err
+	}
+
+	a := bytes.Split(first, []byte("\n"))
+	b := bytes.Split(second.Bytes(), []byte("\n"))
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y []byte
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if !bytes.Equal(x, y) {
+			fmt.Fprintf(cli.errStream, "not idempotent: line %d differs\n  first:  %q\n  second: %q\n", i+1, x, y)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// emptyNullTokens returns the csv b with the unquoted fields that are the
+// null token left empty, so that they are read again as the empty fields
+// they were written for rather than as the token, which would be quoted.
+func emptyNullTokens(b []byte, delim, token string) []byte {
+	d := []byte(delim)
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		j := i
+		if b[i] == '"' {
+			for j++; j < len(b); j++ {
+				if b[j] == '"' {
+					if j+1 < len(b) && b[j+1] == '"' {
+						j++
+						continue
+					}
+					j++
+					break
+				}
+			}
+			out = append(out, b[i:j]...)
+		} else {
+			for j < len(b) && b[j] != '\n' && b[j] != '\r' && !bytes.HasPrefix(b[j:], d) {
+				j++
+			}
+			if string(b[i:j]) != token {
+				out = append(out, b[i:j]...)
+			}
+		}
+		i = j
+		// the delimiter or the line ending after the field
+		if bytes.HasPrefix(b[i:], d) {
+			out = append(out, d...)
+			i += len(d)
+		} else if i < len(b) {
+			out = append(out, b[i])
+			i++
+		}
+	}
+	return out
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// scanFlags walks args as flag.FlagSet.Parse walks them, calling fn with
+// the index of every argument that is a flag, but not those that are the
+// value of one, and reports whether a "--" ended the flags.
+func scanFlags(flags *flag.FlagSet, args []string, fn func(i int)) bool {
+	for i := 0; i < len(args); i++ {
+		s := args[i]
+		if s == "--" {
+			return true
+		}
+		if len(s) < 2 || s[0] != '-' {
+			return false
+		}
+		fn(i)
+		name := strings.TrimPrefix(s[1:], "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		f := flags.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			i++
+		}
+	}
+	return false
+}
+
+// flagsTerminated reports whether flags stopped parsing args at a "--",
+// and not at a "--" that is the value of a flag.
+func flagsTerminated(flags *flag.FlagSet, args []string) bool {
+	return scanFlags(flags, args, func(int) {})
+}
+
+// isTerminal is replaced in tests.
+var isTerminal = term.IsTerminal
+
+// stdinIsTerminal reports whether r is a terminal rather than a file or
+// pipe, in which case reading it would wait for the user to type a csv.
+func stdinIsTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && isTerminal(int(f.Fd()))
+}
+
+// Run invokes the CLI with the given arguments.
+func (cli *CLI) Run(args []string) int {
+	var (
+		opts            Options
+		checkIdempotent bool
+		file            string
+		fileWorkers     int
+		outFile         string
+		inPlace         inPlaceValue
+		gzipOut         bool
+		checkpointFile  string
+		checkpointEvery int
+		resume          bool
+		manifest        string
+		manifestRaw     bool
+		verify          string
+		timing          bool
+		yamlOut         bool
+		arrayDupes      bool
+		yamlTyped       bool
+		yamlColTypes    = mapValue{}
+		keyValue        bool
+		kvKey           string
+		kvValue         string
+		avroSchema      string
+		diffFile        string
+		sortSpec        string
+		sortExternal    bool
+		sortMemory      string
+		maxMemory       string
+		diffKey         string
+		parquetFile     string
+		parquetSchema   string
+		parquetRowGroup int
+		verifyManifest  *Manifest
+		selectSpec      string
+		columnsRegex    string
+		exclude         string
+		fill            = mapValue{}
+		splitRows       int
+		splitBytes      string
+		partitionOpen   int
+		explain         bool
+		delimiter       string
+		quoteChar       string
+		dialect         string
+		hashCols        string
+		ranges          rangesValue
+		maxErrors       int
+		sampleErrors    int
+		ruleSpecs       stringsValue
+		lookupSpecs     stringsValue
+		densityFormat   string
+		pretty          bool
+		wrap            int
+		explainRecord   int
+		schemaRows      schemaRowsValue
+		schemaOnly      bool
+		preview         previewValue
+		addIndex        indexValue
+		abortOnEmpty    emptyValue
+		stdinTimeout    time.Duration
+		countBy         string
+		groupBy         string
+		concatCols      string
+		concatSep       string
+		concatDrop      bool
+		valuesCol       string
+		valuesJSON      bool
+		keysNotIn       string
+		quarantineFile  string
+		errorsFile      string
+		ruleSummaryFile string
+		requireColumns  string
+		uniqueKey       string
+		ddlTable        string
+		noTransform     string
+		trimCols        string
+		regexSpecs      stringsValue
+		renameSpecs     stringsValue
+		castSpecs       stringsValue
+		pseudonymize    string
+		pseudoSalt      string
+		numericCols     stringsValue
+		emailCols       stringsValue
+		urlCols         stringsValue
+		numericLocale   string
+		since           string
+		until           string
+		dateCol         string
+		dateLayout      string
+		keepBadDates    bool
+		regexCols       string
+		collapseCols    string
+		upperCols       string
+		lowerCols       string
+		titleCols       string
+		caseLocale      string
+		lint            bool
+		validateOnly    bool
+		noTrailing      bool
+		rows            string
+		ddlDialect      string
+		limitWidth      int
+		report          string
+
+		version bool
+	)
+
+	// Define option flag parse
+	flags := flag.NewFlagSet(Name, flag.ContinueOnError)
+	flags.SetOutput(cli.errStream)
+
+	flags.BoolVar(&opts.RemoveTab, "remove-tab", false, "remove tab")
+	flags.BoolVar(&opts.RemoveTab, "t", false, "remove tab(Short)")
+	flags.BoolVar(&opts.RemoveNewline, "remove-newline", false, "remove newline in column; the same as -field-newline remove")
+	flags.BoolVar(&opts.RemoveNewline, "n", false, "remove newline in column(Short)")
+	flags.Var(newlineValue{opts: &opts}, "field-newline", "how newlines inside fields are written: escape (default), space, remove or keep, then cr=MODE and lf=MODE for carriage returns and line feeds alone, separated by commas")
+	flags.Var(newlineValue{opts: &opts, part: "cr"}, "cr-handling", "how carriage returns inside fields are written: escape, remove or keep; the same as -field-newline cr=MODE")
+	flags.Var(newlineValue{opts: &opts, part: "lf"}, "lf-handling", "how line feeds inside fields are written: escape, remove or keep; the same as -field-newline lf=MODE")
+	flags.Var(&regexSpecs, "replace-regex", "replace the matches of a regexp in every field, e.g. '-+=-' or '^id_(.*)=$1' (repeatable)")
+	flags.StringVar(&regexCols, "replace-regex-cols", "", "apply -replace-regex only to these comma separated columns")
+	flags.StringVar(&trimCols, "trim-cols", "", "trim white space from the fields of these comma separated columns only")
+	flags.StringVar(&collapseCols, "collapse-cols", "", "collapse runs of white space in the fields of these comma separated columns only")
+	flags.StringVar(&upperCols, "upper", "", "upper case the values of these comma separated columns")
+	flags.StringVar(&lowerCols, "lower", "", "lower case the values of these comma separated columns")
+	flags.StringVar(&titleCols, "title", "", "title case the values of these comma separated columns")
+	flags.StringVar(&caseLocale, "case-locale", "und", "BCP 47 language of -upper, -lower and -title, such as tr for the Turkish dotted i")
+	flags.BoolVar(&opts.RemoveSpace, "remove-space", false, "remove sparse spaces")
+	flags.BoolVar(&opts.RemoveSpace, "s", false, "remove sparse spaces(Short)")
+	flags.StringVar(&avroSchema, "avro", "", "write an avro object container file of records of the record schema in this .avsc file instead of csv")
+	flags.StringVar(&parquetFile, "parquet", "", "write a parquet file here instead of csv, with the column types inferred from the values or given by -parquet-schema")
+	flags.StringVar(&parquetSchema, "parquet-schema", "", "with -parquet, read the column names and types from this file instead of inferring them, so rows are not kept in memory")
+	flags.IntVar(&parquetRowGroup, "parquet-row-group", 100000, "with -parquet, the number of rows per row group")
+	flags.BoolVar(&keyValue, "keyvalue", false, "write a KEY=VALUE line per row, as in a .env file, from the first two columns or -kv-key and -kv-value")
+	flags.StringVar(&kvKey, "kv-key", "", "with -keyvalue, the column of the keys")
+	flags.StringVar(&kvValue, "kv-value", "", "with -keyvalue, the column of the values")
+	flags.BoolVar(&yamlOut, "yaml", false, "write a YAML sequence of mappings keyed by the header instead of csv")
+	flags.BoolVar(&arrayDupes, "array-dupes", false, "with -yaml, write the fields of columns sharing a name as a sequence under the one key")
+	flags.BoolVar(&yamlTyped, "yaml-types", false, "with -yaml, write the columns whose values are all numbers or all booleans as such, and empty fields and -null-token as null; the rows are held until the end")
+	flags.Var(yamlColTypes, "yaml-col-type", "with -yaml-types, write this column as int, float, bool or string whatever its other values, e.g. col=int (repeatable)")
+	flags.StringVar(&opts.Retab, "retab", "", "trim the spaces aligning the fields of a tab separated input, or also align the -tsv output: trim or align")
+	flags.BoolVar(&opts.TSV, "tsv", false, "output tsv")
+	flags.BoolVar(&opts.TSV, "T", false, "output tsv(Short)")
+	flags.StringVar(&opts.TSVNewline, "tsv-newline", "", "with -tsv, how newlines inside fields are written: escape (default), remove or space")
+	flags.StringVar(&opts.NBSPReplacement, "nbsp-replacement", " ", "replace no-break spaces(U+00A0) with this, escapes like \\t are decoded")
+	flags.StringVar(&report, "report", "text", "diagnostics format: text, json or sarif")
+	flags.BoolVar(&explain, "explain", false, "print the effective configuration and quit")
+	flags.BoolVar(&checkIdempotent, "check-idempotent", false, "fail if transforming the output again changes it")
+	flags.StringVar(&file, "file", "", "file")
+	flags.StringVar(&file, "f", "", "file(Short)")
+	flags.StringVar(&opts.ZipEntry, "zip-entry", "", "read this entry of input files ending in .zip, which otherwise must hold a single file")
+	flags.IntVar(&fileWorkers, "file-workers", 1, "number of input files processed concurrently")
+	flags.BoolVar(&opts.SkipHeader, "skip-header", false, "do not output the header row")
+	flags.BoolVar(&opts.PreserveComments, "preserve-comments", false, "copy lines starting with # to the output")
+	flags.StringVar(&opts.CommentPrefix, "comment-output-prefix", "#", "prefix written in place of # on preserved comment lines, empty drops them; they are dropped by default with -avro, -parquet, -pgcopy, -yaml and -keyvalue")
+	flags.BoolVar(&opts.CheckWhitespaceOnly, "check-whitespace-only", false, "report fields that contain only white space")
+	flags.BoolVar(&opts.FixWhitespaceOnly, "fix-whitespace-only", false, "empty fields that contain only white space")
+	flags.BoolVar(&opts.FieldHistogram, "field-histogram", false, "summarize how many data rows have each number of fields")
+	flags.BoolVar(&opts.AbortOnFieldCountChange, "abort-on-field-count-change", false, "fail at the first record whose number of fields differs from the first record's")
+	flags.IntVar(&opts.MaxColumns, "max-columns", 0, "fail on the first record with more than this many fields; 0 for no limit")
+	flags.BoolVar(&opts.CheckSmartChars, "check-smartchars", false, "report smart quotes, dashes, ellipses and no-break spaces")
+	flags.BoolVar(&opts.CleanText, "clean-text", false, "clean every field: replace invalid UTF-8 with U+FFFD and report it, normalize to NFC, remove zero-width characters, replace smart quotes, dashes and ellipses with ASCII and no-break spaces with -nbsp-replacement, then trim white space")
+	flags.BoolVar(&opts.FixSmartChars, "fix-smartchars", false, "replace smart quotes, dashes and ellipses with ASCII")
+	flags.BoolVar(&opts.StripInvisible, "strip-invisible", false, "remove invisible format characters, such as zero-width spaces and joiners and byte order marks, from every field and count them by column")
+	flags.BoolVar(&opts.CheckBOM, "check-bom", false, "report the inputs starting with a UTF-8 byte order mark, and count them")
+	flags.BoolVar(&opts.StripBOM, "strip-bom", false, "remove a UTF-8 byte order mark at the start of every input, so it does not end up in the first field")
+	flags.Func("preset", "apply the output settings for excel, git or postgres; later flags override them", func(name string) error {
+		return applyPreset(&opts, name)
+	})
+	flags.StringVar(&opts.Delimiter, "output-delimiter", ",", "csv output field delimiter")
+	flags.StringVar(&opts.Quote, "quote", QuoteAll, "csv output quoting: all, minimal or none")
+	flags.StringVar(&opts.EscapeDelimiter, "escape-delimiter", "", "with -quote none, write this character before delimiters inside fields")
+	flags.BoolVar(&noTrailing, "no-trailing-newline", false, "do not end the last line of the output with a line ending")
+	flags.BoolVar(&opts.CRLF, "crlf", false, "end output lines with CRLF")
+	flags.BoolVar(&opts.BOM, "bom", false, "start the output with a UTF-8 byte order mark")
+	flags.StringVar(&opts.NullToken, "null-token", "", "write empty fields as this token")
+	flags.BoolVar(&opts.PGCopy, "pgcopy", false, "write the text format of PostgreSQL's COPY, tab separated unless -output-delimiter is given, with \\N for empty fields and backslash escapes")
+	flags.StringVar(&noTransform, "no-transform-cols", "", "comma separated columns whose fields are written as parsed, without any of the field transforms")
+	flags.BoolVar(&opts.EscapeControl, "escape-control", false, "write control characters inside fields as \\xNN or \\uNNNN")
+	flags.BoolVar(&opts.Pad, "pad", false, "extend rows shorter than the header with empty fields")
+	flags.IntVar(&opts.PadTo, "pad-to", 0, "extend every row, and the header, to at least this many fields with empty ones")
+	flags.BoolVar(&opts.PadTruncate, "pad-truncate", false, "with -pad-to, also cut rows with more fields down to it")
+	flags.Var(fill, "fill", "extend short rows, filling the missing column with a default, e.g. col=DEFAULT (repeatable)")
+	flags.IntVar(&opts.Sample, "sample", 0, "output a random sample of this many data rows")
+	flags.Int64Var(&opts.Seed, "seed", 0, "random seed for -sample, defaults to a different one on every run")
+	flags.StringVar(&requireColumns, "require-columns", "", "fail unless the header has all of these comma separated columns, in any order")
+	flags.StringVar(&uniqueKey, "unique-key", "", "report the data rows whose fields in these comma separated key columns are those of an earlier row, with its line")
+	flags.Var(&ranges, "range", "report values of a column outside an inclusive range, e.g. col=MIN:MAX (repeatable)")
+	flags.Var(&emailCols, "validate-email", "report values of this column that are not email addresses (repeatable)")
+	flags.Var(&urlCols, "validate-url", "report values of this column that are not absolute URLs with a host (repeatable)")
+	flags.Var(&numericCols, "check-numeric", "report values of this column that are not numbers, which may group thousands, in the -numeric-locale (repeatable)")
+	flags.StringVar(&since, "since", "", "keep only the data rows whose -date-col is on or after this date")
+	flags.StringVar(&until, "until", "", "keep only the data rows whose -date-col is on or before this date")
+	flags.StringVar(&dateCol, "date-col", "", "the column of -since and -until")
+	flags.StringVar(&dateLayout, "date-layout", defaultDateLayout, "the Go time layout of -date-col, -since and -until")
+	flags.BoolVar(&keepBadDates, "keep-bad-dates", false, "with -since or -until, keep the rows whose date does not parse, which are still reported")
+	flags.StringVar(&numericLocale, "numeric-locale", "en", "how -check-numeric numbers are written: en (1,234.56), de (1.234,56) or fr (1 234,56)")
+	flags.BoolVar(&opts.RangeSkipEmpty, "range-skip-empty", false, "do not report empty values in -range columns")
+	flags.BoolVar(&opts.CheckLineEndings, "check-line-endings", false, "report whether the input uses LF or CRLF line endings, and the lines that differ when they are mixed")
+	flags.StringVar(&ruleSummaryFile, "rule-summary", "", "write the number of data rows checked and of problems by rule to this JSON file")
+	flags.StringVar(&errorsFile, "errors-csv", "", "write the records that fail a check to this CSV file with a column describing the problems")
+	flags.StringVar(&quarantineFile, "quarantine", "", "write the raw input of records that fail to parse or fail a check to this file instead of the output")
+	flags.BoolVar(&validateOnly, "validate-only", false, "like -lint, but write the problems of each row to stdout as a JSON line such as {\"line\":3,\"errors\":[...]}")
+	flags.BoolVar(&lint, "lint", false, "only check the input: write no records, check the files concurrently and fail if any problem is reported")
+	flags.IntVar(&maxErrors, "max-errors", 0, "show at most this many diagnostics, counting the rest; 0 shows all")
+	flags.IntVar(&sampleErrors, "sample-errors", 0, "instead of every problem, show this many examples spread over the rules and count the problems of each rule")
+	flags.BoolVar(&opts.Strict, "strict", false, "exit with an error when any problem is reported")
+	flags.Var(&lookupSpecs, "lookup", "replace the values of a column with those mapped by a key,value csv file, e.g. country=countries.csv (repeatable)")
+	flags.StringVar(&opts.LookupMissing, "lookup-missing", LookupKeep, "what -lookup does with unmapped values: keep, blank or report")
+	flags.Var(&ruleSpecs, "rule", `set a column when a row matches, e.g. 'status=="active" => name=upper(name)' (repeatable)`)
+	flags.StringVar(&ddlTable, "ddl", "", "instead of the records, output a CREATE TABLE statement for this table with the column types inferred from the values")
+	flags.StringVar(&ddlDialect, "ddl-dialect", "postgres", "sql dialect for -ddl: postgres, mysql or sqlite")
+	flags.StringVar(&densityFormat, "density", "", "instead of the records, output how many values of every column are not empty, as a table or json")
+	flags.StringVar(&valuesCol, "values", "", "instead of the records, output the sorted distinct values of this column, one per line")
+	flags.BoolVar(&valuesJSON, "json", false, "with -values or -keys-not-in, output the values as a JSON array")
+	flags.StringVar(&keysNotIn, "keys-not-in", "", "instead of the records, output the sorted distinct values of the -key column that are not in the same column of this csv file")
+	flags.StringVar(&countBy, "count-by", "", "instead of the records, output the number of rows for every value of these comma separated columns")
+	flags.StringVar(&groupBy, "group-by", "", "instead of the records, output one row for every value of these comma separated columns, joining the fields of the -concat columns")
+	flags.StringVar(&concatCols, "concat", "", "with -group-by, the comma separated columns whose fields are joined over the rows of a group, leaving out the empty ones")
+	flags.StringVar(&concatSep, "concat-sep", ",", "the separator -concat joins fields with")
+	flags.BoolVar(&concatDrop, "concat-drop", false, "with -group-by, leave out the columns other than those of -group-by and -concat instead of keeping the fields of the first row")
+	flags.IntVar(&opts.FlattenMultiline, "flatten-multiline", 0, "best effort: join lines with fewer than this many fields with the following ones, for records broken by unquoted newlines")
+	flags.StringVar(&opts.ExplodeJSON, "explode-json", "", "replace this column, holding a json object, with a column for every key")
+	flags.Var(&preview, "preview", "write the first rows, 10 or those of -preview=N, as an aligned table to stderr and stop reading")
+	flags.Var(&schemaRows, "preview-schema", "print the column types and nullability inferred from the first rows written to stderr, 100 or as in -preview-schema=N, then go on")
+	flags.BoolVar(&schemaOnly, "preview-schema-only", false, "print the schema of -preview-schema and stop")
+	flags.IntVar(&explainRecord, "explain-record", 0, "write the record at this input line one column per line, as column: value, and stop reading")
+	flags.BoolVar(&pretty, "pretty", false, "write an aligned table for reading in a terminal instead of csv")
+	flags.IntVar(&limitWidth, "limit-width", -1, "with -pretty, leave out trailing columns beyond this width, by default the terminal width; 0 for no limit")
+	flags.IntVar(&wrap, "wrap", 0, "with -pretty, wrap fields wider than this many columns onto more lines instead of leaving columns out")
+	flags.BoolVar(&opts.LineNumbers, "line-numbers", false, "with -pretty, -preview or -retab align, prepend the input line of every row")
+	flags.StringVar(&sortSpec, "sort", "", "write the data rows sorted by these comma separated columns, each compared as text or, followed by :n, as a number")
+	flags.BoolVar(&sortExternal, "sort-external", false, "with -sort, spill sorted runs to temporary files and merge them, for inputs larger than memory")
+	flags.StringVar(&sortMemory, "sort-memory", "256MB", "with -sort-external, the memory the rows may take before they are spilled")
+	flags.StringVar(&maxMemory, "max-memory", "", "stop, or with -sort spill to temporary files, before the rows and values kept in memory take more than this size, such as 512MB")
+	flags.StringVar(&diffFile, "diff", "", "write only the rows added or changed since this csv file, and then those removed, with a status column; needs -key")
+	flags.StringVar(&diffKey, "key", "", "with -diff, the column identifying a row; with -keys-not-in, the column compared")
+	flags.Var((*splitsValue)(&opts.Splits), "split", "split the fields of a column into new columns in its place, as col=a,b,c (repeatable)")
+	flags.StringVar(&opts.SplitSep, "split-sep", "", "the separator -split splits fields on, by default ; or , when the input delimiter is a comma or not")
+	flags.BoolVar(&opts.SplitKeep, "split-keep", false, "keep the columns split by -split, before their parts")
+	flags.Var((*mergesValue)(&opts.Merges), "merge", "append a column joining the fields of some columns, as a,b,c=name (repeatable)")
+	flags.StringVar(&opts.MergeSep, "merge-sep", " ", "the separator -merge joins fields with")
+	flags.BoolVar(&opts.MergeDrop, "merge-drop", false, "leave out the columns joined by -merge")
+	flags.Var((*coalescesValue)(&opts.Coalesces), "coalesce", "append a column with the first field of some columns that is not empty, as a,b,c=name (repeatable)")
+	flags.BoolVar(&opts.CoalesceDrop, "coalesce-drop", false, "leave out the columns read by -coalesce")
+	flags.DurationVar(&stdinTimeout, "stdin-timeout", 0, "fail with exit code 3 when stdin gives no data for this long, e.g. 30s")
+	flags.Var(&abortOnEmpty, "abort-on-empty", "fail an input without data rows, or with -abort-on-empty=file only one without any record")
+	flags.Var(&addIndex, "add-index", "put a column before every row with its position among the data rows written, named _row or as in -add-index=NAME, which needs the =")
+	flags.BoolVar(&opts.IndexOriginal, "index-original", false, "with -add-index, number the rows by their position in the input instead")
+	flags.StringVar(&opts.HashColumn, "hash-column", "", "append a column of this name with a hash of the normalized row")
+	flags.StringVar(&hashCols, "hash-cols", "", "comma separated columns to hash for -hash-column, all columns by default")
+	flags.StringVar(&delimiter, "delimiter", ",", "input field delimiter, escapes like \\t are decoded")
+	flags.StringVar(&delimiter, "d", ",", "input field delimiter(Short)")
+	flags.StringVar(&quoteChar, "quote-char", `"`, "input quote character")
+	flags.StringVar(&dialect, "dialect", "", "read the input delimiter, quote character, encoding and header settings from this json file")
+	flags.StringVar(&opts.Encoding, "encoding", "utf8", "input encoding: utf8, sjis, cp1252, utf16, utf16le, utf16be or auto")
+	flags.BoolVar(&opts.Verbose, "verbose", false, "log what csvlint detects about the input")
+	flags.BoolVar(&opts.NoHeader, "no-header", false, "the input has no header row")
+	flags.BoolVar(&opts.DedupHeaderRows, "dedup-header-rows", false, "drop data rows equal to the header row, as left by concatenating files")
+	flags.StringVar(&rows, "rows", "", "output only the data rows at these 1-based positions, e.g. 3,7,10-12, and the header")
+	flags.StringVar(&selectSpec, "select", "", "output only these columns, renamed, e.g. \"src:dst,other\"; 1-based positions with -no-header")
+	flags.Var(&castSpecs, "cast", "write the values of a column in the canonical form of a type, e.g. amount=float or day=date:02/01/2006; int, float, bool or date (repeatable)")
+	flags.IntVar(&opts.FloatPrecision, "float-precision", -1, "with -cast to float, write this many digits after the point instead of as few as needed")
+	flags.StringVar(&pseudonymize, "pseudonymize", "", "comma separated columns whose values are replaced by a keyed hash, the same for equal values, e.g. name,email")
+	flags.StringVar(&pseudoSalt, "pseudonymize-salt", "", "the key of the -pseudonymize hash, a random one on every run by default")
+	flags.StringVar(&opts.PseudonymizeEncoding, "pseudonymize-encoding", PseudonymizeHex, "write the -pseudonymize hashes in hex or base64")
+	flags.Var(&renameSpecs, "rename-regex", "rename the header names matching a regexp, e.g. '^col_=' or ' =_' (repeatable)")
+	flags.StringVar(&opts.HeaderCase, "header-case", "", "rewrite the header names in this case: lower, upper, snake or camel")
+	flags.StringVar(&exclude, "exclude", "", "drop these comma separated columns and keep the others in order; 1-based positions with -no-header")
+	flags.StringVar(&opts.ProjectionOrder, "projection-order", ProjectionList, "order of the -select and -columns-regex columns: list, as given, or source, as in the input")
+	flags.StringVar(&columnsRegex, "columns-regex", "", "also output every header column whose name matches this regexp, in header order")
+	flags.Var(&inPlace, "in-place", "replace the input file with the output, keeping the original with this suffix when given as -in-place=SUFFIX")
+	flags.Var(&inPlace, "i", "replace the input file with the output(Short)")
+	flags.StringVar(&outFile, "output", "", "write output to this file instead of stdout")
+	flags.StringVar(&outFile, "o", "", "write output to this file instead of stdout(Short)")
+	flags.StringVar(&checkpointFile, "checkpoint", "", "save how many records were processed to this file every -checkpoint-every records, for -resume")
+	flags.IntVar(&checkpointEvery, "checkpoint-every", defaultCheckpointEvery, "number of input records between two saves of -checkpoint")
+	flags.BoolVar(&resume, "resume", false, "with -checkpoint, carry on an interrupted run from its last save, appending to -output")
+	flags.IntVar(&splitRows, "split-rows", 0, "split the output into chunks of this many data rows, named after -output")
+	flags.StringVar(&splitBytes, "split-bytes", "", "split the output into chunks of at most this size, e.g. 100M")
+	flags.StringVar(&opts.PartitionBy, "partition-by", "", "write each row to a file named after -output and the value of this column")
+	flags.IntVar(&partitionOpen, "partition-max-open", 64, "number of partition files kept open at once")
+	flags.BoolVar(&gzipOut, "gzip-out", false, "gzip compress the output")
+	flags.StringVar(&manifest, "manifest", "", "write record count, byte count and sha256 of the output to this json file")
+	flags.BoolVar(&timing, "timing", false, "print the elapsed time and throughput to stderr at the end")
+	flags.StringVar(&verify, "verify", "", "fail unless the record count, byte count and sha256 of the output match this -manifest file")
+	flags.BoolVar(&manifestRaw, "manifest-uncompressed", false, "with -gzip-out, compute the manifest over the bytes before compression")
+
+	flags.BoolVar(&version, "version", false, "Print version information and quit.")
+
+	// Parse commandline flag
+	if err := flags.Parse(inPlaceSuffixArgs(flags, args[1:])); err != nil {
+		return ExitCodeError
+	}
+
+	// Show version
+	if version {
+		fmt.Fprintf(cli.errStream, "%s version %s\n", Name, Version)
+		return ExitCodeOK
+	}
+
+	if err := applyEnv(flags); err != nil {
+		fmt.Fprintln(cli.errStream, err)
+		return ExitCodeError
+	}
+
+	var err error
+	if opts.Comma, err = parseDelimiter(delimiter); err != nil {
+		fmt.Fprintln(cli.errStream, err)
+		return ExitCodeError
+	}
+	if opts.QuoteChar, err = parseQuoteChar(quoteChar); err != nil {
+		fmt.Fprintln(cli.errStream, err)
+		return ExitCodeError
+	}
+	if dialect != "" {
+		d, err := loadDialect(dialect)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		d.apply(flags, &opts)
+	}
+
+	switch opts.Quote {
+	case QuoteAll, QuoteMinimal, QuoteNone:
+	default:
+		fmt.Fprintf(cli.errStream, "invalid -quote %q: must be all, minimal or none\n", opts.Quote)
+		return ExitCodeError
+	}
+	if opts.TSV && opts.FieldNewline == NewlineKeep {
+		fmt.Fprintln(cli.errStream, "-field-newline keep cannot be combined with -tsv, which has no way to quote newlines")
+		return ExitCodeError
+	}
+	if opts.TSV && (opts.CRHandling == NewlineKeep || opts.LFHandling == NewlineKeep) {
+		fmt.Fprintln(cli.errStream, "-cr-handling keep and -lf-handling keep cannot be combined with -tsv, which has no way to quote newlines")
+		return ExitCodeError
+	}
+	if (opts.FieldNewline == NewlineKeep || opts.CRHandling == NewlineKeep || opts.LFHandling == NewlineKeep) && (splitRows > 0 || splitBytes != "") {
+		fmt.Fprintln(cli.errStream, "newlines kept inside fields cannot be combined with -split-rows or -split-bytes, which split the output by line")
+		return ExitCodeError
+	}
+	switch opts.TSVNewline {
+	case "", NewlineEscape, NewlineRemove, NewlineSpace:
+	default:
+		fmt.Fprintf(cli.errStream, "invalid -tsv-newline %q: must be escape, remove or space\n", opts.TSVNewline)
+		return ExitCodeError
+	}
+	if opts.FlattenMultiline < 0 {
+		fmt.Fprintln(cli.errStream, "-flatten-multiline must be a number of fields")
+		return ExitCodeError
+	} else if opts.FlattenMultiline > 0 && opts.PreserveComments {
+		fmt.Fprintln(cli.errStream, "-flatten-multiline cannot be combined with -preserve-comments")
+		return ExitCodeError
+	}
+	if opts.ExplodeJSON != "" && opts.PreserveComments {
+		fmt.Fprintln(cli.errStream, "-explode-json cannot be combined with -preserve-comments")
+		return ExitCodeError
+	}
+	if opts.EscapeDelimiter != "" {
+		if opts.Quote != QuoteNone || opts.TSV {
+			fmt.Fprintln(cli.errStream, "-escape-delimiter needs -quote none")
+			return ExitCodeError
+		}
+		if utf8.RuneCountInString(opts.EscapeDelimiter) != 1 || opts.EscapeDelimiter == opts.Delimiter {
+			fmt.Fprintf(cli.errStream, "invalid -escape-delimiter %q: must be a single character other than the delimiter\n", opts.EscapeDelimiter)
+			return ExitCodeError
+		}
+	}
+	if !validEncoding(opts.Encoding) {
+		fmt.Fprintf(cli.errStream, "invalid -encoding %q: must be utf8, sjis, cp1252, utf16, utf16le, utf16be or auto\n", opts.Encoding)
+		return ExitCodeError
+	}
+	if opts.Delimiter == "" {
+		fmt.Fprintln(cli.errStream, "-output-delimiter must not be empty")
+		return ExitCodeError
+	}
+
+	diag, err := newDiagnostics(cli.errStream, report)
+	if err != nil {
+		fmt.Fprintln(cli.errStream, err)
+		return ExitCodeError
+	}
+	if maxErrors < 0 {
+		fmt.Fprintln(cli.errStream, "-max-errors must not be negative")
+		return ExitCodeError
+	}
+	diag.max = maxErrors
+	if sampleErrors < 0 {
+		fmt.Fprintln(cli.errStream, "-sample-errors must not be negative")
+		return ExitCodeError
+	}
+	if sampleErrors > 0 && (maxErrors > 0 || validateOnly) {
+		fmt.Fprintln(cli.errStream, "-sample-errors cannot be combined with -max-errors or -validate-only")
+		return ExitCodeError
+	}
+	diag.sample = sampleErrors
+	if validateOnly {
+		if report != "text" {
+			fmt.Fprintln(cli.errStream, "-validate-only writes its own report and cannot be combined with -report")
+			return ExitCodeError
+		}
+		diag.rows = cli.outStream
+		lint = true
+	}
+	defer diag.flush()
+	if report != "text" {
+		// from here on every error is a diagnostic of the report, which
+		// is the only thing written to stderr
+		stderr := cli.errStream
+		cli.errStream = &errorWriter{d: diag}
+		defer func() { cli.errStream = stderr }()
+	}
+	if ruleSummaryFile != "" {
+		diag.perRule = map[string]int{}
+		opts.rules = &ruleSummary{file: ruleSummaryFile}
+	}
+
+	nbsp, err := unescape(opts.NBSPReplacement)
+	if err != nil {
+		fmt.Fprintf(cli.errStream, "invalid -nbsp-replacement %q: %s\n", opts.NBSPReplacement, err)
+		return ExitCodeError
+	}
+	if strings.ContainsAny(nbsp, opts.outputDelimiter()+"\r\n") {
+		fmt.Fprintf(cli.errStream, "invalid -nbsp-replacement %q: must not contain the delimiter or a newline\n", opts.NBSPReplacement)
+		return ExitCodeError
+	}
+	opts.NBSPReplacement = nbsp
+
+	opts.Fill = fill
+	opts.Ranges = ranges
+
+	if opts.Sample > 0 && !isFlagSet(flags, "seed") {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	if opts.PadTo < 0 {
+		fmt.Fprintln(cli.errStream, "-pad-to must be a number of fields")
+		return ExitCodeError
+	} else if opts.PadTruncate && opts.PadTo == 0 {
+		fmt.Fprintln(cli.errStream, "-pad-truncate needs -pad-to")
+		return ExitCodeError
+	}
+	if opts.MaxColumns < 0 {
+		fmt.Fprintln(cli.errStream, "-max-columns must not be negative")
+		return ExitCodeError
+	}
+
+	if opts.DedupHeaderRows && opts.NoHeader {
+		fmt.Fprintln(cli.errStream, "-dedup-header-rows cannot be combined with -no-header")
+		return ExitCodeError
+	}
+
+	if rows != "" {
+		if opts.Rows, err = parseRows(rows); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	if selectSpec != "" {
+		if opts.Select, err = parseSelect(selectSpec, opts.NoHeader); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	switch opts.HeaderCase {
+	case "", HeaderLower, HeaderUpper, HeaderSnake, HeaderCamel:
+	default:
+		fmt.Fprintf(cli.errStream, "invalid -header-case %q: must be lower, upper, snake or camel\n", opts.HeaderCase)
+		return ExitCodeError
+	}
+	if opts.ProjectionOrder != ProjectionList && opts.ProjectionOrder != ProjectionSource {
+		fmt.Fprintf(cli.errStream, "invalid -projection-order %q: must be list or source\n", opts.ProjectionOrder)
+		return ExitCodeError
+	}
+
+	if columnsRegex != "" {
+		if opts.NoHeader {
+			fmt.Fprintln(cli.errStream, "-columns-regex cannot be combined with -no-header")
+			return ExitCodeError
+		}
+		if opts.ColumnsRegex, err = regexp.Compile(columnsRegex); err != nil {
+			fmt.Fprintf(cli.errStream, "invalid -columns-regex %q: %s\n", columnsRegex, err)
+			return ExitCodeError
+		}
+	}
+
+	for _, spec := range ruleSpecs {
+		r, err := parseRule(spec)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.Rules = append(opts.Rules, r)
+	}
+
+	switch opts.LookupMissing {
+	case LookupKeep, LookupBlank, LookupReport:
+	default:
+		fmt.Fprintf(cli.errStream, "invalid -lookup-missing %q: must be keep, blank or report\n", opts.LookupMissing)
+		return ExitCodeError
+	}
+	for _, spec := range lookupSpecs {
+		l, err := loadLookup(spec)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.Lookups = append(opts.Lookups, l)
+	}
+
+	if noTransform != "" {
+		opts.NoTransformCols = strings.Split(noTransform, ",")
+	}
+	if len(emailCols) > 0 {
+		opts.Formats = append(opts.Formats, newEmailCheck(emailCols))
+	}
+	if len(urlCols) > 0 {
+		opts.Formats = append(opts.Formats, newURLCheck(urlCols))
+	}
+	if len(numericCols) > 0 {
+		if opts.Numeric, err = newNumericCheck(numericCols, numericLocale); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	} else if isFlagSet(flags, "numeric-locale") {
+		fmt.Fprintln(cli.errStream, "-numeric-locale needs -check-numeric")
+		return ExitCodeError
+	}
+	if since != "" || until != "" {
+		if dateCol == "" {
+			fmt.Fprintln(cli.errStream, "-since and -until need -date-col")
+			return ExitCodeError
+		}
+		if opts.Dates, err = newDateWindow(dateCol, dateLayout, since, until, keepBadDates); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	} else if dateCol != "" || isFlagSet(flags, "date-layout") || keepBadDates {
+		fmt.Fprintln(cli.errStream, "-date-col, -date-layout and -keep-bad-dates need -since or -until")
+		return ExitCodeError
+	}
+	if exclude != "" {
+		if selectSpec != "" || columnsRegex != "" {
+			fmt.Fprintln(cli.errStream, "-exclude cannot be combined with -select or -columns-regex")
+			return ExitCodeError
+		}
+		opts.Exclude = strings.Split(exclude, ",")
+		if opts.NoHeader {
+			if _, err := resolveKeep(opts.Exclude, nil, true); err != nil {
+				fmt.Fprintf(cli.errStream, "invalid -exclude %q: %s\n", exclude, err)
+				return ExitCodeError
+			}
+		}
+	}
+	for _, spec := range regexSpecs {
+		r, err := parseRegexReplace("replace-regex", spec)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.ReplaceRegex = append(opts.ReplaceRegex, r)
+	}
+	floats := false
+	for _, spec := range castSpecs {
+		c, err := parseCast(spec)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.Casts = append(opts.Casts, c)
+		floats = floats || c.typ == CastFloat
+	}
+	if isFlagSet(flags, "float-precision") && (!floats || opts.FloatPrecision < 0) {
+		fmt.Fprintln(cli.errStream, "-float-precision needs a -cast to float and must not be negative")
+		return ExitCodeError
+	}
+	if pseudonymize != "" {
+		opts.Pseudonymize = strings.Split(pseudonymize, ",")
+		if isFlagSet(flags, "pseudonymize-salt") {
+			opts.PseudonymizeSalt = []byte(pseudoSalt)
+		} else {
+			var err error
+			if opts.PseudonymizeSalt, err = randomSalt(); err != nil {
+				fmt.Fprintln(cli.errStream, err)
+				return ExitCodeError
+			}
+			opts.RandomSalt = true
+		}
+	} else if isFlagSet(flags, "pseudonymize-salt") || isFlagSet(flags, "pseudonymize-encoding") {
+		fmt.Fprintln(cli.errStream, "-pseudonymize-salt and -pseudonymize-encoding need -pseudonymize")
+		return ExitCodeError
+	}
+	if opts.PseudonymizeEncoding != PseudonymizeHex && opts.PseudonymizeEncoding != PseudonymizeBase64 {
+		fmt.Fprintf(cli.errStream, "invalid -pseudonymize-encoding %q: must be hex or base64\n", opts.PseudonymizeEncoding)
+		return ExitCodeError
+	}
+	for _, spec := range renameSpecs {
+		r, err := parseRegexReplace("rename-regex", spec)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.RenameRegex = append(opts.RenameRegex, r)
+	}
+	if regexCols != "" {
+		if len(opts.ReplaceRegex) == 0 {
+			fmt.Fprintln(cli.errStream, "-replace-regex-cols needs -replace-regex")
+			return ExitCodeError
+		}
+		opts.ReplaceRegexCols = strings.Split(regexCols, ",")
+	}
+	if trimCols != "" {
+		opts.TrimCols = strings.Split(trimCols, ",")
+	}
+	if collapseCols != "" {
+		opts.CollapseCols = strings.Split(collapseCols, ",")
+	}
+	if upperCols != "" {
+		opts.Case.upper = strings.Split(upperCols, ",")
+	}
+	if lowerCols != "" {
+		opts.Case.lower = strings.Split(lowerCols, ",")
+	}
+	if titleCols != "" {
+		opts.Case.title = strings.Split(titleCols, ",")
+	}
+	if opts.Case.locale, err = language.Parse(caseLocale); err != nil {
+		fmt.Fprintf(cli.errStream, "invalid -case-locale %q: %s\n", caseLocale, err)
+		return ExitCodeError
+	}
+
+	if len(opts.Splits) == 0 && (opts.SplitSep != "" || opts.SplitKeep) {
+		fmt.Fprintln(cli.errStream, "-split-sep and -split-keep need -split")
+		return ExitCodeError
+	} else if isFlagSet(flags, "split-sep") && opts.SplitSep == "" {
+		fmt.Fprintln(cli.errStream, "-split-sep cannot be empty")
+		return ExitCodeError
+	} else if opts.SplitSep == "" {
+		opts.SplitSep = defaultSplitSep(opts.Comma)
+	}
+	opts.AddIndex = string(addIndex)
+	opts.AbortOnEmpty = string(abortOnEmpty)
+	if opts.AddIndex == "" && (isFlagSet(flags, "add-index") || opts.IndexOriginal) {
+		fmt.Fprintln(cli.errStream, "-add-index needs a column name, and -index-original needs -add-index")
+		return ExitCodeError
+	} else if opts.AddIndex != "" {
+		opts.index = &rowIndexer{column: opts.AddIndex, original: opts.IndexOriginal}
+	}
+	if len(opts.Merges) == 0 && (isFlagSet(flags, "merge-sep") || opts.MergeDrop) {
+		fmt.Fprintln(cli.errStream, "-merge-sep and -merge-drop need -merge")
+		return ExitCodeError
+	}
+	if len(opts.Coalesces) == 0 && opts.CoalesceDrop {
+		fmt.Fprintln(cli.errStream, "-coalesce-drop needs -coalesce")
+		return ExitCodeError
+	}
+
+	if requireColumns != "" {
+		if opts.NoHeader {
+			fmt.Fprintln(cli.errStream, "-require-columns cannot be combined with -no-header")
+			return ExitCodeError
+		}
+		opts.RequireColumns = strings.Split(requireColumns, ",")
+	}
+	if uniqueKey != "" {
+		opts.UniqueKey = strings.Split(uniqueKey, ",")
+		opts.keys = newKeySet()
+	}
+
+	if hashCols != "" {
+		if opts.HashColumn == "" {
+			fmt.Fprintln(cli.errStream, "-hash-cols requires -hash-column")
+			return ExitCodeError
+		}
+		opts.HashCols = strings.Split(hashCols, ",")
+	}
+
+	if explain {
+		fmt.Fprint(cli.errStream, opts.String())
+		return ExitCodeOK
+	}
+
+	files := flags.Args()
+	if file != "" {
+		files = append([]string{file}, files...)
+	}
+
+	// "-f -" or a trailing "--" ask for stdin even when it is a terminal.
+	forceStdin := flagsTerminated(flags, args[1:])
+	if len(files) == 1 && files[0] == "-" {
+		files, forceStdin = nil, true
+	}
+	if len(files) == 0 && !forceStdin && stdinIsTerminal(cli.inStream) {
+		fmt.Fprintf(cli.errStream, "%s: no input file given and stdin is a terminal\n\n", Name)
+		fmt.Fprintf(cli.errStream, "Usage: %s [options] [file ...]\n", Name)
+		flags.PrintDefaults()
+		return ExitCodeError
+	}
+	if opts.ZipEntry != "" {
+		zipped := false
+		for _, name := range files {
+			zipped = zipped || isZip(name)
+		}
+		if !zipped {
+			fmt.Fprintln(cli.errStream, "-zip-entry needs an input file ending in .zip")
+			return ExitCodeError
+		}
+	}
+	// the temporary files to remove if the process is interrupted
+	var onSignal signalCleanup
+	defer onSignal.stop()
+
+	var inPlaceTemp string
+	if inPlace.enabled {
+		if len(files) != 1 {
+			fmt.Fprintln(cli.errStream, "-in-place needs a single input file")
+			return ExitCodeError
+		}
+		if isZip(files[0]) {
+			fmt.Fprintln(cli.errStream, "-in-place cannot rewrite an entry of a zip archive")
+			return ExitCodeError
+		}
+		if outFile != "" || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || lint || preview > 0 {
+			fmt.Fprintln(cli.errStream, "-in-place cannot be combined with -output, -partition-by, -split-rows, -split-bytes, -lint or -preview")
+			return ExitCodeError
+		}
+		if inPlaceTemp, err = createInPlaceTemp(files[0]); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		// Until it is renamed over the input, any failure leaves the input
+		// as it was.
+		defer func() {
+			if inPlaceTemp != "" {
+				os.Remove(inPlaceTemp)
+			}
+		}()
+		temp := inPlaceTemp
+		onSignal.add(func() { os.Remove(temp) })
+		outFile = inPlaceTemp
+	}
+	if noTrailing && (opts.PartitionBy != "" || splitRows > 0 || splitBytes != "") {
+		fmt.Fprintln(cli.errStream, "-no-trailing-newline cannot be combined with -partition-by, -split-rows or -split-bytes")
+		return ExitCodeError
+	}
+	if lint {
+		if opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || manifest != "" || verify != "" || checkIdempotent || pretty || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" {
+			fmt.Fprintln(cli.errStream, "-lint writes no output and cannot be combined with output options such as -partition-by, -split-rows, -manifest, -verify, -pretty, -count-by, -values, -ddl or -density")
+			return ExitCodeError
+		}
+		// -add-index counts the rows of the inputs in order, which one
+		// worker does
+		if !isFlagSet(flags, "file-workers") && opts.AddIndex == "" {
+			fileWorkers = runtime.NumCPU()
+		}
+		opts.Strict = true
+		opts.BOM = false
+		outFile = ""
+	}
+	if fileWorkers < 1 {
+		fmt.Fprintln(cli.errStream, "-file-workers must be at least 1")
+		return ExitCodeError
+	} else if fileWorkers > 1 && opts.AddIndex != "" {
+		fmt.Fprintln(cli.errStream, "-add-index numbers the rows across the inputs in order and cannot be combined with -file-workers")
+		return ExitCodeError
+	}
+
+	var (
+		dst    io.WriteCloser
+		digest *digestWriter
+		split  *splitWriter
+	)
+	stdout := cli.outStream
+	if densityFormat != "" {
+		if densityFormat != "table" && densityFormat != "json" {
+			fmt.Fprintf(cli.errStream, "invalid -density %q: must be table or json\n", densityFormat)
+			return ExitCodeError
+		}
+		if opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-density cannot be combined with -partition-by, -split-rows, -split-bytes or -check-idempotent")
+			return ExitCodeError
+		}
+		opts.density = new(density)
+		opts.BOM = false
+	}
+	if ddlTable != "" {
+		if _, ok := ddlTypes[ddlDialect]; !ok {
+			fmt.Fprintf(cli.errStream, "invalid -ddl-dialect %q: must be postgres, mysql or sqlite\n", ddlDialect)
+			return ExitCodeError
+		}
+		if opts.density != nil || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-ddl cannot be combined with -density, -partition-by, -split-rows, -split-bytes or -check-idempotent")
+			return ExitCodeError
+		}
+		opts.types = new(typeInference)
+		opts.BOM = false
+	}
+	if maxMemory != "" {
+		limit, err := parseSize(maxMemory)
+		if err != nil || limit == 0 {
+			fmt.Fprintf(cli.errStream, "invalid -max-memory %q\n", maxMemory)
+			return ExitCodeError
+		}
+		opts.memory = &memoryLimit{max: limit, spec: maxMemory}
+	}
+	if countBy != "" {
+		// the columns are bound in the header of the first file, which the
+		// rows of the other files would not wait for
+		if opts.types != nil || opts.density != nil || opts.PartitionBy != "" || fileWorkers > 1 || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-count-by cannot be combined with -ddl, -density, -partition-by, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		if opts.counts, err = newGroupCounter(strings.Split(countBy, ","), opts.NoHeader); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.counts.memory = opts.memory
+	}
+	if groupBy != "" {
+		if concatCols == "" {
+			fmt.Fprintln(cli.errStream, "-group-by needs -concat")
+			return ExitCodeError
+		}
+		if avroSchema != "" || parquetFile != "" || yamlOut || keyValue || opts.Retab == RetabAlign || sortSpec != "" || lint || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || diffFile != "" || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-group-by cannot be combined with other output formats than -tsv, -pgcopy and -pretty, nor with -sort, -lint, -count-by, -values, -ddl, -density, -diff, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		if opts.concat, err = newGroupConcat(strings.Split(groupBy, ","), strings.Split(concatCols, ","), concatSep, concatDrop, opts.NoHeader); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.concat.memory, opts.concat.skipHeader = opts.memory, opts.SkipHeader
+		opts.SkipHeader = false
+	} else if concatCols != "" || concatDrop || isFlagSet(flags, "concat-sep") {
+		fmt.Fprintln(cli.errStream, "-concat, -concat-sep and -concat-drop need -group-by")
+		return ExitCodeError
+	}
+	if sortSpec != "" {
+		if avroSchema != "" || parquetFile != "" || yamlOut || pretty || preview > 0 || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || lint || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-sort cannot be combined with other output formats, -lint, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		keys, err := parseSortKeys(sortSpec)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		memory, err := parseSize(sortMemory)
+		if err != nil {
+			fmt.Fprintf(cli.errStream, "invalid -sort-memory %q\n", sortMemory)
+			return ExitCodeError
+		}
+		if opts.memory != nil {
+			// spill rather than go over -max-memory
+			sortExternal = true
+			if opts.memory.max < memory {
+				memory = opts.memory.max
+			}
+		}
+		if opts.sorter, err = newSorter(keys, opts.NoHeader, sortExternal, memory); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		defer opts.sorter.cleanup()
+		if sortExternal {
+			onSignal.add(opts.sorter.cleanup)
+		}
+	} else if sortExternal || isFlagSet(flags, "sort-memory") {
+		fmt.Fprintln(cli.errStream, "-sort-external and -sort-memory need -sort")
+		return ExitCodeError
+	}
+	if keysNotIn != "" {
+		if diffKey == "" {
+			fmt.Fprintln(cli.errStream, "-keys-not-in needs -key")
+			return ExitCodeError
+		}
+		if diffFile != "" || valuesCol != "" || avroSchema != "" || parquetFile != "" || yamlOut || pretty || preview > 0 || countBy != "" || ddlTable != "" || densityFormat != "" || lint || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || checkIdempotent || explainRecord > 0 {
+			fmt.Fprintln(cli.errStream, "-keys-not-in cannot be combined with -diff, -values, other output formats, -lint, -partition-by, -split-rows, -split-bytes, -check-idempotent or -explain-record")
+			return ExitCodeError
+		}
+		// the same as -values of the key, less the keys of the other file
+		valuesCol = diffKey
+	}
+	if diffFile != "" {
+		if diffKey == "" {
+			fmt.Fprintln(cli.errStream, "-diff needs -key")
+			return ExitCodeError
+		}
+		if avroSchema != "" || parquetFile != "" || yamlOut || pretty || preview > 0 || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || lint || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent || opts.Sample > 0 || opts.AddIndex != "" {
+			fmt.Fprintln(cli.errStream, "-diff cannot be combined with other output formats, -lint, -partition-by, -split-rows, -split-bytes, -file-workers, -check-idempotent, -sample or -add-index")
+			return ExitCodeError
+		}
+		opts.diff = newDiffer(diffFile, diffKey, opts.NoHeader, opts.memory)
+		// the header binds the columns, and always gets the status column
+		opts.SkipHeader = false
+	} else if diffKey != "" && keysNotIn == "" {
+		fmt.Fprintln(cli.errStream, "-key needs -diff or -keys-not-in")
+		return ExitCodeError
+	}
+	if opts.PreserveComments && (avroSchema != "" || parquetFile != "" || opts.PGCopy || yamlOut || keyValue) {
+		// a comment line would be read as data by these formats
+		if !isFlagSet(flags, "comment-output-prefix") {
+			opts.CommentPrefix = ""
+		} else if opts.CommentPrefix != "" && !yamlOut && !keyValue {
+			fmt.Fprintln(cli.errStream, "-comment-output-prefix must be empty with -avro, -parquet or -pgcopy")
+			return ExitCodeError
+		}
+	}
+	if avroSchema != "" {
+		if yamlOut || opts.TSV || pretty || preview > 0 || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent || opts.Sample > 0 || noTrailing {
+			fmt.Fprintln(cli.errStream, "-avro cannot be combined with other output formats, -partition-by, -split-rows, -split-bytes, -file-workers, -check-idempotent, -sample or -no-trailing-newline")
+			return ExitCodeError
+		}
+		if opts.avro, err = newAvroWriter(avroSchema); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		// the header maps the columns to fields, and is never written
+		opts.SkipHeader = false
+		opts.BOM = false
+	}
+	if parquetFile != "" {
+		if avroSchema != "" || yamlOut || opts.TSV || pretty || preview > 0 || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || lint || outFile != "" || gzipOut || manifest != "" || verify != "" || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent || opts.Sample > 0 || noTrailing {
+			fmt.Fprintln(cli.errStream, "-parquet cannot be combined with other output formats, -lint, -output, -gzip-out, -manifest, -verify, -partition-by, -split-rows, -split-bytes, -file-workers, -check-idempotent, -sample or -no-trailing-newline")
+			return ExitCodeError
+		}
+		if parquetRowGroup < 1 {
+			fmt.Fprintln(cli.errStream, "-parquet-row-group must be at least 1")
+			return ExitCodeError
+		}
+		if opts.parquet, err = newParquetWriter(parquetSchema, parquetRowGroup, opts.NullToken); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.parquet.memory = opts.memory
+		// the header names the columns, and is never written as a row
+		opts.SkipHeader = false
+		opts.BOM = false
+	} else if parquetSchema != "" || isFlagSet(flags, "parquet-row-group") {
+		fmt.Fprintln(cli.errStream, "-parquet-schema and -parquet-row-group need -parquet")
+		return ExitCodeError
+	}
+	if (arrayDupes || yamlTyped) && !yamlOut {
+		fmt.Fprintln(cli.errStream, "-array-dupes and -yaml-types need -yaml")
+		return ExitCodeError
+	}
+	if len(yamlColTypes) > 0 && !yamlTyped {
+		fmt.Fprintln(cli.errStream, "-yaml-col-type needs -yaml-types")
+		return ExitCodeError
+	}
+	if yamlOut {
+		if opts.TSV || pretty || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-yaml cannot be combined with -tsv, -pretty, -count-by, -values, -ddl, -density, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		opts.yaml = &yamlWriter{arrays: arrayDupes}
+		if yamlTyped {
+			if opts.sorter != nil {
+				fmt.Fprintln(cli.errStream, "-yaml-types cannot be combined with -sort")
+				return ExitCodeError
+			}
+			for col, kind := range yamlColTypes {
+				if !validYAMLKind(kind) {
+					fmt.Fprintf(cli.errStream, "invalid -yaml-col-type %s=%s: must be int, float, bool or string\n", col, kind)
+					return ExitCodeError
+				}
+			}
+			opts.yaml.typed = &yamlTypes{nullToken: opts.NullToken, columns: yamlColTypes, noHeader: opts.NoHeader, memory: opts.memory}
+		}
+		// the header gives the keys, and is never written as a row
+		opts.SkipHeader = false
+		opts.BOM = false
+	}
+	if opts.PGCopy {
+		if avroSchema != "" || parquetFile != "" || yamlOut || keyValue || opts.TSV || pretty || preview > 0 || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || isFlagSet(flags, "preset") || isFlagSet(flags, "quote") || opts.EscapeDelimiter != "" || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-pgcopy cannot be combined with other output formats, -preset, -quote, -escape-delimiter or -check-idempotent")
+			return ExitCodeError
+		}
+		if !isFlagSet(flags, "output-delimiter") {
+			opts.Delimiter = "\t"
+		} else if !validPgDelimiter(opts.Delimiter) {
+			fmt.Fprintf(cli.errStream, "invalid -output-delimiter %q for -pgcopy: must be a single ASCII character other than a letter, a digit, a backslash, a period or a line ending\n", opts.Delimiter)
+			return ExitCodeError
+		}
+		if opts.concat != nil {
+			opts.concat.skipHeader = true
+		}
+		opts.BOM = false
+	}
+	if keyValue {
+		if avroSchema != "" || parquetFile != "" || yamlOut || opts.TSV || pretty || countBy != "" || valuesCol != "" || ddlTable != "" || densityFormat != "" || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent || opts.sorter != nil || opts.diff != nil {
+			fmt.Fprintln(cli.errStream, "-keyvalue cannot be combined with other output formats, -sort, -diff, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		opts.keyValue = &keyValueWriter{key: kvKey, value: kvValue, noHeader: opts.NoHeader}
+		// the header names the columns, and is never written as a row
+		opts.SkipHeader = false
+		opts.BOM = false
+	} else if kvKey != "" || kvValue != "" {
+		fmt.Fprintln(cli.errStream, "-kv-key and -kv-value need -keyvalue")
+		return ExitCodeError
+	}
+	if valuesCol != "" {
+		// the column is bound in the header of the first file, which the
+		// rows of the other files would not wait for
+		if opts.counts != nil || opts.types != nil || opts.density != nil || pretty || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-values cannot be combined with -count-by, -ddl, -density, -pretty, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		if opts.values, err = newDistinctValues(valuesCol, opts.NoHeader); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.values.memory = opts.memory
+		opts.BOM = false
+		// the header is needed to find the column, and never written
+		opts.SkipHeader = false
+	} else if valuesJSON {
+		fmt.Fprintln(cli.errStream, "-json needs -values")
+		return ExitCodeError
+	}
+	if preview > 0 {
+		if rows != "" || lint || outFile != "" || gzipOut || manifest != "" || verify != "" || opts.yaml != nil || opts.values != nil || opts.counts != nil || opts.types != nil || opts.density != nil || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || checkIdempotent || noTrailing || report != "text" {
+			fmt.Fprintln(cli.errStream, "-preview writes to stderr and cannot be combined with -rows, -report or other output options")
+			return ExitCodeError
+		}
+		opts.Rows = rowSet{{1, int(preview)}}
+		pretty, stdout = true, cli.errStream
+	}
+	if explainRecord < 0 {
+		fmt.Fprintln(cli.errStream, "-explain-record must be a line number")
+		return ExitCodeError
+	} else if explainRecord > 0 {
+		if len(files) > 1 || rows != "" || lint || pretty || preview > 0 || opts.avro != nil || opts.parquet != nil || opts.yaml != nil || opts.values != nil || opts.counts != nil || opts.types != nil || opts.density != nil || opts.sorter != nil || opts.diff != nil || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || opts.Sample > 0 || checkIdempotent || inPlace.enabled {
+			fmt.Fprintln(cli.errStream, "-explain-record needs a single input and cannot be combined with -rows, -sample or other output options")
+			return ExitCodeError
+		}
+		opts.record = &recordDump{line: explainRecord}
+		// the header names the columns, and is never written as a row
+		opts.SkipHeader = false
+		opts.BOM = false
+	}
+	if schemaOnly {
+		if lint || pretty || preview > 0 || opts.avro != nil || opts.parquet != nil || opts.yaml != nil || opts.values != nil || opts.counts != nil || opts.types != nil || opts.density != nil || opts.sorter != nil || opts.diff != nil || opts.record != nil || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || opts.Sample > 0 || checkIdempotent || inPlace.enabled {
+			fmt.Fprintln(cli.errStream, "-preview-schema-only writes no output and cannot be combined with -lint, -sample or other output options")
+			return ExitCodeError
+		}
+		if schemaRows.n == 0 {
+			schemaRows.n = defaultSchemaRows
+		}
+	}
+	if arg := flags.Arg(0); schemaRows.bare && arg != "" {
+		// -preview-schema 10 reads 10 as an input
+		if _, err := strconv.Atoi(arg); err == nil {
+			if _, err := os.Stat(arg); err != nil {
+				fmt.Fprintf(cli.errStream, "-preview-schema takes the number of rows as -preview-schema=%s; there is no input file %q\n", arg, arg)
+				return ExitCodeError
+			}
+		}
+	}
+	if schemaRows.n > 0 {
+		opts.schema = &schemaPreview{w: cli.errStream, diag: diag, rows: schemaRows.n, only: schemaOnly, nullToken: opts.NullToken}
+	}
+	if pretty {
+		if opts.types != nil || opts.density != nil || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || checkIdempotent {
+			fmt.Fprintln(cli.errStream, "-pretty cannot be combined with -ddl, -density, -partition-by, -split-rows, -split-bytes or -check-idempotent")
+			return ExitCodeError
+		}
+		if wrap < 0 {
+			fmt.Fprintln(cli.errStream, "-wrap must be a positive width")
+			return ExitCodeError
+		} else if wrap > 0 {
+			if limitWidth >= 0 {
+				fmt.Fprintln(cli.errStream, "-wrap cannot be combined with -limit-width")
+				return ExitCodeError
+			}
+			limitWidth = 0
+		}
+		if limitWidth < 0 {
+			limitWidth = terminalWidth(stdout)
+		}
+		opts.pretty = &prettyTable{limit: limitWidth, wrap: wrap, memory: opts.memory}
+		opts.BOM = false
+	} else if wrap != 0 {
+		fmt.Fprintln(cli.errStream, "-wrap needs -pretty or -preview")
+		return ExitCodeError
+	}
+	if opts.LineNumbers {
+		if opts.pretty == nil && opts.Retab != RetabAlign {
+			fmt.Fprintln(cli.errStream, "-line-numbers needs -pretty, -preview or -retab align")
+			return ExitCodeError
+		}
+		if opts.Sample > 0 {
+			fmt.Fprintln(cli.errStream, "-line-numbers cannot be combined with -sample, which writes the rows once the input is done")
+			return ExitCodeError
+		}
+	}
+	switch opts.Retab {
+	case "", RetabTrim, RetabAlign:
+	default:
+		fmt.Fprintf(cli.errStream, "invalid -retab %q: must be trim or align\n", opts.Retab)
+		return ExitCodeError
+	}
+	if opts.Retab == RetabTrim && opts.Comma != '\t' {
+		fmt.Fprintln(cli.errStream, "-retab trim needs a tab separated input, -delimiter '\\t'")
+		return ExitCodeError
+	}
+	if opts.Retab == RetabAlign {
+		if !opts.TSV {
+			fmt.Fprintln(cli.errStream, "-retab align needs -tsv")
+			return ExitCodeError
+		}
+		if pretty || opts.avro != nil || opts.parquet != nil || opts.yaml != nil || opts.values != nil || opts.counts != nil || opts.types != nil || opts.density != nil || opts.sorter != nil || opts.diff != nil || opts.record != nil || opts.PartitionBy != "" || splitRows > 0 || splitBytes != "" || fileWorkers > 1 || checkIdempotent || schemaOnly {
+			fmt.Fprintln(cli.errStream, "-retab align cannot be combined with other output options, -sort, -diff, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent")
+			return ExitCodeError
+		}
+		opts.aligned = &tsvAligner{memory: opts.memory}
+	}
+	if checkpointFile != "" {
+		if len(files) != 1 {
+			fmt.Fprintln(cli.errStream, "-checkpoint needs a single input file, as stdin cannot be read again to resume")
+			return ExitCodeError
+		}
+		if outFile == "" {
+			fmt.Fprintln(cli.errStream, "-checkpoint needs -output, which -resume appends to")
+			return ExitCodeError
+		}
+		if gzipOut || manifest != "" || verify != "" || noTrailing || opts.sorter != nil || opts.Sample > 0 || rows != "" || opts.diff != nil || splitRows > 0 || splitBytes != "" || opts.PartitionBy != "" || inPlace.enabled || checkIdempotent || lint || pretty || opts.avro != nil || opts.parquet != nil || opts.yaml != nil || opts.keyValue != nil || opts.values != nil || opts.counts != nil || opts.concat != nil || opts.types != nil || opts.density != nil || opts.record != nil || opts.aligned != nil || schemaOnly {
+			fmt.Fprintln(cli.errStream, "-checkpoint cannot be combined with -gzip-out, -manifest, -verify, -no-trailing-newline, -sort, -sample, -rows, -diff, -split-rows, -split-bytes, -partition-by, -in-place, -check-idempotent or outputs other than csv and tsv")
+			return ExitCodeError
+		}
+		if opts.AddIndex != "" || len(opts.UniqueKey) > 0 || quarantineFile != "" || errorsFile != "" || opts.RandomSalt {
+			// a resumed run would start them over
+			fmt.Fprintln(cli.errStream, "-checkpoint does not save the state of -add-index, -unique-key, -quarantine, -errors-csv or a random -pseudonymize salt and cannot be combined with them")
+			return ExitCodeError
+		}
+		if checkpointEvery < 1 {
+			fmt.Fprintln(cli.errStream, "-checkpoint-every must be a positive number of records")
+			return ExitCodeError
+		}
+		opts.checkpoint = &checkpoint{file: checkpointFile, every: checkpointEvery, input: files[0]}
+	} else if resume || isFlagSet(flags, "checkpoint-every") {
+		fmt.Fprintln(cli.errStream, "-resume and -checkpoint-every need -checkpoint")
+		return ExitCodeError
+	}
+	var resumed *Checkpoint
+	if resume {
+		if resumed, err = loadCheckpoint(checkpointFile, files[0]); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if verify != "" {
+		if verifyManifest, err = loadManifest(verify); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.PartitionBy != "" {
+		if outFile == "" {
+			fmt.Fprintln(cli.errStream, "-partition-by needs -output as the base name of the partitions")
+			return ExitCodeError
+		}
+		if splitRows > 0 || splitBytes != "" || manifest != "" || verify != "" || checkIdempotent || fileWorkers > 1 {
+			fmt.Fprintln(cli.errStream, "-partition-by cannot be combined with -split-rows, -split-bytes, -manifest, -verify, -check-idempotent or -file-workers")
+			return ExitCodeError
+		}
+		if partitionOpen < 1 {
+			fmt.Fprintln(cli.errStream, "-partition-max-open must be at least 1")
+			return ExitCodeError
+		}
+		opts.partitions = newPartitioner(outFile, gzipOut, partitionOpen)
+		opts.partitions.bom = opts.BOM
+		// Everything goes to the partitions; nothing is left for -output.
+		stdout, outFile, opts.BOM = io.Discard, "", false
+	}
+
+	if splitRows > 0 || splitBytes != "" {
+		if outFile == "" {
+			fmt.Fprintln(cli.errStream, "-split-rows and -split-bytes need -output as the base name of the chunks")
+			return ExitCodeError
+		}
+		if manifest != "" || verify != "" {
+			fmt.Fprintln(cli.errStream, "-manifest and -verify cannot be combined with -split-rows or -split-bytes")
+			return ExitCodeError
+		}
+		split = &splitWriter{
+			open: func(i int) (*output, error) {
+				return openOutput(nil, chunkName(outFile, i), gzipOut, false, false)
+			},
+			maxRows:   splitRows,
+			hasHeader: !opts.SkipHeader && (!opts.NoHeader || selectHeader(opts.Select) != nil),
+		}
+		if splitBytes != "" {
+			if split.maxBytes, err = parseSize(splitBytes); err != nil {
+				fmt.Fprintln(cli.errStream, err)
+				return ExitCodeError
+			}
+		}
+		dst = split
+	} else if resumed != nil {
+		// the rows written after the last save are written again
+		if err := os.Truncate(outFile, resumed.OutputBytes); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		o, err := appendOutput(outFile, false)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		dst, opts.checkpoint.output = o, o.file
+		opts.checkpoint.skip, opts.checkpoint.saved = resumed.Records, resumed.Records
+		// the output has them already
+		opts.SkipHeader, opts.BOM = true, false
+	} else {
+		o, err := openOutput(stdout, outFile, gzipOut, manifest != "" || verify != "", manifestRaw)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		dst, digest = o, o.digest
+		if opts.checkpoint != nil {
+			opts.checkpoint.output = o.file
+		}
+	}
+	if noTrailing {
+		dst = &noTrailingNewline{w: dst, ending: []byte(opts.lineEnding())}
+	}
+	defer dst.Close()
+
+	if opts.BOM {
+		if _, err := io.WriteString(dst, "\uFEFF"); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	if quarantineFile != "" {
+		q, err := openOutput(nil, quarantineFile, false, false, false)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		opts.quarantine = &quarantine{w: q}
+		defer opts.quarantine.close()
+	}
+
+	if errorsFile != "" {
+		fp, err := openOutput(nil, errorsFile, false, false, false)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		defer fp.Close()
+		opts.errorRows = newErrorRows(fp)
+	}
+
+	var out io.Writer = dst
+	var first bytes.Buffer
+	if checkIdempotent {
+		out = &first
+	} else if lint || opts.avro != nil || opts.parquet != nil || opts.values != nil || opts.counts != nil || opts.concat != nil || opts.types != nil || opts.density != nil || opts.pretty != nil || opts.record != nil || opts.aligned != nil || schemaOnly {
+		out = io.Discard
+	}
+
+	if timing {
+		opts.read = new(byteCounter)
+	}
+	start := time.Now()
+	if opts.avro != nil {
+		if err := opts.avro.start(dst); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	var parquetOut *output
+	if opts.parquet != nil {
+		if parquetOut, err = openOutput(nil, parquetFile, false, false, false); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		defer parquetOut.Close()
+		if parquetSchema != "" {
+			opts.parquet.start(parquetOut)
+		}
+	}
+
+	// the other files are read as the input is, once every option is set
+	if opts.diff != nil {
+		if err := opts.diff.load(&opts, diag, inputSize(files)); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if keysNotIn != "" {
+		if opts.values.exclude, err = loadKeys(keysNotIn, diffKey, &opts, diag); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	var records int
+	if len(files) == 0 {
+		in := cli.inStream
+		if stdinTimeout > 0 {
+			in = newTimeoutReader(in, stdinTimeout)
+		}
+		if lint {
+			records, err = inLineOrder(diag, func(diag *diagnostics) (int, error) {
+				return transform("", in, out, diag, &opts)
+			})
+		} else {
+			records, err = transform("", in, out, diag, &opts)
+		}
+	} else {
+		records, err = transformFiles(files, out, diag, &opts, fileWorkers, lint)
+	}
+	if opts.rules != nil {
+		// written for a failed run too, which it is about
+		if err := opts.rules.write(diag.perRule); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if err == errFilesFailed || reported(err) {
+		return ExitCodeError
+	} else if err == errStdinTimeout {
+		fmt.Fprintf(cli.errStream, "no input on stdin for %s (-stdin-timeout)\n", stdinTimeout)
+		return ExitCodeTimeout
+	} else if err != nil {
+		fmt.Fprintln(cli.errStream, err)
+		return ExitCodeError
+	}
+	if opts.schema != nil {
+		if err := opts.schema.flush(); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	if checkIdempotent {
+		if _, err := dst.Write(first.Bytes()); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		ok, err := cli.checkIdempotent(first.Bytes(), diag, &opts)
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		if !ok {
+			return ExitCodeError
+		}
+	}
+
+	if opts.quarantine != nil {
+		if err := opts.quarantine.close(); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.errorRows != nil {
+		if err := opts.errorRows.close(); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		diag.count("rows written to -errors-csv", opts.errorRows.written)
+	}
+	if opts.avro != nil {
+		if err := opts.avro.flush(); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		if opts.avro.skipped > 0 {
+			diag.count("skipped rows", opts.avro.skipped)
+		}
+	}
+	if opts.sorter != nil {
+		n, err := opts.sorter.write(func(row []string) error {
+			return printerFor(&opts)(dst, row, &opts)
+		})
+		records += n
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.diff != nil {
+		err := opts.diff.finish(&opts, diag, func(row []string) error {
+			records++
+			return printerFor(&opts)(dst, row, &opts)
+		})
+		if err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.parquet != nil {
+		if err := opts.parquet.finish(parquetOut, diag); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		if err := parquetOut.Close(); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		if opts.parquet.skipped > 0 {
+			diag.count("skipped rows", opts.parquet.skipped)
+		}
+	}
+	if opts.values != nil {
+		if err := opts.values.write(dst, valuesJSON, &opts); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.concat != nil {
+		for _, row := range opts.concat.rows() {
+			if opts.pretty != nil {
+				if err := opts.pretty.add(row); err != nil {
+					fmt.Fprintln(cli.errStream, err)
+					return ExitCodeError
+				}
+			} else if err := printerFor(&opts)(dst, row, &opts); err != nil {
+				fmt.Fprintln(cli.errStream, err)
+				return ExitCodeError
+			}
+		}
+	}
+	if opts.counts != nil {
+		// The counts are written like any other output.
+		for _, row := range opts.counts.rows(&opts) {
+			if opts.pretty != nil {
+				if err := opts.pretty.add(row); err != nil {
+					fmt.Fprintln(cli.errStream, err)
+					return ExitCodeError
+				}
+			} else if err := printerFor(&opts)(dst, row, &opts); err != nil {
+				fmt.Fprintln(cli.errStream, err)
+				return ExitCodeError
+			}
+		}
+	}
+	if opts.types != nil {
+		if err := writeDDL(dst, opts.types, ddlTable, ddlDialect); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.density != nil {
+		if err := opts.density.write(dst, densityFormat); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.yaml != nil {
+		if err := opts.yaml.flush(dst); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.pretty != nil {
+		if err := opts.pretty.write(dst, &opts); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.aligned != nil {
+		if err := opts.aligned.write(dst, &opts); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if opts.record != nil {
+		if err := opts.record.write(dst); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+
+	if err := dst.Close(); err != nil {
+		fmt.Fprintln(cli.errStream, err)
+		return ExitCodeError
+	}
+	if opts.checkpoint != nil {
+		// the run is complete, there is nothing to resume
+		if err := os.Remove(checkpointFile); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+	}
+	if split != nil {
+		diag.count("chunks written", split.chunks)
+	}
+	if p := opts.partitions; p != nil {
+		if err := p.Close(); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		for _, name := range p.names {
+			diag.count("rows in "+name, p.rows[name])
+		}
+	}
+
+	if manifest != "" || verifyManifest != nil {
+		m := newManifest(records, digest, gzipOut && !manifestRaw)
+		if manifest != "" {
+			if err := writeManifest(manifest, m); err != nil {
+				fmt.Fprintln(cli.errStream, err)
+				return ExitCodeError
+			}
+		}
+		if verifyManifest != nil {
+			if diff := verifyManifest.diff(m); diff != "" {
+				fmt.Fprintf(cli.errStream, "output does not match %s:\n%s", verify, diff)
+				return ExitCodeError
+			}
+		}
+	}
+
+	if opts.read != nil {
+		diag.logf("%s", throughput(opts.read.n, records, time.Since(start)))
+	}
+
+	if opts.Strict && diag.reported > 0 {
+		return ExitCodeError
+	}
+	if inPlaceTemp != "" {
+		if err := replaceInPlace(inPlaceTemp, files[0], inPlace.suffix); err != nil {
+			fmt.Fprintln(cli.errStream, err)
+			return ExitCodeError
+		}
+		inPlaceTemp = ""
+	}
 	return ExitCodeOK
 }
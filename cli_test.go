@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"testing"
 )
@@ -24,28 +26,859 @@ func TestRun_versionFlag(t *testing.T) {
 }
 
 func TestRun_removeTabFlag(t *testing.T) {
+	inStream := strings.NewReader("a\tb,c\n")
 	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
-	cli := &CLI{outStream: outStream, errStream: errStream}
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
 	args := strings.Split("./csvlint -remove-tab", " ")
 
 	status := cli.Run(args)
-	_ = status
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+
+	expected := "\"ab\",\"c\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
 }
 
 func TestRun_removeNewlineFlag(t *testing.T) {
+	inStream := strings.NewReader("\"a\nb\",c\n")
 	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
-	cli := &CLI{outStream: outStream, errStream: errStream}
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
 	args := strings.Split("./csvlint -remove-newline", " ")
 
 	status := cli.Run(args)
-	_ = status
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+
+	expected := "\"ab\",\"c\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
 }
 
 func TestRun_tsvFlag(t *testing.T) {
+	inStream := strings.NewReader("a,\"b\tc\"\n")
 	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
-	cli := &CLI{outStream: outStream, errStream: errStream}
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
 	args := strings.Split("./csvlint -tsv", " ")
 
 	status := cli.Run(args)
-	_ = status
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+
+	expected := "a\tb\\tc\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_tsvNewlineFlag(t *testing.T) {
+	input := "id,note\n1,\"first line\r\nsecond\nthird\"\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -tsv", "id\tnote\n1\tfirst line\\nsecond\\nthird\n"},
+		{"./csvlint -tsv -tsv-newline remove", "id\tnote\n1\tfirst linesecondthird\n"},
+		{"./csvlint -tsv -tsv-newline space", "id\tnote\n1\tfirst line second third\n"},
+		{"./csvlint -tsv -remove-newline", "id\tnote\n1\tfirst linesecondthird\n"},
+		{"./csvlint -tsv -remove-newline -tsv-newline escape", "id\tnote\n1\tfirst line\\nsecond\\nthird\n"},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_fieldNewlineFlag(t *testing.T) {
+	input := "\"crlf\r\nend\",\"cr\rend\",\"lf\nend\",\"lflf\n\nend\"\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{"./csvlint -field-newline escape", ExitCodeOK, `"crlf\nend","cr\rend","lf\nend","lflf\n\nend"` + "\n"},
+		{"./csvlint -field-newline space", ExitCodeOK, "\"crlf end\",\"cr end\",\"lf end\",\"lflf  end\"\n"},
+		{"./csvlint -field-newline remove", ExitCodeOK, "\"crlfend\",\"crend\",\"lfend\",\"lflfend\"\n"},
+		{"./csvlint -field-newline keep", ExitCodeOK, "\"crlf\nend\",\"cr\rend\",\"lf\nend\",\"lflf\n\nend\"\n"},
+		{"./csvlint -field-newline keep -remove-newline", ExitCodeOK, "\"crlf\nend\",\"cr\rend\",\"lf\nend\",\"lflf\n\nend\"\n"},
+		{"./csvlint -field-newline space -tsv", ExitCodeOK, "crlf end\tcr end\tlf end\tlflf  end\n"},
+		{"./csvlint -field-newline space -tsv -tsv-newline remove", ExitCodeOK, "crlfend\tcrend\tlfend\tlflfend\n"},
+		{"./csvlint -field-newline keep -tsv", ExitCodeError, ""},
+		{"./csvlint -field-newline crlf", ExitCodeError, ""},
+		{"./csvlint -field-newline cr=space", ExitCodeError, ""},
+		{"./csvlint -field-newline crlf=keep", ExitCodeError, ""},
+		{"./csvlint -cr-handling space", ExitCodeError, ""},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != tt.status {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, tt.status, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_crLfHandlingFlags(t *testing.T) {
+	// The csv reader turns CRLF inside quoted fields into LF.
+	input := "\"crlf\r\nend\",\"cr\rend\",\"lf\nend\"\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint", `"crlf\nend","cr\rend","lf\nend"` + "\n"},
+		{"./csvlint -cr-handling remove", `"crlf\nend","crend","lf\nend"` + "\n"},
+		{"./csvlint -cr-handling keep -lf-handling remove", "\"crlfend\",\"cr\rend\",\"lfend\"\n"},
+		{"./csvlint -remove-newline -lf-handling keep", "\"crlf\nend\",\"crend\",\"lf\nend\"\n"},
+		{"./csvlint -tsv -tsv-newline space -cr-handling remove", "crlf end\tcrend\tlf end\n"},
+		{"./csvlint -field-newline cr=remove", `"crlf\nend","crend","lf\nend"` + "\n"},
+		{"./csvlint -field-newline remove,lf=keep", "\"crlf\nend\",\"crend\",\"lf\nend\"\n"},
+		{"./csvlint -field-newline cr=keep,lf=remove", "\"crlfend\",\"cr\rend\",\"lfend\"\n"},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -tsv -lf-handling keep", "-cr-handling keep and -lf-handling keep cannot be combined with -tsv, which has no way to quote newlines\n"},
+		{"./csvlint -cr-handling keep -split-rows 10 -o out.csv", "newlines kept inside fields cannot be combined with -split-rows or -split-bytes, which split the output by line\n"},
+		{"./csvlint -field-newline keep -split-bytes 1K -o out.csv", "newlines kept inside fields cannot be combined with -split-rows or -split-bytes, which split the output by line\n"},
+	} {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: new(bytes.Buffer), errStream: errStream}
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_checkIdempotentFlag(t *testing.T) {
+	inStream := strings.NewReader("a,\"b\nc\"\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -check-idempotent", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"a\",\"b\\nc\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+// The null token written for an empty field reads again as empty, and a
+// field that is the token itself stays quoted.
+func TestRun_checkIdempotentFlag_nullToken(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -check-idempotent -preset postgres", "1,\\N,\"\\N\",a\\nb\n"},
+		{"./csvlint -check-idempotent -null-token NULL -field-newline keep", "\"1\",NULL,\"\\N\",\"a\nb\"\n"},
+		{"./csvlint -check-idempotent -null-token NULL -quote minimal -crlf", "1,NULL,\\N,a\\nb\r\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("1,,\\N,\"a\nb\"\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+// The column -hash-column adds is not added again by the second pass.
+func TestRun_checkIdempotentFlag_hashColumn(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader("id,name\n1,a\n"), outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -check-idempotent -quote minimal -hash-column h", " ")
+
+	if status := cli.Run(args); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if !strings.HasPrefix(outStream.String(), "id,name,h\n1,a,") {
+		t.Errorf("expected %q to hold the hash column", outStream.String())
+	}
+}
+
+func TestRun_checkIdempotentFlag_notIdempotent(t *testing.T) {
+	inStream := strings.NewReader("\"\"\"a\"\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -tsv -check-idempotent", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	expected := "line 1 differs"
+	if !strings.Contains(errStream.String(), expected) {
+		t.Errorf("expected %q to contain %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_nbspReplacementFlag(t *testing.T) {
+	inStream := strings.NewReader("a\u00a0b,c\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-nbsp-replacement", `\u3000`}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"a\u3000b\",\"c\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_nbspReplacementFlag_delimiter(t *testing.T) {
+	inStream := strings.NewReader("a\u00a0b\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-tsv", "-nbsp-replacement", `\t`}
+
+	status := cli.Run(args)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+}
+
+func TestRun_selectFlag(t *testing.T) {
+	inStream := strings.NewReader("id,name,age\n1,alice,30\n2,bob\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -select age:years,name", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"years\",\"name\"\n\"30\",\"alice\"\n\"\",\"bob\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+// A column selected twice keeps the columns after it.
+func TestRun_selectFlag_twice(t *testing.T) {
+	inStream := strings.NewReader("id,name\n1,alice\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -select id,id:id2,name", " ")
+
+	if status := cli.Run(args); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"id\",\"id2\",\"name\"\n\"1\",\"1\",\"alice\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_selectFlag_unknownColumn(t *testing.T) {
+	inStream := strings.NewReader("id,name\n1,alice\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -select nope:x", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	expected := `unknown column "nope"`
+	if !strings.Contains(errStream.String(), expected) {
+		t.Errorf("expected %q to contain %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_selectFlag_noHeader(t *testing.T) {
+	inStream := strings.NewReader("1,alice\n2,bob\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -no-header -select 2:name,1:id", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"name\",\"id\"\n\"alice\",\"1\"\n\"bob\",\"2\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_selectFlag_noHeaderOutOfRange(t *testing.T) {
+	inStream := strings.NewReader("1,alice\n2,bob\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -no-header -select 1:id,5:x", " ")
+
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	expected := "unknown column \"5\": the first record has 2 fields\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+	// only the header of the target names is written
+	if outStream.String() != "\"id\",\"x\"\n" {
+		t.Errorf("expected no rows, got %q", outStream.String())
+	}
+}
+
+func TestRun_columnsRegexFlag(t *testing.T) {
+	input := "id,metric_2021,name,metric_2022\n1,10,a,20\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -columns-regex ^metric_", ExitCodeOK, "metric_2021,metric_2022\n10,20\n", ""},
+		{"./csvlint -quote minimal -select id,metric_2022:last -columns-regex ^metric_", ExitCodeOK, "id,last,metric_2021\n1,20,10\n", ""},
+		{"./csvlint -columns-regex ^x", ExitCodeError, "", "no column matches -columns-regex \"^x\"\n"},
+		{"./csvlint -columns-regex (", ExitCodeError, "", "invalid -columns-regex \"(\": error parsing regexp: missing closing ): `(`\n"},
+		{"./csvlint -columns-regex . -no-header", ExitCodeError, "", "-columns-regex cannot be combined with -no-header\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_projectionOrderFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		input    string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -select name,id", "id,metric,name\n1,10,a\n", ExitCodeOK, "name,id\na,1\n", ""},
+		{"./csvlint -quote minimal -select name,id -projection-order source", "id,metric,name\n1,10,a\n", ExitCodeOK, "id,name\n1,a\n", ""},
+		{"./csvlint -quote minimal -select name:n,metric:m -columns-regex ^i -projection-order source", "id,metric,name\n1,10,a\n", ExitCodeOK, "id,m,n\n1,10,a\n", ""},
+		{"./csvlint -quote minimal -no-header -select 3:c,1:a -projection-order source", "1,10,a\n", ExitCodeOK, "a,c\n1,a\n", ""},
+		{"./csvlint -projection-order input", "a\n", ExitCodeError, "", "invalid -projection-order \"input\": must be list or source\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(test.input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_preserveCommentsFlag(t *testing.T) {
+	inStream := strings.NewReader("# exported\nid,name\n1,\"a\n# b\"\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -tsv -preserve-comments -comment-output-prefix //", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "// exported\nid\tname\n1\ta\\n# b\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_preserveCommentsFlag_pgcopy(t *testing.T) {
+	cases := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{"./csvlint -pgcopy -preserve-comments", ExitCodeOK, "1\ta\n"},
+		{"./csvlint -pgcopy -preserve-comments -comment-output-prefix=", ExitCodeOK, "1\ta\n"},
+		{"./csvlint -pgcopy -preserve-comments -comment-output-prefix #", ExitCodeError, ""},
+	}
+	for _, c := range cases {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("# exported\nid,name\n1,a\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(c.args, " ")); status != c.status {
+			t.Errorf("%s: expected %d to eq %d: %s", c.args, status, c.status, errStream.String())
+		}
+		if outStream.String() != c.expected {
+			t.Errorf("%s: expected %q to eq %q", c.args, outStream.String(), c.expected)
+		}
+	}
+}
+
+func TestRun_checkWhitespaceOnlyFlag(t *testing.T) {
+	inStream := strings.NewReader("id,name\n1, \t\n2,\"\u00a0\u3000\"\n3,a b\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -check-whitespace-only -fix-whitespace-only", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "line 2 column 2: whitespace-only field\nline 3 column 2: whitespace-only field\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+
+	expected = "\"id\",\"name\"\n\"1\",\"\"\n\"2\",\"\"\n\"3\",\"a b\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_noTransformColsFlag(t *testing.T) {
+	inStream := strings.NewReader("id,html,note\n1,\"<p>a\u00a0 b</p>\n\",\" x\u00a0 y \"\n2,\" \",\" \"\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -remove-space -fix-whitespace-only -no-transform-cols html -select note,html", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"note\",\"html\"\n\"x y\",\"<p>a\u00a0 b</p>\n\"\n\"\",\" \"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_presetFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -preset excel", "\uFEFF\"a b\",\"\"\r\n"},
+		{"./csvlint -preset git", "a b,\n"},
+		{"./csvlint -preset git -quote all -null-token NULL", "\"a b\",NULL\n"},
+		{"./csvlint -preset postgres", "a b,\\N\n"},
+		{"./csvlint -preset postgres -null-token NULL", "a b,NULL\n"},
+		{"./csvlint -quote all -preset git", "a b,\n"},
+		{"./csvlint -preset excel -crlf=false -output-delimiter ;", "\uFEFF\"a b\";\"\"\n"},
+	}
+
+	for _, tt := range tests {
+		inStream := strings.NewReader("a b,\n")
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+
+	// a field that is the null token is quoted to tell it from an empty one
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader("\\N,,x\n"), outStream: outStream, errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-preset", "postgres"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if expected := "\"\\N\",\\N,x\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_quoteFlag_minimal(t *testing.T) {
+	inStream := strings.NewReader("a,\"b,c\",\" d\",\"e\"\"f\"\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -quote minimal", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "a,\"b,c\",\" d\",\"e\"\"f\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+// splitEscaped splits a line written with -quote none -escape-delimiter.
+func splitEscaped(line string, delim, esc rune) []string {
+	var fields []string
+	var field []rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			field = append(field, r)
+			escaped = false
+		case r == esc:
+			escaped = true
+		case r == delim:
+			fields = append(fields, string(field))
+			field = nil
+		default:
+			field = append(field, r)
+		}
+	}
+	return append(fields, string(field))
+}
+
+func TestRun_escapeDelimiterFlag(t *testing.T) {
+	fields := []string{"a,b", `c\d`, `e\,f`, "", "plain"}
+	var input bytes.Buffer
+	for i, v := range fields {
+		if i > 0 {
+			input.WriteString(",")
+		}
+		input.WriteString(`"` + strings.Replace(v, `"`, `""`, -1) + `"`)
+	}
+	input.WriteString("\n")
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: &input, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "none", "-escape-delimiter", `\`}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := `a\,b,c\\d,e\\\,f,,plain` + "\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	got := splitEscaped(strings.TrimSuffix(outStream.String(), "\n"), ',', '\\')
+	if strings.Join(got, "|") != strings.Join(fields, "|") || len(got) != len(fields) {
+		t.Errorf("expected %q to round-trip to %q", got, fields)
+	}
+}
+
+func TestRun_noTrailingNewlineFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		input    string
+		expected string
+	}{
+		{"./csvlint -quote minimal", "a\nb\n", "a\nb\n"},
+		{"./csvlint -quote minimal -no-trailing-newline", "a\nb\n", "a\nb"},
+		{"./csvlint -quote minimal -no-trailing-newline -crlf", "a\nb\n", "a\r\nb"},
+		{"./csvlint -quote minimal -no-trailing-newline", "\"x\ny\"\n", `x\ny`},
+		{"./csvlint -quote minimal -no-trailing-newline", "", ""},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(tt.input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestNoTrailingNewline_splitCRLF(t *testing.T) {
+	var b bytes.Buffer
+	w := &noTrailingNewline{w: nopCloser{&b}, ending: []byte("\r\n")}
+	for _, p := range []string{"a\r", "\nb\r", "\n"} {
+		w.Write([]byte(p))
+	}
+	w.Close()
+	if b.String() != "a\r\nb" {
+		t.Errorf("expected %q to eq %q", b.String(), "a\r\nb")
+	}
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestRun_escapeControlFlag(t *testing.T) {
+	inStream := strings.NewReader("a\x00b,\"c\td\x1be\",\u0085\xff\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -tsv -escape-control", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "a\\x00b\tc\\x09d\\x1Be\t\\u0085\\xFF\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_stdinIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w.WriteString("a\n")
+	w.Close()
+
+	defer func(f func(int) bool) { isTerminal = f }(isTerminal)
+	isTerminal = func(int) bool { return true }
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: r, outStream: outStream, errStream: errStream}
+
+	status := cli.Run([]string{"./csvlint"})
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if !strings.Contains(errStream.String(), "Usage:") {
+		t.Errorf("expected usage, got %q", errStream.String())
+	}
+
+	status = cli.Run([]string{"./csvlint", "-f", "-"})
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if outStream.String() != "\"a\"\n" {
+		t.Errorf("expected stdin to be read with -f -, got %q", outStream.String())
+	}
+
+	if status = cli.Run([]string{"./csvlint", "-quote", "minimal", "--"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	// a "--" that is the value of a flag does not end the flags
+	errStream.Reset()
+	if status = cli.Run([]string{"./csvlint", "-null-token", "--"}); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if !strings.Contains(errStream.String(), "Usage:") {
+		t.Errorf("expected usage, got %q", errStream.String())
+	}
+}
+
+func TestRun_fillFlag(t *testing.T) {
+	inStream := strings.NewReader("id,name,country\n1,alice,jp\n2\n3,carol\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -quote minimal -fill country=unknown", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "id,name,country\n1,alice,jp\n2,,unknown\n3,carol,unknown\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+
+	expected = "padded rows: 2\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_padFlag(t *testing.T) {
+	inStream := strings.NewReader("a,b,c\n1\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -quote minimal -no-header -pad", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "a,b,c\n1,,\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_padToFlag(t *testing.T) {
+	input := "a,b\n1\n1,2,3,4\n1,2,3\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -pad-to 3", ExitCodeOK, "a,b,\n1,,\n1,2,3,4\n1,2,3\n", "padded rows: 1\n"},
+		{"./csvlint -quote minimal -pad-to 3 -pad-truncate", ExitCodeOK, "a,b,\n1,,\n1,2,3\n1,2,3\n", "padded rows: 1\ntruncated rows: 1\n"},
+		{"./csvlint -quote minimal -pad-to 1 -pad-truncate", ExitCodeOK, "a\n1\n1\n1\n", "truncated rows: 2\n"},
+		{"./csvlint -pad-truncate", ExitCodeError, "", "-pad-truncate needs -pad-to\n"},
+		{"./csvlint -pad-to -1", ExitCodeError, "", "-pad-to must be a number of fields\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != tt.status {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, tt.status, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
+
+func TestRun_sampleFlag(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("n\n")
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&input, "%d\n", i)
+	}
+
+	var outputs []string
+	for i := 0; i < 2; i++ {
+		inStream := bytes.NewReader(input.Bytes())
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+		args := strings.Split("./csvlint -quote minimal -sample 5 -seed 42", " ")
+
+		status := cli.Run(args)
+		if status != ExitCodeOK {
+			t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+		}
+		outputs = append(outputs, outStream.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(outputs[0], "\n"), "\n")
+	if len(lines) != 6 || lines[0] != "n" {
+		t.Errorf("expected the header and 5 rows, got %q", outputs[0])
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("expected the same seed to give the same sample, got %q and %q", outputs[0], outputs[1])
+	}
+}
+
+type unreadable struct{}
+
+func (unreadable) Read([]byte) (int, error) {
+	panic("input must not be read")
+}
+
+func TestRun_explainFlag(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: unreadable{}, outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -preset git -quote all -remove-space -explain", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	for _, expected := range []string{"quoting:       all\n", "line ending:   LF\n", ". collapse runs of white space and trim\n"} {
+		if !strings.Contains(errStream.String(), expected) {
+			t.Errorf("expected %q to contain %q", errStream.String(), expected)
+		}
+	}
+}
+
+func TestRun_hashColumnFlag(t *testing.T) {
+	run := func(input string, args string) []string {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split("./csvlint -quote minimal -remove-space "+args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+		}
+		return strings.Split(strings.TrimSuffix(outStream.String(), "\n"), "\n")
+	}
+
+	a := run("id,name,note\n1,Alice,x\n2,Bob,y\n", "-hash-column hash -hash-cols id,name")
+	b := run("id,name,note\n1,  Alice ,z\n2,Bob  ,y\n", "-hash-column hash -hash-cols id,name")
+	if a[0] != "id,name,note,hash" {
+		t.Errorf("expected %q to eq %q", a[0], "id,name,note,hash")
+	}
+	if len(a) != 3 || len(b) != 3 {
+		t.Fatalf("expected 3 lines, got %q and %q", a, b)
+	}
+	for i := 1; i < 3; i++ {
+		ha, hb := a[i][strings.LastIndex(a[i], ",")+1:], b[i][strings.LastIndex(b[i], ",")+1:]
+		if len(ha) != 16 || ha != hb {
+			t.Errorf("expected the hashes of %q and %q to be equal, got %q and %q", a[i], b[i], ha, hb)
+		}
+	}
+
+	c := run("id,name,note\n1,Alice,x\n1,Alice,z\n", "-hash-column hash")
+	if c[1][len(c[1])-16:] == c[2][len(c[2])-16:] {
+		t.Errorf("expected rows %q and %q to hash differently", c[1], c[2])
+	}
 }
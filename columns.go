@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// selectColumn is one entry of a -select list: a source column, given by
+// header name or by 1-based position, and the name it is emitted under.
+type selectColumn struct {
+	source string
+	index  int
+	target string
+}
+
+// parseSelect parses a list like "sourceName:targetName,other". Without a
+// header, sources must be 1-based positions.
+func parseSelect(spec string, noHeader bool) ([]selectColumn, error) {
+	var cols []selectColumn
+	named := 0
+	for _, item := range strings.Split(spec, ",") {
+		source, target := item, ""
+		if i := strings.Index(item, ":"); i >= 0 {
+			source, target = item[:i], item[i+1:]
+			named++
+		}
+		if source == "" {
+			return nil, fmt.Errorf("invalid select %q: empty column", item)
+		}
+
+		col := selectColumn{source: source, index: -1, target: target}
+		if noHeader {
+			n, err := strconv.Atoi(source)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid select %q: columns must be 1-based positions without a header", item)
+			}
+			col.index = n - 1
+		} else if col.target == "" {
+			col.target = source
+		}
+		cols = append(cols, col)
+	}
+
+	if noHeader && named != 0 && named != len(cols) {
+		return nil, fmt.Errorf("invalid select %q: give a target name for every column or for none", spec)
+	}
+	return cols, nil
+}
+
+// headerIndex maps column names to their position, keeping the first of
+// duplicated names.
+func headerIndex(header []string) map[string]int {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+	return index
+}
+
+// resolveSelect returns the input field index of every selected column.
+// Without a header, header is the first record, whose width the positions
+// must be in, or nil when it is not read yet.
+func resolveSelect(cols []selectColumn, header []string) ([]int, error) {
+	index := headerIndex(header)
+	indices := make([]int, len(cols))
+	for i, col := range cols {
+		if col.index >= 0 {
+			if header != nil && col.index >= len(header) {
+				return nil, fmt.Errorf("unknown column %q: the first record has %d fields", col.source, len(header))
+			}
+			indices[i] = col.index
+			continue
+		}
+		n, ok := index[col.source]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", col.source)
+		}
+		indices[i] = n
+	}
+	return indices, nil
+}
+
+// matchColumns returns the index of every header column whose name
+// matches re, in header order, leaving out those already in indices.
+func matchColumns(header []string, re *regexp.Regexp, indices []int) []int {
+	picked := make(map[int]bool, len(indices))
+	for _, n := range indices {
+		picked[n] = true
+	}
+	var matched []int
+	for i, name := range header {
+		if !picked[i] && re.MatchString(name) {
+			matched = append(matched, i)
+		}
+	}
+	return matched
+}
+
+// sourceOrder sorts the projected columns by their position in the input,
+// keeping names, when there are any, with their columns.
+func sourceOrder(indices []int, names []string) {
+	order := make([]int, len(indices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return indices[order[i]] < indices[order[j]]
+	})
+	sorted := make([]int, len(indices))
+	for i, n := range order {
+		sorted[i] = indices[n]
+	}
+	copy(indices, sorted)
+	if names != nil {
+		sortedNames := make([]string, len(names))
+		for i, n := range order {
+			sortedNames[i] = names[n]
+		}
+		copy(names, sortedNames)
+	}
+}
+
+// selectHeader returns the header row of the selection, or nil when the
+// columns have no target names.
+func selectHeader(cols []selectColumn) []string {
+	var header []string
+	for _, col := range cols {
+		if col.target == "" {
+			return nil
+		}
+		header = append(header, col.target)
+	}
+	return header
+}
+
+// project picks the fields at indices from record. Missing fields are empty.
+func project(record []string, indices []int) []string {
+	row := make([]string, len(indices))
+	for i, n := range indices {
+		if n < len(record) {
+			row[i] = record[n]
+		}
+	}
+	return row
+}
+
+// compositeKey joins values into one map key. Each value is length
+// prefixed, so that "a,b"+"c" and "a"+"b,c" differ.
+func compositeKey(values []string) string {
+	var key strings.Builder
+	for _, v := range values {
+		key.WriteString(strconv.Itoa(len(v)))
+		key.WriteByte(':')
+		key.WriteString(v)
+	}
+	return key.String()
+}
+
+// projectInPlace is project for a record that is not used otherwise. When
+// indices only increase, as those of -exclude, the fields are moved within
+// record rather than copied to a new slice for every row.
+func projectInPlace(record []string, indices []int) []string {
+	for i := 1; i < len(indices); i++ {
+		if indices[i] <= indices[i-1] {
+			return project(record, indices)
+		}
+	}
+	// a field is moved only to its own index or a lower one, which no
+	// field after it is read from
+	width := len(record)
+	for i, n := range indices {
+		v := ""
+		if n < width {
+			v = record[n]
+		}
+		if i < len(record) {
+			record[i] = v
+		} else {
+			record = append(record, v)
+		}
+	}
+	// the fields left out are not kept alive by rows held for later
+	for i := len(indices); i < len(record); i++ {
+		record[i] = ""
+	}
+	return record[:len(indices)]
+}
+
+// keptColumns returns the index of every one of width fields that is not
+// excluded.
+func keptColumns(width int, excluded map[int]bool) []int {
+	kept := []int{}
+	for i := 0; i < width; i++ {
+		if !excluded[i] {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+// columnIndex resolves a column given by header name, or by 1-based
+// position when there is no header.
+func columnIndex(name string, index map[string]int, noHeader bool) (int, error) {
+	if noHeader {
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid column %q: columns must be 1-based positions without a header", name)
+		}
+		return n - 1, nil
+	}
+	n, ok := index[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown column %q", name)
+	}
+	return n, nil
+}
+
+// columnIndices resolves the named columns in header. Errors are prefixed
+// with the flag the columns were given to, unless it is empty.
+func columnIndices(flag string, names []string, header []string, noHeader bool) ([]int, error) {
+	index := headerIndex(header)
+	indices := make([]int, len(names))
+	for i, name := range names {
+		n, err := columnIndex(name, index, noHeader)
+		if err != nil {
+			if flag != "" {
+				err = fmt.Errorf("%s: %s", flag, err)
+			}
+			return nil, err
+		}
+		indices[i] = n
+	}
+	return indices, nil
+}
+
+// resolveFill maps the columns of -fill defaults to their index.
+func resolveFill(fill map[string]string, header []string, noHeader bool) (map[int]string, error) {
+	index := headerIndex(header)
+	defaults := make(map[int]string, len(fill))
+	// sorted, so that the same column is reported when several are unknown
+	names := make([]string, 0, len(fill))
+	for name := range fill {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		n, err := columnIndex(name, index, noHeader)
+		if err != nil {
+			return nil, err
+		}
+		defaults[n] = fill[name]
+	}
+	return defaults, nil
+}
+
+// pad extends record to width fields, taking missing values from defaults.
+// It reports whether the record was short.
+func pad(record []string, width int, defaults map[int]string) ([]string, bool) {
+	if len(record) >= width {
+		return record, false
+	}
+	for i := len(record); i < width; i++ {
+		record = append(record, defaults[i])
+	}
+	return record, true
+}
+
+// padTo extends record with empty fields to n fields, and with truncate
+// cuts it to n. It reports whether record was extended or cut.
+func padTo(record []string, n int, truncate bool) (out []string, padded, cut bool) {
+	if len(record) > n && truncate {
+		return record[:n], false, true
+	}
+	for len(record) < n {
+		record = append(record, "")
+		padded = true
+	}
+	return record, padded, false
+}
+
+// equalRecords reports whether a and b have the same fields.
+func equalRecords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// groupConcat collapses the rows sharing the values of the -group-by
+// columns into one, for -group-by and -concat: the -concat columns join
+// the values of every row of the group, and the other columns keep those
+// of its first row, or are left out with drop. Like SQL GROUP_CONCAT,
+// which skips nulls, the empty values are not joined: "a", "" and "b"
+// join to "a,b". Every group keeps its first
+// row and the values to join, so the memory taken grows with the groups
+// and the -concat values, not with the other fields of later rows.
+type groupConcat struct {
+	mu      sync.Mutex
+	keys    []string
+	columns []string
+	sep     string
+	drop    bool
+	// skipHeader leaves the header out of the rows; it is still read to
+	// bind the columns.
+	skipHeader bool
+
+	bound     bool
+	keyIdx    []int
+	concatIdx []int
+	header    []string
+	groups    map[string]*concatGroup
+	order     []*concatGroup
+	memory    *memoryLimit
+}
+
+type concatGroup struct {
+	first []string
+	// values holds, per -concat column, the values not empty.
+	values [][]string
+}
+
+func newGroupConcat(keys, columns []string, sep string, drop, noHeader bool) (*groupConcat, error) {
+	g := &groupConcat{keys: keys, columns: columns, sep: sep, drop: drop, groups: map[string]*concatGroup{}}
+	if noHeader {
+		if err := g.bind(nil, true); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (g *groupConcat) bind(header []string, noHeader bool) error {
+	var err error
+	if g.keyIdx, err = resolveHashCols(g.keys, header, noHeader); err != nil {
+		return fmt.Errorf("-group-by: %s", err)
+	}
+	if g.concatIdx, err = resolveHashCols(g.columns, header, noHeader); err != nil {
+		return fmt.Errorf("-concat: %s", err)
+	}
+	for _, n := range g.concatIdx {
+		if containsInt(g.keyIdx, n) {
+			return fmt.Errorf("-concat: column %s is one of -group-by", g.columnName(header, n))
+		}
+	}
+	g.bound = true
+	return nil
+}
+
+func (g *groupConcat) columnName(header []string, n int) string {
+	if n < len(header) {
+		return strconv.Quote(header[n])
+	}
+	return strconv.Itoa(n + 1)
+}
+
+func (g *groupConcat) add(record []string, isHeader bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if isHeader {
+		if g.header != nil {
+			return nil
+		}
+		if err := g.bind(record, false); err != nil {
+			return err
+		}
+		g.header = g.output(record, nil)
+		return nil
+	}
+	if !g.bound {
+		return errors.New("-group-by: a data row came before the header that names the columns")
+	}
+
+	key := compositeKey(project(record, g.keyIdx))
+	gr, ok := g.groups[key]
+	if !ok {
+		gr = &concatGroup{first: append([]string(nil), record...), values: make([][]string, len(g.concatIdx))}
+		if err := g.memory.grow("-group-by", recordSize(record)+int64(len(key))); err != nil {
+			return err
+		}
+		g.groups[key] = gr
+		g.order = append(g.order, gr)
+	}
+	for i, n := range g.concatIdx {
+		if v := field(record, n); v != "" {
+			gr.values[i] = append(gr.values[i], v)
+			if err := g.memory.grow("-group-by", int64(len(v))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// output returns the row of a group: record, its first row, with the
+// -concat columns replaced by the joined values, or for the header the
+// names. With drop, only the -group-by and -concat columns are kept.
+func (g *groupConcat) output(record []string, values [][]string) []string {
+	width := len(record)
+	for _, n := range append(append([]int(nil), g.keyIdx...), g.concatIdx...) {
+		if n >= width {
+			width = n + 1
+		}
+	}
+	var row []string
+	for n := 0; n < width; n++ {
+		i := -1
+		for j, c := range g.concatIdx {
+			if c == n {
+				i = j
+			}
+		}
+		switch {
+		case i >= 0 && values != nil:
+			row = append(row, strings.Join(values[i], g.sep))
+		case i >= 0 || !g.drop || containsInt(g.keyIdx, n):
+			row = append(row, field(record, n))
+		}
+	}
+	return row
+}
+
+// rows returns the header, unless skipped, and one row per group in the
+// order the groups were first seen.
+func (g *groupConcat) rows() [][]string {
+	var rows [][]string
+	if g.header != nil && !g.skipHeader {
+		rows = append(rows, g.header)
+	}
+	for _, gr := range g.order {
+		rows = append(rows, g.output(gr.first, gr.values))
+	}
+	return rows
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_groupByFlag(t *testing.T) {
+	input := "id,name,tag\n2,Bob,x\n1,Ada,a\n2,Bobby,\n1,Ada L.,b\n2,Bob,y\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -quote minimal -group-by id -concat tag", "id,name,tag\n2,Bob,\"x,y\"\n1,Ada,\"a,b\"\n"},
+		{"./csvlint -quote minimal -group-by id -concat tag,name -concat-sep | -concat-drop", "id,name,tag\n2,Bob|Bobby|Bob,x|y\n1,Ada|Ada L.,a|b\n"},
+		{"./csvlint -quote minimal -group-by name -concat id -concat-drop -skip-header", "\"2,2\",Bob\n1,Ada\n2,Bobby\n1,Ada L.\n"},
+		{"./csvlint -quote minimal -no-header -rows 2-6 -group-by 1 -concat 3 -concat-drop", "2,\"x,y\"\n1,\"a,b\"\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -group-by id", "-group-by needs -concat\n"},
+		{"./csvlint -concat tag", "-concat, -concat-sep and -concat-drop need -group-by\n"},
+		{"./csvlint -group-by id -concat nope", "-concat: unknown column \"nope\"\n"},
+		{"./csvlint -group-by id -concat id", "-concat: column \"id\" is one of -group-by\n"},
+	} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestGroupConcat_add(t *testing.T) {
+	g, err := newGroupConcat([]string{"id"}, []string{"tag"}, ",", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "-group-by: a data row came before the header that names the columns"
+	if err := g.add([]string{"1", "a"}, false); err == nil || err.Error() != expected {
+		t.Errorf("expected %v to eq %q", err, expected)
+	}
+
+	// empty fields are not joined
+	for _, record := range [][]string{{"id", "tag"}, {"1", "a"}, {"1", ""}, {"1", "b"}} {
+		if err := g.add(record, record[0] == "id"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rows := g.rows(); len(rows) != 2 || strings.Join(rows[1], "|") != "1|a,b" {
+		t.Errorf("expected %q to eq %q", rows, [][]string{{"id", "tag"}, {"1", "a,b"}})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// groupCounter tallies rows by the values of the -count-by columns. Only
+// one entry per distinct group is kept, not the rows.
+type groupCounter struct {
+	mu      sync.Mutex
+	columns []string
+	indices []int
+	header  []string
+	groups  map[string]*group
+	order   []*group
+	memory  *memoryLimit
+}
+
+type group struct {
+	values []string
+	count  int
+}
+
+func newGroupCounter(columns []string, noHeader bool) (*groupCounter, error) {
+	g := &groupCounter{columns: columns, groups: map[string]*group{}}
+	if noHeader {
+		var err error
+		if g.indices, err = resolveHashCols(columns, nil, true); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (g *groupCounter) add(record []string, isHeader bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if isHeader {
+		if g.header != nil {
+			return nil
+		}
+		var err error
+		if g.indices, err = resolveHashCols(g.columns, record, false); err != nil {
+			return err
+		}
+		g.header = append(project(record, g.indices), "count")
+		return nil
+	}
+
+	values := project(record, g.indices)
+	key := compositeKey(values)
+	gr, ok := g.groups[key]
+	if !ok {
+		if err := g.memory.grow("-count-by", recordSize(values)+int64(len(key))); err != nil {
+			return err
+		}
+		gr = &group{values: values}
+		g.groups[key] = gr
+		g.order = append(g.order, gr)
+	}
+	gr.count++
+	return nil
+}
+
+// rows returns the header, unless opts skips it, and one row per group with
+// its count, the largest groups first and equal ones in the order they
+// were first seen.
+func (g *groupCounter) rows(opts *Options) [][]string {
+	var rows [][]string
+	if g.header != nil && !opts.SkipHeader {
+		rows = append(rows, g.header)
+	}
+	sort.SliceStable(g.order, func(i, j int) bool {
+		return g.order[i].count > g.order[j].count
+	})
+	for _, gr := range g.order {
+		rows = append(rows, append(gr.values, strconv.Itoa(gr.count)))
+	}
+	return rows
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_countByFlag(t *testing.T) {
+	input := "status,country,id\nactive,JP,1\n\"in,active\",US,2\nactive,US,3\nactive,JP,4\n\"in,active\",US,5\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -quote minimal -count-by status", "status,count\nactive,3\n\"in,active\",2\n"},
+		{"./csvlint -quote minimal -count-by country,status", "country,status,count\nJP,active,2\nUS,\"in,active\",2\nUS,active,1\n"},
+		{"./csvlint -quote minimal -count-by 2 -no-header", "US,3\nJP,2\ncountry,1\n"},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+// The columns are bound in the header of the first file, so the files
+// cannot be read concurrently.
+func TestRun_countByFlagFileWorkers(t *testing.T) {
+	files := writeFiles(t, "k\na\nb\n", "k\na\n", "k\nb\n", "k\na\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	if status := cli.Run(append([]string{"./csvlint", "-quote", "minimal", "-count-by", "k"}, files...)); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if expected := "k,count\na,3\nb,2\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+
+	outStream, errStream = new(bytes.Buffer), new(bytes.Buffer)
+	cli = &CLI{outStream: outStream, errStream: errStream}
+	if status := cli.Run(append([]string{"./csvlint", "-file-workers", "4", "-count-by", "k"}, files...)); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if expected := "-count-by cannot be combined with -ddl, -density, -partition-by, -file-workers or -check-idempotent\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
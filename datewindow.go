@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultDateLayout is the -date-layout used when none is given.
+const defaultDateLayout = "2006-01-02"
+
+// dateWindow keeps only the data rows whose -date-col falls between since
+// and until, both inclusive. A zero bound leaves that side open.
+type dateWindow struct {
+	column       string
+	layout       string
+	since, until time.Time
+	// keepInvalid keeps the rows whose date does not parse, which are
+	// reported either way.
+	keepInvalid bool
+}
+
+func newDateWindow(column, layout, since, until string, keepInvalid bool) (*dateWindow, error) {
+	d := &dateWindow{column: column, layout: layout, keepInvalid: keepInvalid}
+	var err error
+	if since != "" {
+		if d.since, err = time.Parse(layout, since); err != nil {
+			return nil, fmt.Errorf("invalid -since %q: not a date in the layout %s", since, layout)
+		}
+	}
+	if until != "" {
+		if d.until, err = time.Parse(layout, until); err != nil {
+			return nil, fmt.Errorf("invalid -until %q: not a date in the layout %s", until, layout)
+		}
+	}
+	if since != "" && until != "" && d.until.Before(d.since) {
+		return nil, fmt.Errorf("-until %s is before -since %s", until, since)
+	}
+	return d, nil
+}
+
+// bind resolves the date column in header.
+func (d *dateWindow) bind(header []string, noHeader bool) (int, error) {
+	n, err := columnIndex(d.column, headerIndex(header), noHeader)
+	if err != nil {
+		return 0, fmt.Errorf("-date-col: %s", err)
+	}
+	return n, nil
+}
+
+// contains tells whether record is in the window. n is the bound column.
+// A date that does not parse is reported.
+func (d *dateWindow) contains(name string, record []string, reader recordReader, diag *diagnostics, n int) bool {
+	v := ""
+	line := 0
+	if n < len(record) {
+		v = strings.TrimSpace(record[n])
+		line, _ = reader.FieldPos(n)
+	}
+	t, err := time.Parse(d.layout, v)
+	if err != nil {
+		if line == 0 {
+			line, _ = reader.FieldPos(0)
+		}
+		diag.report(Diagnostic{File: name, Line: line, Column: n + 1, Rule: "date", Message: fmt.Sprintf("%s: %q is not a date in the layout %s", d.column, v, d.layout)})
+		return d.keepInvalid
+	}
+	if !d.since.IsZero() && t.Before(d.since) {
+		return false
+	}
+	if !d.until.IsZero() && t.After(d.until) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_sinceUntilFlags(t *testing.T) {
+	input := "id,day\n1,2024-01-01\n2,2024-01-05\n3,soon\n4,2024-01-07\n5,2024-01-08\n"
+	bad := "line 4 column 2: day: \"soon\" is not a date in the layout 2006-01-02\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -date-col day -since 2024-01-05", ExitCodeOK, "id,day\n2,2024-01-05\n4,2024-01-07\n5,2024-01-08\n", bad + "rows outside the dates: 2\n"},
+		{"./csvlint -quote minimal -date-col day -since 2024-01-05 -until 2024-01-07", ExitCodeOK, "id,day\n2,2024-01-05\n4,2024-01-07\n", bad + "rows outside the dates: 3\n"},
+		{"./csvlint -quote minimal -date-col day -until 2024-01-04 -keep-bad-dates", ExitCodeOK, "id,day\n1,2024-01-01\n3,soon\n", bad + "rows outside the dates: 3\n"},
+		{"./csvlint -quote minimal -date-col day -since 2024-01-05 -strict", ExitCodeError, "id,day\n2,2024-01-05\n4,2024-01-07\n5,2024-01-08\n", bad + "rows outside the dates: 2\n"},
+		{"./csvlint -since 2024-01-05", ExitCodeError, "", "-since and -until need -date-col\n"},
+		{"./csvlint -date-col day", ExitCodeError, "", "-date-col, -date-layout and -keep-bad-dates need -since or -until\n"},
+		{"./csvlint -date-col day -since 2024-01-05 -until 2024-01-01", ExitCodeError, "", "-until 2024-01-01 is before -since 2024-01-05\n"},
+		{"./csvlint -date-col day -since jan", ExitCodeError, "", "invalid -since \"jan\": not a date in the layout 2006-01-02\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != tt.status {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, tt.status)
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
+
+func TestRun_sinceUntilFlagsLayout(t *testing.T) {
+	input := "a,02/01/2024\nb,31/12/2023\n"
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+	args := "./csvlint -quote minimal -no-header -date-col 2 -date-layout 02/01/2006 -since 01/01/2024"
+	if status := cli.Run(strings.Split(args, " ")); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if expected := "a,02/01/2024\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Inferred column types, from the most to the least specific. A column
+// takes the first type that every non-empty value of it fits. The types do
+// not widen each other: 10 is an INTEGER and 2016-01-02 a DATE, but a
+// column of both is TEXT.
+const (
+	TypeInteger = "INTEGER"
+	TypeNumeric = "NUMERIC"
+	TypeBoolean = "BOOLEAN"
+	TypeDate    = "DATE"
+	TypeText    = "TEXT"
+)
+
+var inferredTypes = []string{TypeInteger, TypeNumeric, TypeBoolean, TypeDate, TypeText}
+
+// fitsType reports whether v, which is not empty, is a value of typ: the
+// values -cast reads, booleans only when spelled true or false in any case
+// and dates only as 2006-01-02. A number out of the range of a float is
+// still NUMERIC.
+func fitsType(v, typ string) bool {
+	switch typ {
+	case TypeInteger:
+		_, ok := parseInt(v)
+		return ok
+	case TypeNumeric:
+		return reNumeric.MatchString(v)
+	case TypeBoolean:
+		b, ok := parseBool(v)
+		return ok && strings.EqualFold(v, strconv.FormatBool(b))
+	case TypeDate:
+		_, ok := parseDate(v, ddlDateLayouts)
+		return ok
+	}
+	return true
+}
+
+// ddlDateLayouts is the layout of the values of a DATE column.
+var ddlDateLayouts = []string{"2006-01-02"}
+
+// typeInference narrows down the type of every column as rows are added.
+type typeInference struct {
+	mu     sync.Mutex
+	header []string
+	// fits holds, per column, whether all its values so far fit each of
+	// inferredTypes.
+	fits [][]bool
+	seen []bool
+}
+
+func (t *typeInference) add(record []string, isHeader bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if isHeader {
+		if t.header == nil {
+			t.header = append([]string(nil), record...)
+		}
+		return
+	}
+	for len(t.fits) < len(record) {
+		t.fits = append(t.fits, []bool{true, true, true, true, true})
+		t.seen = append(t.seen, false)
+	}
+	for i, v := range record {
+		if v == "" {
+			continue
+		}
+		t.seen[i] = true
+		for k, typ := range inferredTypes {
+			t.fits[i][k] = t.fits[i][k] && fitsType(v, typ)
+		}
+	}
+}
+
+// typeOf returns the most specific type that all values of column i fit,
+// TEXT when it has none.
+func (t *typeInference) typeOf(i int) string {
+	if i >= len(t.fits) || !t.seen[i] {
+		return TypeText
+	}
+	for k, typ := range inferredTypes {
+		if t.fits[i][k] {
+			return typ
+		}
+	}
+	return TypeText
+}
+
+// columns returns the name and inferred type of every column. Columns
+// without any value are TEXT.
+func (t *typeInference) columns() (names, types []string) {
+	n := len(t.fits)
+	if len(t.header) > n {
+		n = len(t.header)
+	}
+	for i := 0; i < n; i++ {
+		name := "column" + strconv.Itoa(i+1)
+		if i < len(t.header) {
+			name = t.header[i]
+		}
+		names = append(names, name)
+		types = append(types, t.typeOf(i))
+	}
+	return names, types
+}
+
+// ddlTypes maps the inferred types to the type names of each -ddl-dialect.
+var ddlTypes = map[string]map[string]string{
+	"postgres": {TypeInteger: "BIGINT", TypeNumeric: "NUMERIC", TypeBoolean: "BOOLEAN", TypeDate: "DATE", TypeText: "TEXT"},
+	"mysql":    {TypeInteger: "BIGINT", TypeNumeric: "DOUBLE", TypeBoolean: "BOOLEAN", TypeDate: "DATE", TypeText: "TEXT"},
+	// SQLite has no boolean or date storage class.
+	"sqlite": {TypeInteger: "INTEGER", TypeNumeric: "REAL", TypeBoolean: "INTEGER", TypeDate: "TEXT", TypeText: "TEXT"},
+}
+
+var reIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// sqlNames turns header names into unique SQL identifiers made of
+// lowercase letters, digits and '_'.
+func sqlNames(names []string) []string {
+	used := map[string]bool{}
+	out := make([]string, len(names))
+	for i, name := range names {
+		s := strings.Trim(reIdentifier.ReplaceAllString(strings.ToLower(name), "_"), "_")
+		if s == "" {
+			s = "column" + strconv.Itoa(i+1)
+		} else if unicode.IsDigit(rune(s[0])) {
+			s = "_" + s
+		}
+		base := s
+		for n := 2; used[s]; n++ {
+			s = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[s] = true
+		out[i] = s
+	}
+	return out
+}
+
+// quoteIdent quotes a sanitized identifier for dialect.
+func quoteIdent(name, dialect string) string {
+	if dialect == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// writeDDL prints a CREATE TABLE statement for the columns inferred by t.
+func writeDDL(w io.Writer, t *typeInference, table, dialect string) error {
+	names, types := t.columns()
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", quoteIdent(sqlNames([]string{table})[0], dialect))
+	for i, name := range sqlNames(names) {
+		sep := ","
+		if i == len(names)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "  %s %s%s\n", quoteIdent(name, dialect), ddlTypes[dialect][types[i]], sep)
+	}
+	b.WriteString(");\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
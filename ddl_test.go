@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun_ddlFlag(t *testing.T) {
+	input := "ID,Unit Price,active,Created On,note,2nd,note\n" +
+		"1,9.5,true,2016-01-02,a,,x\n" +
+		"2,10,FALSE,2016-02-30,,,y\n" +
+		"-3,1e3,false,2016-12-31,0x1F,,z\n"
+	tests := []struct {
+		dialect  string
+		expected string
+	}{
+		{"postgres", "CREATE TABLE \"sales\" (\n" +
+			"  \"id\" BIGINT,\n" +
+			"  \"unit_price\" NUMERIC,\n" +
+			"  \"active\" BOOLEAN,\n" +
+			"  \"created_on\" TEXT,\n" +
+			"  \"note\" TEXT,\n" +
+			"  \"_2nd\" TEXT,\n" +
+			"  \"note_2\" TEXT\n" +
+			");\n"},
+		{"mysql", "CREATE TABLE `sales` (\n" +
+			"  `id` BIGINT,\n" +
+			"  `unit_price` DOUBLE,\n" +
+			"  `active` BOOLEAN,\n" +
+			"  `created_on` TEXT,\n" +
+			"  `note` TEXT,\n" +
+			"  `_2nd` TEXT,\n" +
+			"  `note_2` TEXT\n" +
+			");\n"},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run([]string{"./csvlint", "-ddl", "Sales", "-ddl-dialect", tt.dialect})
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.dialect, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.dialect, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestTypeInference(t *testing.T) {
+	var ti typeInference
+	ti.add([]string{"a", "b", "c", "d"}, true)
+	ti.add([]string{"1", "2016-01-01", "", "1"}, false)
+	ti.add([]string{"2", "2016-01-02", "", "1.5"}, false)
+
+	_, types := ti.columns()
+	expected := []string{TypeInteger, TypeDate, TypeText, TypeNumeric}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %q to eq %q", types, expected)
+	}
+}
+
+func TestTypeInference_mixed(t *testing.T) {
+	var ti typeInference
+	ti.add([]string{"a", "b", "c"}, true)
+	ti.add([]string{"10", "1", "1.5"}, false)
+	ti.add([]string{"2016-01-02", "true", "2016-01-01"}, false)
+
+	_, types := ti.columns()
+	expected := []string{TypeText, TypeText, TypeText}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %q to eq %q", types, expected)
+	}
+}
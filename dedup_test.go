@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_dedupHeaderRowsFlag(t *testing.T) {
+	input := "id,name\n1,a\nid,name\n2,b\nid,\"name\"\nid,name,\n3,id\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -dedup-header-rows", ExitCodeOK, "id,name\n1,a\n2,b\nid,name,\n3,id\n", "dropped header rows: 2\n"},
+		{"./csvlint -quote minimal -dedup-header-rows -rows 2-3", ExitCodeOK, "id,name\n2,b\nid,name,\n", "dropped header rows: 2\n"},
+		{"./csvlint -quote minimal", ExitCodeOK, "id,name\n1,a\nid,name\n2,b\nid,name\nid,name,\n3,id\n", ""},
+		{"./csvlint -dedup-header-rows -no-header", ExitCodeError, "", "-dedup-header-rows cannot be combined with -no-header\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode"
+)
+
+// density counts the non-empty values of every column for -density. Fields
+// holding only white space count as empty.
+type density struct {
+	mu       sync.Mutex
+	header   []string
+	nonEmpty []int
+	rows     int
+}
+
+// isBlank reports whether v is empty or white space alone.
+func isBlank(v string) bool {
+	return v == "" || isWhitespaceOnly(v)
+}
+
+func (d *density) add(record []string, isHeader bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if isHeader {
+		if d.header == nil {
+			d.header = append([]string(nil), record...)
+		}
+		return
+	}
+	d.rows++
+	for len(d.nonEmpty) < len(record) {
+		d.nonEmpty = append(d.nonEmpty, 0)
+	}
+	for i, v := range record {
+		if !isBlank(v) {
+			d.nonEmpty[i]++
+		}
+	}
+}
+
+type densityColumn struct {
+	Name     string  `json:"name"`
+	NonEmpty int     `json:"non_empty"`
+	Percent  float64 `json:"percent"`
+}
+
+// columns returns the counts of every column, named by header or, without
+// one, by 1-based position.
+func (d *density) columns() []densityColumn {
+	n := len(d.nonEmpty)
+	if len(d.header) > n {
+		n = len(d.header)
+	}
+	cols := make([]densityColumn, n)
+	for i := range cols {
+		cols[i].Name = strconv.Itoa(i + 1)
+		if i < len(d.header) {
+			cols[i].Name = d.header[i]
+		}
+		if i < len(d.nonEmpty) {
+			cols[i].NonEmpty = d.nonEmpty[i]
+		}
+		if d.rows > 0 {
+			cols[i].Percent = float64(cols[i].NonEmpty) * 100 / float64(d.rows)
+		}
+	}
+	return cols
+}
+
+// populated returns the names of the columns with a value in any row, as
+// a -select list.
+func populated(cols []densityColumn) string {
+	var names []string
+	for _, c := range cols {
+		if c.NonEmpty > 0 {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// write prints the report as an aligned table or, with format "json", as a
+// JSON document.
+func (d *density) write(w io.Writer, format string) error {
+	cols := d.columns()
+	if format == "json" {
+		b, err := json.MarshalIndent(struct {
+			Rows      int             `json:"rows"`
+			Columns   []densityColumn `json:"columns"`
+			Populated string          `json:"populated"`
+		}{d.rows, cols, populated(cols)}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "column\tnon-empty\tpercent\n")
+	for _, c := range cols {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", strings.Map(tabToSpace, c.Name), c.NonEmpty, c.Percent)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "rows: %d\npopulated: -select %s\n", d.rows, populated(cols))
+	return err
+}
+
+func tabToSpace(r rune) rune {
+	if r == '\t' || unicode.IsControl(r) {
+		return ' '
+	}
+	return r
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRun_densityFlag(t *testing.T) {
+	input := "id,name,note,extra\n1,a,,\n2,,\" \",\n3,c,,\n4,d,x,\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+	status := cli.Run(strings.Split("./csvlint -density table", " "))
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	expected := "" +
+		"column  non-empty  percent\n" +
+		"id      4          100.0%\n" +
+		"name    3          75.0%\n" +
+		"note    1          25.0%\n" +
+		"extra   0          0.0%\n" +
+		"rows: 4\n" +
+		"populated: -select id,name,note\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+
+	outStream.Reset()
+	cli = &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	status = cli.Run(strings.Split("./csvlint -density json -no-header", " "))
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	var report struct {
+		Rows    int
+		Columns []densityColumn
+	}
+	if err := json.Unmarshal(outStream.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 5 || len(report.Columns) != 4 || report.Columns[3].Name != "4" || report.Columns[3].NonEmpty != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
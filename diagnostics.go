@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Diagnostic is a single problem found in the input.
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	s := ""
+	if d.File != "" {
+		s = d.File + ": "
+	}
+	if d.Line > 0 {
+		s += fmt.Sprintf("line %d", d.Line)
+		if d.Column > 0 {
+			s += fmt.Sprintf(" column %d", d.Column)
+		}
+		s += ": "
+	}
+	return s + d.Message
+}
+
+// diagnostics collects the problems reported while processing. In the text
+// format every diagnostic is printed to w as it is reported; otherwise they
+// are kept until the report is written at the end.
+type diagnostics struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+	list   []Diagnostic
+	// buffered keeps text diagnostics in list too, for a child whose
+	// diagnostics are merged later.
+	buffered bool
+	// rows, when set by -validate-only, receives the diagnostics about
+	// rows as JSON Lines, one line per row, when they are flushed. The
+	// others are still written to w.
+	rows io.Writer
+
+	// reported counts every diagnostic, for -strict. Once max diagnostics
+	// have been shown, the rest are only counted as suppressed.
+	reported   int
+	max        int
+	shown      int
+	suppressed int
+
+	// sample, when set by -sample-errors, keeps only this many examples
+	// of the diagnostics about rows, spread over the rules, and counts
+	// the diagnostics of every rule.
+	sample   int
+	rules    []string
+	examples map[string][]Diagnostic
+	byRule   map[string]int
+
+	// perRule, when set for -rule-summary, counts every diagnostic by
+	// rule, including those -max-errors or -sample-errors do not show.
+	perRule map[string]int
+
+	// watched, while watching, keeps the diagnostics reported since watch
+	// was called, so that -errors-csv can tell the problems of a row.
+	watching bool
+	watched  []Diagnostic
+
+	// summary holds counters printed once processing is done.
+	summary     map[string]int
+	summaryKeys []string
+
+	// schema, set by -preview-schema, is added to a json or sarif report.
+	schema *previewedSchema
+	// messages are those of logf in a json or sarif report.
+	messages []string
+}
+
+func newDiagnostics(w io.Writer, format string) (*diagnostics, error) {
+	switch format {
+	case "text", "json", "sarif":
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+	return &diagnostics{w: w, format: format}, nil
+}
+
+// child returns a collector with the same format that logs to w and keeps
+// its diagnostics, so a file's output can be buffered and merged in order
+// later.
+func (d *diagnostics) child(w io.Writer) *diagnostics {
+	c := &diagnostics{w: w, format: d.format, buffered: true}
+	if d.perRule != nil {
+		c.perRule = map[string]int{}
+	}
+	return c
+}
+
+func (d *diagnostics) report(diag Diagnostic) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reported++
+	if d.perRule != nil {
+		d.perRule[diag.Rule]++
+	}
+	if d.watching {
+		d.watched = append(d.watched, diag)
+	}
+	d.emit(diag)
+}
+
+// watch starts keeping the diagnostics reported, forgetting the ones kept
+// before.
+func (d *diagnostics) watch() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.watching, d.watched = true, d.watched[:0]
+}
+
+// since returns the diagnostics reported since watch was last called.
+func (d *diagnostics) since() []Diagnostic {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.watched
+}
+
+func (d *diagnostics) emit(diag Diagnostic) {
+	if d.max > 0 && d.shown >= d.max {
+		d.suppressed++
+		return
+	}
+	if d.sample > 0 && diag.Line > 0 {
+		d.keepExample(diag)
+		return
+	}
+	d.shown++
+	if d.format == "text" && !d.buffered && (d.rows == nil || diag.Line == 0) {
+		fmt.Fprintln(d.w, diag)
+		return
+	}
+	d.list = append(d.list, diag)
+}
+
+// keepExample counts diag under its rule, and keeps it if the rule has
+// fewer than sample examples so far; no more can be shown.
+func (d *diagnostics) keepExample(diag Diagnostic) {
+	if d.examples == nil {
+		d.examples, d.byRule = map[string][]Diagnostic{}, map[string]int{}
+	}
+	if _, ok := d.byRule[diag.Rule]; !ok {
+		d.rules = append(d.rules, diag.Rule)
+	}
+	d.byRule[diag.Rule]++
+	if len(d.examples[diag.Rule]) < d.sample {
+		d.examples[diag.Rule] = append(d.examples[diag.Rule], diag)
+	}
+}
+
+// sampled returns the number of diagnostics counted by keepExample.
+func (d *diagnostics) sampled() int {
+	n := 0
+	for _, c := range d.byRule {
+		n += c
+	}
+	return n
+}
+
+// sampleExamples picks the examples shown by -sample-errors, taking one of
+// every rule in turn so that rare rules are shown next to common ones, and
+// adds the count of every rule to the summary.
+func (d *diagnostics) sampleExamples() []Diagnostic {
+	var picked []Diagnostic
+	for k := 0; len(picked) < d.sample; k++ {
+		more := false
+		for _, rule := range d.rules {
+			if k < len(d.examples[rule]) && len(picked) < d.sample {
+				picked = append(picked, d.examples[rule][k])
+				more = true
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	sortByFileAndLine(picked)
+	for _, rule := range d.rules {
+		d.add(rule+" problems", d.byRule[rule])
+	}
+	return picked
+}
+
+// reportedCount returns the number of diagnostics reported so far.
+func (d *diagnostics) reportedCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reported
+}
+
+// reportError reports err, taking the position from it when it is a csv
+// parse error.
+func (d *diagnostics) reportError(file, rule string, err error) {
+	diag := Diagnostic{File: file, Rule: rule, Message: err.Error()}
+	switch e := err.(type) {
+	case *csv.ParseError:
+		diag.Line, diag.Column, diag.Message = e.Line, e.Column, e.Err.Error()
+	case *os.PathError:
+		diag.Message = e.Op + ": " + e.Err.Error()
+	}
+	d.report(diag)
+}
+
+// logf writes an informational message, such as the -verbose output, or
+// keeps it for a json or sarif report.
+func (d *diagnostics) logf(format string, a ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.format != "text" {
+		d.messages = append(d.messages, fmt.Sprintf(format, a...))
+		return
+	}
+	fmt.Fprintf(d.w, format+"\n", a...)
+}
+
+// errorWriter reports every line written to it as a diagnostic of d, so
+// that with a json or sarif report the errors Run prints are part of the
+// report instead of text next to it.
+type errorWriter struct {
+	d   *diagnostics
+	buf []byte
+}
+
+func (e *errorWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	for {
+		i := bytes.IndexByte(e.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		e.d.report(Diagnostic{Rule: "error", Message: string(e.buf[:i])})
+		e.buf = e.buf[i+1:]
+	}
+}
+
+// count adds n to the named summary counter.
+func (d *diagnostics) count(key string, n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.add(key, n)
+}
+
+func (d *diagnostics) add(key string, n int) {
+	if d.summary == nil {
+		d.summary = map[string]int{}
+	}
+	if _, ok := d.summary[key]; !ok {
+		d.summaryKeys = append(d.summaryKeys, key)
+	}
+	d.summary[key] += n
+}
+
+// setSchema adds the -preview-schema schema to the report.
+func (d *diagnostics) setSchema(schema *previewedSchema) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.schema = schema
+}
+
+// sortByLine orders the kept diagnostics by line, those about the whole
+// input first, keeping the order of diagnostics on the same line.
+func (d *diagnostics) sortByLine() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sort.SliceStable(d.list, func(i, j int) bool {
+		return d.list[i].Line < d.list[j].Line
+	})
+}
+
+// merge appends the diagnostics and counters kept by c.
+func (d *diagnostics) merge(c *diagnostics) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, diag := range c.list {
+		d.emit(diag)
+	}
+	d.reported += c.reported
+	d.suppressed += c.suppressed
+	d.messages = append(d.messages, c.messages...)
+	for rule, n := range c.perRule {
+		d.perRule[rule] += n
+	}
+	for _, key := range c.summaryKeys {
+		d.add(key, c.summary[key])
+	}
+}
+
+// flush writes the summary, and the collected diagnostics in the json or
+// sarif format.
+func (d *diagnostics) flush() error {
+	if d.rows != nil {
+		if err := d.writeRows(); err != nil {
+			return err
+		}
+	}
+	if d.sample > 0 {
+		examples := d.sampleExamples()
+		if d.format == "text" {
+			if len(examples) > 0 {
+				if _, err := fmt.Fprintf(d.w, "%d examples of %d problems:\n", len(examples), d.sampled()); err != nil {
+					return err
+				}
+			}
+			for _, diag := range examples {
+				if _, err := fmt.Fprintf(d.w, "  %s [%s]\n", diag, diag.Rule); err != nil {
+					return err
+				}
+			}
+		} else {
+			d.list = append(d.list, examples...)
+		}
+	}
+
+	// the diagnostics found after the rows were read, such as those of
+	// -check-line-endings, are reported with the rows they are about
+	sortByFileAndLine(d.list)
+	var v interface{}
+	switch d.format {
+	case "json":
+		list := d.list
+		if list == nil {
+			list = []Diagnostic{}
+		}
+		v = struct {
+			Diagnostics []Diagnostic     `json:"diagnostics"`
+			Suppressed  int              `json:"suppressed,omitempty"`
+			Summary     map[string]int   `json:"summary,omitempty"`
+			Schema      *previewedSchema `json:"schema,omitempty"`
+			Messages    []string         `json:"messages,omitempty"`
+		}{list, d.suppressed, d.summary, d.schema, d.messages}
+	case "sarif":
+		log := newSarifLog(d.list)
+		if d.summary != nil || d.suppressed > 0 || d.schema != nil || d.messages != nil {
+			props := map[string]interface{}{}
+			if d.summary != nil {
+				props["summary"] = d.summary
+			}
+			if d.suppressed > 0 {
+				props["suppressed"] = d.suppressed
+			}
+			if d.schema != nil {
+				props["schema"] = d.schema
+			}
+			if d.messages != nil {
+				props["messages"] = d.messages
+			}
+			log.Runs[0].Properties = props
+		}
+		v = log
+	default:
+		if d.suppressed > 0 {
+			if _, err := fmt.Fprintf(d.w, "... and %d more\n", d.suppressed); err != nil {
+				return err
+			}
+		}
+		for _, key := range d.summaryKeys {
+			if _, err := fmt.Fprintf(d.w, "%s: %d\n", key, d.summary[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = d.w.Write(append(b, '\n'))
+	return err
+}
+
+// sortByFileAndLine orders list by file, in the order they first appear,
+// and then by line.
+func sortByFileAndLine(list []Diagnostic) {
+	files := map[string]int{}
+	for _, diag := range list {
+		if _, ok := files[diag.File]; !ok {
+			files[diag.File] = len(files)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if files[list[i].File] != files[list[j].File] {
+			return files[list[i].File] < files[list[j].File]
+		}
+		return list[i].Line < list[j].Line
+	})
+}
+
+// rowErrors is a line of the -validate-only output: the problems of one
+// row.
+type rowErrors struct {
+	File   string     `json:"file,omitempty"`
+	Line   int        `json:"line"`
+	Errors []rowError `json:"errors"`
+}
+
+type rowError struct {
+	Column  int    `json:"column,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// writeRows writes the kept diagnostics to d.rows grouped by row, the
+// files in the order they were first reported and the rows of each by
+// line.
+func (d *diagnostics) writeRows() error {
+	list := append([]Diagnostic(nil), d.list...)
+	sortByFileAndLine(list)
+
+	enc := json.NewEncoder(d.rows)
+	for i := 0; i < len(list); {
+		row := rowErrors{File: list[i].File, Line: list[i].Line}
+		for ; i < len(list) && list[i].File == row.File && list[i].Line == row.Line; i++ {
+			row.Errors = append(row.Errors, rowError{list[i].Column, list[i].Rule, list[i].Message})
+		}
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The subset of SARIF 2.1.0 needed to describe csvlint diagnostics.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool              `json:"tool"`
+		Results    []sarifResult          `json:"results"`
+		Properties map[string]interface{} `json:"properties,omitempty"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name    string      `json:"name"`
+		Version string      `json:"version"`
+		Rules   []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+)
+
+func newSarifLog(list []Diagnostic) *sarifLog {
+	driver := sarifDriver{Name: Name, Version: Version, Rules: []sarifRule{}}
+	results := []sarifResult{}
+	seen := map[string]bool{}
+	for _, d := range list {
+		if !seen[d.Rule] {
+			seen[d.Rule] = true
+			driver.Rules = append(driver.Rules, sarifRule{ID: d.Rule})
+		}
+
+		res := sarifResult{RuleID: d.Rule, Level: "error", Message: sarifMessage{Text: d.Message}}
+		if d.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: d.File}}}
+			if d.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+			}
+			res.Locations = []sarifLocation{loc}
+		}
+		results = append(results, res)
+	}
+
+	return &sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
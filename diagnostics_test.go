@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_reportFlag_json(t *testing.T) {
+	files := writeFiles(t, "a\n")
+	missing := filepath.Join(filepath.Dir(files[0]), "missing.csv")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-report", "json", files[0], missing}
+
+	status := cli.Run(args)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	var report struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(errStream.Bytes(), &report); err != nil {
+		t.Fatalf("expected a json report, got %q: %s", errStream.String(), err)
+	}
+	if len(report.Diagnostics) != 1 || report.Diagnostics[0].File != missing || report.Diagnostics[0].Rule != "file" {
+		t.Errorf("unexpected diagnostics %+v", report.Diagnostics)
+	}
+}
+
+// Errors and -verbose messages are part of the report, which is all that is
+// written to stderr.
+func TestRun_reportFlag_jsonErrors(t *testing.T) {
+	files := writeFiles(t, "a\n1\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-report", "json", "-verbose", "-encoding", "auto", "-sort", "nope", files[0]}
+
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	var report struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+		Messages    []string     `json:"messages"`
+	}
+	if err := json.Unmarshal(errStream.Bytes(), &report); err != nil {
+		t.Fatalf("expected a json report, got %q: %s", errStream.String(), err)
+	}
+	if len(report.Diagnostics) != 1 || report.Diagnostics[0].Rule != "file" || report.Diagnostics[0].Message != "-sort: unknown column \"nope\"" {
+		t.Errorf("unexpected diagnostics %+v", report.Diagnostics)
+	}
+	if len(report.Messages) != 1 || report.Messages[0] != files[0]+": detected encoding utf8" {
+		t.Errorf("unexpected messages %q", report.Messages)
+	}
+
+	errStream.Reset()
+	if status := cli.Run([]string{"./csvlint", "-report", "json", "-sort-external", files[0]}); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if err := json.Unmarshal(errStream.Bytes(), &report); err != nil {
+		t.Fatalf("expected a json report, got %q: %s", errStream.String(), err)
+	}
+	if len(report.Diagnostics) != 1 || report.Diagnostics[0].Rule != "error" || report.Diagnostics[0].Message != "-sort-external and -sort-memory need -sort" {
+		t.Errorf("unexpected diagnostics %+v", report.Diagnostics)
+	}
+}
+
+func TestRun_reportFlag_sarif(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.csv")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-report", "sarif", missing}
+
+	cli.Run(args)
+
+	var log sarifLog
+	if err := json.Unmarshal(errStream.Bytes(), &log); err != nil {
+		t.Fatalf("expected a sarif log, got %q: %s", errStream.String(), err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected sarif log %+v", log)
+	}
+	res := log.Runs[0].Results[0]
+	if res.RuleID != "file" || res.Locations[0].PhysicalLocation.ArtifactLocation.URI != missing {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestDiagnostic_String(t *testing.T) {
+	d := Diagnostic{File: "a.csv", Line: 3, Column: 2, Rule: "parse", Message: "bare quote"}
+	expected := "a.csv: line 3 column 2: bare quote"
+	if d.String() != expected {
+		t.Errorf("expected %q to eq %q", d.String(), expected)
+	}
+}
+
+func TestRun_maxErrorsFlag(t *testing.T) {
+	files := writeFiles(t, "n\na\nb\n", "n\nc\nd\n")
+	for _, workers := range []string{"1", "2"} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := append(strings.Split("./csvlint -range n=0:1 -max-errors 3 -strict -file-workers "+workers, " "), files...)
+
+		status := cli.Run(args)
+		if status != ExitCodeError {
+			t.Errorf("expected %d to eq %d", status, ExitCodeError)
+		}
+		lines := strings.Split(strings.TrimSuffix(errStream.String(), "\n"), "\n")
+		if len(lines) != 4 || lines[3] != "... and 1 more" {
+			t.Errorf("expected 3 diagnostics and a truncation note, got %q", errStream.String())
+		}
+	}
+}
+
+func TestRun_validateOnlyFlag(t *testing.T) {
+	files := writeFiles(t, "id,age\n1,200\n2,\" \"\n3,30\n", "id,age\n4,-1\n")
+	missing := filepath.Join(filepath.Dir(files[0]), "missing.csv")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := append(strings.Split("./csvlint -validate-only -file-workers 2 -check-whitespace-only -range age=0:120", " "), files[0], missing, files[1])
+
+	status := cli.Run(args)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	expected := `{"file":"` + files[0] + `","line":2,"errors":[{"column":2,"rule":"range","message":"age: 200 is outside 0:120"}]}` + "\n" +
+		`{"file":"` + files[0] + `","line":3,"errors":[{"column":2,"rule":"whitespace-only","message":"whitespace-only field"},{"column":2,"rule":"number","message":"age: \"\" is not a number"}]}` + "\n" +
+		`{"file":"` + files[1] + `","line":2,"errors":[{"column":2,"rule":"range","message":"age: -1 is outside 0:120"}]}` + "\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	// problems that are not about a row are still written to stderr
+	if expected := missing + ": open: no such file or directory\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+
+	errStream.Reset()
+	cli = &CLI{outStream: new(bytes.Buffer), errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-validate-only", "-report", "json", files[0]}); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if expected := "-validate-only writes its own report and cannot be combined with -report\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_sampleErrorsFlag(t *testing.T) {
+	files := writeFiles(t, "id,age\n1,200\n2,\" \"\n3,300\n", "id,age\n4,x\n5,500\n")
+	for _, workers := range []string{"1", "2"} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := append(strings.Split("./csvlint -lint -check-whitespace-only -range age=0:120 -sample-errors 3 -file-workers "+workers, " "), files...)
+
+		if status := cli.Run(args); status != ExitCodeError {
+			t.Errorf("expected %d to eq %d", status, ExitCodeError)
+		}
+		expected := "3 examples of 6 problems:\n" +
+			"  " + files[0] + ": line 2 column 2: age: 200 is outside 0:120 [range]\n" +
+			"  " + files[0] + ": line 3 column 2: whitespace-only field [whitespace-only]\n" +
+			"  " + files[0] + ": line 3 column 2: age: \"\" is not a number [number]\n" +
+			"range problems: 3\n" +
+			"whitespace-only problems: 1\n" +
+			"number problems: 2\n"
+		if errStream.String() != expected {
+			t.Errorf("%s workers: expected %q to eq %q", workers, errStream.String(), expected)
+		}
+	}
+
+	errStream := new(bytes.Buffer)
+	cli := &CLI{outStream: new(bytes.Buffer), errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-sample-errors", "2", "-max-errors", "2", files[0]}); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if expected := "-sample-errors cannot be combined with -max-errors or -validate-only\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dialect describes how an input file is formatted, so that the settings
+// for a recurring feed can be kept in a file next to the data:
+//
+//	{
+//	  "delimiter": ";",
+//	  "quotechar": "'",
+//	  "encoding": "sjis",
+//	  "header": false
+//	}
+//
+// Every key is optional. Flags given on the command line take precedence.
+type Dialect struct {
+	// Delimiter is the field separator, like -delimiter.
+	Delimiter string `json:"delimiter"`
+	// QuoteChar is the character fields are quoted with, like -quote-char.
+	QuoteChar string `json:"quotechar"`
+	// Encoding is the input encoding, like -encoding.
+	Encoding string `json:"encoding"`
+	// Header tells whether the first record is a header; false is like
+	// -no-header.
+	Header *bool `json:"header"`
+}
+
+// loadDialect reads and validates a dialect file.
+func loadDialect(name string) (*Dialect, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	d := new(Dialect)
+	if err := dec.Decode(d); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	if err := d.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return d, nil
+}
+
+func (d *Dialect) validate() error {
+	if d.Delimiter != "" {
+		if _, err := parseDelimiter(d.Delimiter); err != nil {
+			return err
+		}
+	}
+	if d.QuoteChar != "" {
+		if _, err := parseQuoteChar(d.QuoteChar); err != nil {
+			return err
+		}
+	}
+	if !validEncoding(d.Encoding) {
+		return fmt.Errorf("invalid encoding %q", d.Encoding)
+	}
+	return nil
+}
+
+// apply copies the settings of d into opts, except those whose flag was
+// given explicitly.
+func (d *Dialect) apply(flags *flag.FlagSet, opts *Options) {
+	if d.Delimiter != "" && !isFlagSet(flags, "delimiter") && !isFlagSet(flags, "d") {
+		opts.Comma, _ = parseDelimiter(d.Delimiter)
+	}
+	if d.QuoteChar != "" && !isFlagSet(flags, "quote-char") {
+		opts.QuoteChar, _ = parseQuoteChar(d.QuoteChar)
+	}
+	if d.Encoding != "" && !isFlagSet(flags, "encoding") {
+		opts.Encoding = d.Encoding
+	}
+	if d.Header != nil && !isFlagSet(flags, "no-header") {
+		opts.NoHeader = !*d.Header
+	}
+}
+
+// parseDelimiter parses an input delimiter, which may be escaped like \t.
+func parseDelimiter(s string) (rune, error) {
+	v, err := unescape(s)
+	if err != nil || utf8.RuneCountInString(v) != 1 {
+		return 0, fmt.Errorf("invalid delimiter %q: must be a single character", s)
+	}
+	r, _ := utf8.DecodeRuneInString(v)
+	if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q", s)
+	}
+	return r, nil
+}
+
+// parseQuoteChar parses a quote character. Only ASCII characters are
+// supported, since they are swapped with '"' before parsing.
+func parseQuoteChar(s string) (rune, error) {
+	if len(s) != 1 || s[0] >= utf8.RuneSelf || s[0] == '\r' || s[0] == '\n' {
+		return 0, fmt.Errorf("invalid quote character %q: must be a single ASCII character", s)
+	}
+	return rune(s[0]), nil
+}
+
+// swapReader exchanges two ASCII bytes in everything read through it. The
+// csv reader only knows '"' as the quote character, so another quote
+// character is swapped with it on input and back again in the fields.
+type swapReader struct {
+	r    io.Reader
+	a, b byte
+}
+
+func (s *swapReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	for i, c := range p[:n] {
+		switch c {
+		case s.a:
+			p[i] = s.b
+		case s.b:
+			p[i] = s.a
+		}
+	}
+	return n, err
+}
+
+// swapRunes exchanges a and b in v.
+func swapRunes(v string, a, b rune) string {
+	if !strings.ContainsRune(v, a) && !strings.ContainsRune(v, b) {
+		return v
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case a:
+			return b
+		case b:
+			return a
+		}
+		return r
+	}, v)
+}
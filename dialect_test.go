@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_dialectFlag(t *testing.T) {
+	dir := t.TempDir()
+	dialect := filepath.Join(dir, "feed.json")
+	if err := os.WriteFile(dialect, []byte(`{"delimiter": ";", "quotechar": "'", "header": false}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args, expected string
+	}{
+		{"", "\"1\",\"a;b\",\"say \"\"hi\"\"\"\n"},
+		// An explicit flag wins over the dialect file.
+		{"-quote-char \"", "\"1\",\"'a\",\"b'\",\"say \"\"hi\"\"\"\n"},
+	}
+	for _, c := range cases {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("1;'a;b';say \"hi\"\n"), outStream: outStream, errStream: errStream}
+		args := append([]string{"./csvlint", "-dialect", dialect}, strings.Fields(c.args)...)
+
+		status := cli.Run(args)
+		if status != ExitCodeOK {
+			t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != c.expected {
+			t.Errorf("expected %q to eq %q", outStream.String(), c.expected)
+		}
+	}
+}
+
+func TestLoadDialect_invalid(t *testing.T) {
+	dir := t.TempDir()
+	for _, body := range []string{
+		`{"delimiter": ";;"}`,
+		`{"quotechar": "«"}`,
+		`{"encoding": "latin9"}`,
+		`{"separator": ";"}`,
+	} {
+		name := filepath.Join(dir, "dialect.json")
+		if err := os.WriteFile(name, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadDialect(name); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Statuses of the rows written by -diff.
+const (
+	DiffAdded   = "added"
+	DiffChanged = "changed"
+	DiffRemoved = "removed"
+)
+
+// diffStatusColumn is the column -diff adds to the header.
+const diffStatusColumn = "status"
+
+// differ compares the rows of the input with those of another csv file by
+// key, so that only rows that were added or changed are written, followed
+// by those that were removed. The smaller side is kept in memory by key
+// and the other one streamed: the other file as the input is read, or the
+// input, whose rows are then written once the other file has been read
+// through twice, first to compare and then to find the removed rows.
+type differ struct {
+	key      string
+	other    string
+	noHeader bool
+	// held is set when the input is the side kept in memory.
+	held bool
+
+	// header is that of the other file. rows are those of the side kept
+	// in memory and byKey the index of each by key
+	header []string
+	rows   [][]string
+	byKey  map[string]int
+	seen   []bool
+
+	bound  bool
+	keyIdx int
+	// index maps the input columns to those of the other file, -1 for
+	// the columns it does not have. It is nil without a header, when
+	// columns are matched by position.
+	index []int
+	// lines holds the line of every key of the input.
+	lines  map[string]int
+	memory *memoryLimit
+}
+
+func newDiffer(other, key string, noHeader bool, memory *memoryLimit) *differ {
+	return &differ{key: key, other: other, noHeader: noHeader, byKey: map[string]int{}, lines: map[string]int{}, memory: memory}
+}
+
+// errOtherHeader stops reading the other file once its header is read.
+var errOtherHeader = errors.New("header read")
+
+// otherOptions returns the options the other file of -diff or -keys-not-in
+// is read with: the dialect, encoding, filters and transforms of the
+// input, so that its rows compare with those written, but none of the
+// checks or outputs. Every row is handed to collect instead.
+func otherOptions(opts *Options, collect func(record []string, isHeader bool, line int) error) *Options {
+	o := *opts
+	o.collect = collect
+	o.SkipHeader = false
+	o.diff = nil
+	o.sorter = nil
+	o.schema = nil
+	o.checkpoint = nil
+	o.quarantine = nil
+	o.errorRows = nil
+	o.rules = nil
+	o.read = nil
+	o.record = nil
+	o.Rows = nil
+	o.Sample = 0
+	o.AddIndex = ""
+	o.index = nil
+	o.UniqueKey = nil
+	o.keys = nil
+	o.RequireColumns = nil
+	o.Ranges = nil
+	o.Numeric = nil
+	o.Formats = nil
+	o.CheckLineEndings = false
+	o.CheckBOM = false
+	o.FieldHistogram = false
+	o.AbortOnEmpty = ""
+	return &o
+}
+
+// readKeyed reads the other file of -diff or -keys-not-in, see
+// otherOptions, and resolves key in it. each gets the header, unless
+// -no-header, and every data row with its key, line and the column of the
+// key.
+func readKeyed(name, key string, opts *Options, diag *diagnostics, each func(k string, record []string, isHeader bool, line, column int) error) error {
+	keyIdx := -1
+	_, err := transformFile(name, io.Discard, diag, otherOptions(opts, func(record []string, isHeader bool, line int) error {
+		if keyIdx < 0 {
+			var header []string
+			if isHeader {
+				header = record
+			}
+			var err error
+			if keyIdx, err = columnIndex(key, headerIndex(header), opts.NoHeader); err != nil {
+				return fmt.Errorf("-key: %s", err)
+			}
+		}
+		if isHeader {
+			return each("", record, true, 0, 0)
+		}
+		return each(field(record, keyIdx), record, false, line, keyIdx+1)
+	}))
+	if err == errOtherHeader {
+		return nil
+	} else if err != nil && !reported(err) {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	return err
+}
+
+// load reads the other file, or only its header when it is larger than the
+// input, of inputSize bytes, or -1 for stdin. Duplicate keys are reported
+// and the first row of each used.
+func (d *differ) load(opts *Options, diag *diagnostics, inputSize int64) error {
+	if info, err := os.Stat(d.other); err != nil {
+		return err
+	} else if inputSize >= 0 && inputSize < info.Size() {
+		d.held = true
+		if d.noHeader {
+			return nil
+		}
+		return readKeyed(d.other, d.key, opts, diag, func(_ string, record []string, _ bool, _, _ int) error {
+			d.header = record
+			return errOtherHeader
+		})
+	}
+
+	lines := map[string]int{}
+	err := readKeyed(d.other, d.key, opts, diag, func(k string, record []string, isHeader bool, line, column int) error {
+		if isHeader {
+			d.header = record
+			return nil
+		}
+		if first, ok := lines[k]; ok {
+			diag.report(Diagnostic{File: d.other, Line: line, Column: column, Rule: "diff", Message: fmt.Sprintf("duplicate key %q, the row of line %d is used", k, first)})
+			return nil
+		}
+		if err := d.memory.grow("-diff", recordSize(record)); err != nil {
+			return err
+		}
+		lines[k] = line
+		d.byKey[k] = len(d.rows)
+		d.rows = append(d.rows, record)
+		return nil
+	})
+	d.seen = make([]bool, len(d.rows))
+	return err
+}
+
+// field returns the nth field of record, or "" when it is short.
+func field(record []string, n int) string {
+	if n < len(record) {
+		return record[n]
+	}
+	return ""
+}
+
+// withStatus returns a copy of record followed by status.
+func withStatus(record []string, status string) []string {
+	return append(record[:len(record):len(record)], status)
+}
+
+// bind resolves the key and matches the columns of the input header with
+// those of the other file by name. It returns the header with the status
+// column added.
+func (d *differ) bind(header []string) ([]string, error) {
+	d.bound = true
+	index := headerIndex(header)
+	var err error
+	if d.keyIdx, err = columnIndex(d.key, index, d.noHeader); err != nil {
+		return nil, fmt.Errorf("-key: %s", err)
+	}
+	if d.noHeader {
+		return withStatus(header, diffStatusColumn), nil
+	}
+	if _, ok := index[diffStatusColumn]; ok {
+		return nil, fmt.Errorf("-diff cannot add the %s column: the header already has one", diffStatusColumn)
+	}
+	other := headerIndex(d.header)
+	d.index = make([]int, len(header))
+	for i, name := range header {
+		d.index[i] = -1
+		if n, ok := other[name]; ok {
+			d.index[i] = n
+		}
+	}
+	return withStatus(header, diffStatusColumn), nil
+}
+
+// compare returns the record followed by its status, or nil when the
+// other file has the same row. A key already seen in the input is
+// reported and the row left out. When the input is held, the row is kept
+// and written by finish.
+func (d *differ) compare(name string, record []string, line int, diag *diagnostics) ([]string, error) {
+	if !d.bound {
+		// without a header, only the key is bound
+		if _, err := d.bind(nil); err != nil {
+			return nil, err
+		}
+	}
+	k := field(record, d.keyIdx)
+	if first, ok := d.lines[k]; ok {
+		diag.report(Diagnostic{File: name, Line: line, Column: d.keyIdx + 1, Rule: "diff", Message: fmt.Sprintf("duplicate key %q, the row of line %d is used", k, first)})
+		return nil, nil
+	}
+	d.lines[k] = line
+
+	if d.held {
+		d.byKey[k] = len(d.rows)
+		d.rows = append(d.rows, append([]string(nil), record...))
+		return nil, d.memory.grow("-diff", recordSize(record))
+	}
+	n, ok := d.byKey[k]
+	if !ok {
+		return withStatus(record, DiffAdded), nil
+	}
+	d.seen[n] = true
+	if d.same(record, d.rows[n]) {
+		return nil, nil
+	}
+	return withStatus(record, DiffChanged), nil
+}
+
+// same tells whether the input record and the other file's row have the
+// same fields.
+func (d *differ) same(record, other []string) bool {
+	if d.index == nil {
+		return equalRecords(record, other)
+	}
+	for i, v := range record {
+		o := ""
+		if i < len(d.index) && d.index[i] >= 0 {
+			o = field(other, d.index[i])
+		}
+		if v != o {
+			return false
+		}
+	}
+	return true
+}
+
+// asRemoved returns a row of the other file in the columns of the input,
+// followed by its status.
+func (d *differ) asRemoved(other []string) []string {
+	if d.index == nil {
+		return withStatus(other, DiffRemoved)
+	}
+	row := make([]string, 0, len(d.index)+1)
+	for _, i := range d.index {
+		v := ""
+		if i >= 0 {
+			v = field(other, i)
+		}
+		row = append(row, v)
+	}
+	return append(row, DiffRemoved)
+}
+
+// finish writes with emit the rows left once the input is read: those of
+// the other file whose key was not in the input or, when the input is
+// held, its added and changed rows before them.
+func (d *differ) finish(opts *Options, diag *diagnostics, emit func([]string) error) error {
+	if !d.held {
+		for n, record := range d.rows {
+			if !d.seen[n] {
+				if err := emit(d.asRemoved(record)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	status := make([]string, len(d.rows))
+	for n := range status {
+		status[n] = DiffAdded
+	}
+	// firsts holds the line of the first row of every key of the other
+	// file, the one compared
+	firsts := map[string]int{}
+	err := readKeyed(d.other, d.key, opts, diag, func(k string, record []string, isHeader bool, line, column int) error {
+		if isHeader {
+			return nil
+		}
+		if first, ok := firsts[k]; ok {
+			diag.report(Diagnostic{File: d.other, Line: line, Column: column, Rule: "diff", Message: fmt.Sprintf("duplicate key %q, the row of line %d is used", k, first)})
+			return nil
+		}
+		firsts[k] = line
+		if n, ok := d.byKey[k]; ok {
+			status[n] = DiffChanged
+			if d.same(d.rows[n], record) {
+				status[n] = ""
+			}
+			return nil
+		}
+		return d.memory.grow("-diff", int64(len(k))+fieldOverhead)
+	})
+	if err != nil {
+		return err
+	}
+	for n, record := range d.rows {
+		if status[n] != "" {
+			if err := emit(withStatus(record, status[n])); err != nil {
+				return err
+			}
+		}
+	}
+
+	// read again for the removed rows, with the problems already reported
+	quiet := diag.child(io.Discard)
+	return readKeyed(d.other, d.key, opts, quiet, func(k string, record []string, isHeader bool, line, _ int) error {
+		if isHeader || firsts[k] != line {
+			return nil
+		}
+		if _, ok := d.byKey[k]; ok {
+			return nil
+		}
+		return emit(d.asRemoved(record))
+	})
+}
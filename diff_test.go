@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_diffFlag(t *testing.T) {
+	files := writeFiles(t,
+		"id,name,age\n1,Ada,36\n2,Bob,40\n3,Cy,22\n3,Dup,1\n",
+		"id,age,name\n1,36,Ada\n2,41,Bob\n4,50,Di\n4,51,Dup\n",
+		"1,Ada\n2,Bob\n",
+		"2,Bo\n5,Ed\n",
+	)
+
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{
+			[]string{"-diff", files[0], "-key", "id", files[1]},
+			ExitCodeOK,
+			"id,age,name,status\n2,41,Bob,changed\n4,50,Di,added\n3,22,Cy,removed\n",
+			files[0] + ": line 5 column 1: duplicate key \"3\", the row of line 4 is used\n" +
+				files[1] + ": line 5 column 1: duplicate key \"4\", the row of line 4 is used\n",
+		},
+		{
+			[]string{"-diff", files[0], "-key", "id", "-select", "id,name", "-skip-header", files[1]},
+			ExitCodeOK,
+			"id,name,status\n4,Di,added\n3,Cy,removed\n",
+			files[0] + ": line 5 column 1: duplicate key \"3\", the row of line 4 is used\n" +
+				files[1] + ": line 5 column 1: duplicate key \"4\", the row of line 4 is used\n",
+		},
+		{
+			[]string{"-no-header", "-diff", files[2], "-key", "1", files[3]},
+			ExitCodeOK,
+			"2,Bo,changed\n5,Ed,added\n1,Ada,removed\n",
+			"",
+		},
+		{[]string{"-diff", files[0], files[1]}, ExitCodeError, "", "-diff needs -key\n"},
+		{[]string{"-key", "id", files[1]}, ExitCodeError, "", "-key needs -diff or -keys-not-in\n"},
+		{[]string{"-diff", files[0], "-key", "name", "-select", "id", files[1]}, ExitCodeError, "", files[0] + ": -key: unknown column \"name\"\n"},
+		{[]string{"-diff", files[0], "-key", "x", files[1]}, ExitCodeError, "", files[0] + ": -key: unknown column \"x\"\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(""), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(append([]string{"./csvlint", "-quote", "minimal"}, test.args...))
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+// The other file is read with the dialect and transforms of the input, and
+// when the input is the smaller side it is the one kept in memory, with
+// the same output.
+func TestRun_diffFlagOtherFile(t *testing.T) {
+	files := writeFiles(t,
+		"id;name\n1;Ada\n2; Bob\n",
+		"id;name\n1;Ada \n2;Bob\n",
+		"id,age,name\n1,36,Ada\n2,41,Bob\n3,22,Cy\n3,1,Dup\n5,60,Ed\n6,70,Flo\n",
+		"id,name,age\n2,Bob,40\n1,Ada,36\n4,Di,50\n",
+	)
+	tests := []struct {
+		args     []string
+		expected string
+		errors   string
+	}{
+		{[]string{"-delimiter", ";", "-remove-space", "-diff", files[0], "-key", "id", files[1]}, "id,name,status\n", ""},
+		{[]string{"-delimiter", ";", "-diff", files[0], "-key", "id", files[1]}, "id,name,status\n1,Ada ,changed\n2,Bob,changed\n", ""},
+		{[]string{"-diff", files[2], "-key", "id", files[3]}, "id,name,age,status\n2,Bob,40,changed\n4,Di,50,added\n3,Cy,22,removed\n5,Ed,60,removed\n6,Flo,70,removed\n", files[2] + ": line 5 column 1: duplicate key \"3\", the row of line 4 is used\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(append([]string{"./csvlint", "-quote", "minimal"}, test.args...)); status != ExitCodeOK {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, ExitCodeOK)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
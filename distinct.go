@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// distinctValues collects the distinct values of the -values column. It
+// keeps each value once, so memory grows with the column's cardinality.
+// With -keys-not-in, the values in exclude are left out.
+type distinctValues struct {
+	mu      sync.Mutex
+	column  string
+	index   int
+	seen    map[string]bool
+	exclude map[string]struct{}
+	memory  *memoryLimit
+}
+
+func newDistinctValues(column string, noHeader bool) (*distinctValues, error) {
+	d := &distinctValues{column: column, index: -1, seen: map[string]bool{}}
+	if noHeader {
+		var err error
+		if d.index, err = columnIndex(column, nil, true); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d *distinctValues) add(record []string, isHeader bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if isHeader {
+		if d.index >= 0 {
+			return nil
+		}
+		var err error
+		d.index, err = columnIndex(d.column, headerIndex(record), false)
+		return err
+	}
+	v := ""
+	if d.index < len(record) {
+		v = record[d.index]
+	}
+	if _, ok := d.exclude[v]; ok || d.seen[v] {
+		return nil
+	}
+	d.seen[v] = true
+	return d.memory.grow("-values", int64(len(v))+fieldOverhead)
+}
+
+// loadKeys reads the values of the key column of the other file of
+// -keys-not-in, which is kept in memory. The file is read as the input is,
+// so that its values compare with those written.
+func loadKeys(other, key string, opts *Options, diag *diagnostics) (map[string]struct{}, error) {
+	keys := map[string]struct{}{}
+	err := readKeyed(other, key, opts, diag, func(k string, _ []string, isHeader bool, _, _ int) error {
+		if _, ok := keys[k]; ok || isHeader {
+			return nil
+		}
+		keys[k] = struct{}{}
+		return opts.memory.grow("-keys-not-in", int64(len(k))+fieldOverhead)
+	})
+	return keys, err
+}
+
+// write outputs the values sorted, one per line or as a JSON array.
+func (d *distinctValues) write(w io.Writer, asJSON bool, opts *Options) error {
+	values := make([]string, 0, len(d.seen))
+	for v := range d.seen {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	if asJSON {
+		b, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+	for _, v := range values {
+		if _, err := fmt.Fprint(w, v, opts.lineEnding()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_valuesFlag(t *testing.T) {
+	input := "id,status\n1,b\n2,\"a,x\"\n3,b\n4, c \n5\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -values status", ExitCodeOK, "\n c \na,x\nb\n", ""},
+		{"./csvlint -values status -remove-space -skip-header -crlf", ExitCodeOK, "\r\na,x\r\nb\r\nc\r\n", ""},
+		{"./csvlint -values status -json", ExitCodeOK, "[\n  \"\",\n  \" c \",\n  \"a,x\",\n  \"b\"\n]\n", ""},
+		{"./csvlint -values 2 -no-header", ExitCodeOK, "\n c \na,x\nb\nstatus\n", ""},
+		{"./csvlint -values st -select status:st", ExitCodeOK, "\n c \na,x\nb\n", ""},
+		{"./csvlint -values name", ExitCodeError, "", "unknown column \"name\"\n"},
+		{"./csvlint -json", ExitCodeError, "", "-json needs -values\n"},
+		{"./csvlint -values status -file-workers 4", ExitCodeError, "", "-values cannot be combined with -count-by, -ddl, -density, -pretty, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_keysNotInFlag(t *testing.T) {
+	files := writeFiles(t,
+		"code,name\nb,Bob\nd,Di\n",
+		"name,code\nZed,c\nAda,a\nBob,b\nCy,c\nEd,e\n",
+		"b\nd\n",
+		"c,1\na,2\nb,3\n",
+		"code;n\n b;1\n",
+		"name;code\nBob;b\nCy;c\n",
+	)
+
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{[]string{"-keys-not-in", files[0], "-key", "code", files[1]}, ExitCodeOK, "a\nc\ne\n", ""},
+		{[]string{"-keys-not-in", files[0], "-key", "code", "-json", files[1]}, ExitCodeOK, "[\n  \"a\",\n  \"c\",\n  \"e\"\n]\n", ""},
+		{[]string{"-no-header", "-keys-not-in", files[2], "-key", "1", files[3]}, ExitCodeOK, "a\nc\n", ""},
+		{[]string{"-delimiter", ";", "-remove-space", "-keys-not-in", files[4], "-key", "code", files[5]}, ExitCodeOK, "c\n", ""},
+		{[]string{"-delimiter", ";", "-keys-not-in", files[4], "-key", "code", files[5]}, ExitCodeOK, "b\nc\n", ""},
+		{[]string{"-keys-not-in", files[0], files[1]}, ExitCodeError, "", "-keys-not-in needs -key\n"},
+		{[]string{"-keys-not-in", files[0], "-key", "name", "-values", "name", files[1]}, ExitCodeError, "", "-keys-not-in cannot be combined with -diff, -values, other output formats, -lint, -partition-by, -split-rows, -split-bytes, -check-idempotent or -explain-record\n"},
+		{[]string{"-keys-not-in", files[3], "-key", "code", files[1]}, ExitCodeError, "", files[3] + ": -key: unknown column \"code\"\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(""), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(append([]string{"./csvlint"}, test.args...))
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+// The column is bound in the header of the first file, so the files cannot
+// be read concurrently.
+func TestRun_valuesFlagFileWorkers(t *testing.T) {
+	files := writeFiles(t, "k\na\n", "k\nb\n", "k\nc\n", "k\nd\n")
+	for _, workers := range []string{"1", "4"} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+
+		status := cli.Run(append([]string{"./csvlint", "-file-workers", workers, "-values", "k"}, files...))
+		if workers == "1" {
+			if status != ExitCodeOK || outStream.String() != "a\nb\nc\nd\n" {
+				t.Errorf("%s: expected %d, %q to eq %d, %q: %s", workers, status, outStream.String(), ExitCodeOK, "a\nb\nc\nd\n", errStream.String())
+			}
+		} else if status != ExitCodeError || !strings.Contains(errStream.String(), "-file-workers") {
+			t.Errorf("%s: expected %d to eq %d: %s", workers, status, ExitCodeError, errStream.String())
+		}
+	}
+}
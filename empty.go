@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Modes of -abort-on-empty.
+const (
+	// EmptyRows fails an input without data rows, even with a header.
+	EmptyRows = "rows"
+	// EmptyFile fails only an input without any record, header included.
+	EmptyFile = "file"
+)
+
+// emptyValue is the mode of -abort-on-empty, which can be given alone for
+// EmptyRows.
+type emptyValue string
+
+func (v *emptyValue) String() string {
+	return string(*v)
+}
+
+func (v *emptyValue) Set(s string) error {
+	switch s {
+	case "true":
+		s = EmptyRows
+	case "false":
+		s = ""
+	case EmptyRows, EmptyFile:
+	default:
+		return fmt.Errorf("expected %s or %s, got %q", EmptyRows, EmptyFile, s)
+	}
+	*v = emptyValue(s)
+	return nil
+}
+
+func (v *emptyValue) IsBoolFlag() bool { return true }
+
+// errEmptyInput is returned by transform when -abort-on-empty meets an
+// input without rows. It has already been reported.
+var errEmptyInput = errors.New("empty input")
+
+// checkEmpty reports an input that read records, of which dataRows data
+// rows, as empty for the mode, and returns errEmptyInput.
+func checkEmpty(name, mode string, records, dataRows int, diag *diagnostics) error {
+	switch {
+	case mode == EmptyFile && records == 0:
+		diag.report(Diagnostic{File: name, Rule: "empty", Message: "the input has no records"})
+	case mode == EmptyRows && dataRows == 0:
+		diag.report(Diagnostic{File: name, Rule: "empty", Message: "the input has no data rows"})
+	default:
+		return nil
+	}
+	return errEmptyInput
+}
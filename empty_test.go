@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_abortOnEmptyFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		input    string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -abort-on-empty", "id\n1\n", ExitCodeOK, "id\n1\n", ""},
+		{"./csvlint -quote minimal -abort-on-empty", "id\n", ExitCodeError, "id\n", "the input has no data rows\n"},
+		{"./csvlint -quote minimal -abort-on-empty", "", ExitCodeError, "", "the input has no data rows\n"},
+		{"./csvlint -quote minimal -abort-on-empty=file", "id\n", ExitCodeOK, "id\n", ""},
+		{"./csvlint -quote minimal -abort-on-empty=file", "", ExitCodeError, "", "the input has no records\n"},
+		{"./csvlint -quote minimal -abort-on-empty -no-header", "id\n", ExitCodeOK, "id\n", ""},
+		{"./csvlint -quote minimal", "", ExitCodeOK, "", ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(test.input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s %q: expected %d to eq %d", test.args, test.input, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s %q: expected %q to eq %q", test.args, test.input, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s %q: expected %q to eq %q", test.args, test.input, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_abortOnEmptyFiles(t *testing.T) {
+	files := writeFiles(t, "id\n1\n", "id\n", "id\n2\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+
+	args := append([]string{"./csvlint", "-quote", "minimal", "-abort-on-empty"}, files...)
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if expected := "id\n1\n2\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	if expected := files[1] + ": the input has no data rows\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
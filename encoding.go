@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodings are the input encodings accepted by -encoding.
+var encodings = map[string]encoding.Encoding{
+	"utf8":   encoding.Nop,
+	"sjis":   japanese.ShiftJIS,
+	"cp1252": charmap.Windows1252,
+	// A byte order mark, if any, is removed and decides the byte order;
+	// without one utf16 is taken to be little endian, as Windows writes it.
+	"utf16":   unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf16le": unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf16be": unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+}
+
+// sniffSize is how much input is inspected by -encoding auto.
+const sniffSize = 64 * 1024
+
+// validEncoding reports whether name is accepted by -encoding.
+func validEncoding(name string) bool {
+	_, ok := encodings[name]
+	return ok || name == "" || name == "auto"
+}
+
+// decodeInput returns a reader that decodes r from the named encoding to
+// UTF-8, and the encoding it reads. With "auto" the encoding is guessed
+// from the first bytes of r, which are then replayed to the decoder; with
+// "utf16" the byte order mark, if any, decides between utf16le and
+// utf16be.
+func decodeInput(r io.Reader, name string, log func(format string, a ...interface{})) (io.Reader, string) {
+	if name == "auto" {
+		br := bufio.NewReaderSize(r, sniffSize)
+		// A short read is fine: Peek returns what there is before EOF.
+		head, _ := br.Peek(sniffSize)
+		var bom bool
+		name, bom = sniffEncoding(head)
+		log("detected encoding %s", name)
+		r = br
+		if bom && name == "utf8" {
+			return unicode.UTF8BOM.NewDecoder().Reader(r), name
+		}
+	}
+	if name == "utf16" {
+		br := bufio.NewReader(r)
+		name = "utf16le"
+		if head, _ := br.Peek(2); bytes.Equal(head, []byte("\xFE\xFF")) {
+			name = "utf16be"
+		}
+		r = br
+	}
+
+	enc, ok := encodings[name]
+	if !ok || enc == encoding.Nop {
+		return r, name
+	}
+	return enc.NewDecoder().Reader(r), name
+}
+
+// rawEncoders encode text back into the named input encoding, without
+// the byte order mark the input may have started with.
+var rawEncoders = map[string]encoding.Encoding{
+	"sjis":    japanese.ShiftJIS,
+	"cp1252":  charmap.Windows1252,
+	"utf16le": unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf16be": unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+}
+
+// encodeRaw returns b, read from an input in the named encoding, in that
+// encoding again.
+func encodeRaw(b []byte, name string) []byte {
+	enc, ok := rawEncoders[name]
+	if !ok {
+		return b
+	}
+	if out, err := enc.NewEncoder().Bytes(b); err == nil {
+		return out
+	}
+	return b
+}
+
+// sniffEncoding guesses the encoding of head, the start of the input. A
+// UTF-8 or UTF-16 byte order mark wins, then valid UTF-8. Then, of
+// Shift-JIS, if every multi-byte sequence is a well formed Shift-JIS
+// character, and Windows-1252, if no byte is undefined in it, the one
+// whose reading looks more like text wins, Windows-1252 on a tie. Anything
+// else is taken to be UTF-8.
+func sniffEncoding(head []byte) (name string, bom bool) {
+	if bytes.HasPrefix(head, []byte("\xEF\xBB\xBF")) {
+		return "utf8", true
+	}
+	if bytes.HasPrefix(head, []byte("\xFF\xFE")) {
+		return "utf16le", true
+	}
+	if bytes.HasPrefix(head, []byte("\xFE\xFF")) {
+		return "utf16be", true
+	}
+
+	// The chunk may end in the middle of a character, which is left out.
+	valid := head
+	for k := 1; k < utf8.UTFMax && k <= len(head); k++ {
+		if tail := head[len(head)-k:]; utf8.RuneStart(tail[0]) {
+			if !utf8.FullRune(tail) {
+				valid = head[:len(head)-k]
+			}
+			break
+		}
+	}
+	if utf8.Valid(valid) {
+		return "utf8", false
+	}
+
+	sjis, cp1252 := isShiftJIS(head), isWindows1252(head)
+	if sjis && cp1252 {
+		// "na\xEFve" is a well formed Shift-JIS kanji between two Latin
+		// letters, but more likely "na\u00efve"
+		if shiftJISScore(head) > windows1252Score(head) {
+			return "sjis", false
+		}
+		return "cp1252", false
+	}
+	if sjis {
+		return "sjis", false
+	}
+	if cp1252 {
+		return "cp1252", false
+	}
+	return "utf8", false
+}
+
+// shiftJISScore counts the Shift-JIS characters of b that are next to
+// another one, as in Japanese words; a lone one between ASCII bytes is
+// rather a Windows-1252 letter.
+func shiftJISScore(b []byte) int {
+	score, prevWide := 0, false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c < 0x80 {
+			prevWide = false
+			continue
+		}
+		n := 1
+		if c >= 0x81 && c <= 0x9F || c >= 0xE0 && c <= 0xFC {
+			n = 2
+		}
+		if prevWide || i+n < len(b) && b[i+n] >= 0x80 {
+			score++
+		}
+		prevWide = true
+		i += n - 1
+	}
+	return score
+}
+
+// windows1252Score counts the bytes of b that are Windows-1252 letters
+// next to an ASCII letter, as accented letters are in Latin words.
+func windows1252Score(b []byte) int {
+	score := 0
+	for i, c := range b {
+		if !isWindows1252Letter(c) {
+			continue
+		}
+		if i > 0 && isASCIILetter(b[i-1]) || i+1 < len(b) && isASCIILetter(b[i+1]) {
+			score++
+		}
+	}
+	return score
+}
+
+func isWindows1252Letter(c byte) bool {
+	switch c {
+	case 0x8A, 0x8C, 0x8E, 0x9A, 0x9C, 0x9E, 0x9F:
+		return true
+	case 0xD7, 0xF7:
+		// the multiplication and division signs
+		return false
+	}
+	return c >= 0xC0
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isShiftJIS(b []byte) bool {
+	pairs := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c < 0x80, c >= 0xA1 && c <= 0xDF:
+			// ASCII or half-width katakana
+		case c >= 0x81 && c <= 0x9F, c >= 0xE0 && c <= 0xFC:
+			if i+1 == len(b) {
+				// cut off at the end of the chunk
+				return pairs > 0
+			}
+			t := b[i+1]
+			if t < 0x40 || t == 0x7F || t > 0xFC {
+				return false
+			}
+			pairs++
+			i++
+		default:
+			return false
+		}
+	}
+	return pairs > 0
+}
+
+func isWindows1252(b []byte) bool {
+	for _, c := range b {
+		switch c {
+		case 0x81, 0x8D, 0x8F, 0x90, 0x9D:
+			return false
+		}
+	}
+	return true
+}
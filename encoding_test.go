@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_encodingFlag_auto(t *testing.T) {
+	ja := "\"id\",\"name\"\n\"1\",\"山田太郎\"\n\"2\",\"ｶﾀｶﾅ\"\n"
+	fr := "\"id\",\"name\"\n\"1\",\"Café crème\"\n\"2\",\"“quoted” – naïve\"\n"
+	tests := []struct {
+		file     string
+		detected string
+		expected string
+	}{
+		{"testdata/utf8.csv", "utf8", ja},
+		{"testdata/utf8bom.csv", "utf8", ja},
+		{"testdata/sjis.csv", "sjis", ja},
+		{"testdata/cp1252.csv", "cp1252", fr},
+		{"testdata/utf16le.csv", "utf16le", ja},
+		{"testdata/utf16be.csv", "utf16be", ja},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := []string{"./csvlint", "-encoding", "auto", "-verbose", "-f", tt.file}
+
+		status := cli.Run(args)
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.file, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.file, outStream.String(), tt.expected)
+		}
+		if !strings.Contains(errStream.String(), "detected encoding "+tt.detected) {
+			t.Errorf("%s: expected %q to report %s", tt.file, errStream.String(), tt.detected)
+		}
+	}
+}
+
+func TestRun_encodingFlag_sjis(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-encoding", "sjis", "-f", "testdata/sjis.csv"}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"1\",\"山田太郎\"\n"
+	if !strings.Contains(outStream.String(), expected) {
+		t.Errorf("expected %q to contain %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_encodingFlag_utf16(t *testing.T) {
+	tests := []struct {
+		encoding string
+		file     string
+	}{
+		{"utf16", "testdata/utf16le.csv"},
+		{"utf16", "testdata/utf16be.csv"},
+		{"utf16", "testdata/utf16le-nobom.csv"},
+		{"utf16le", "testdata/utf16le-nobom.csv"},
+		{"utf16be", "testdata/utf16be.csv"},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := []string{"./csvlint", "-encoding", tt.encoding, "-f", tt.file}
+
+		status := cli.Run(args)
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.file, status, ExitCodeOK, errStream.String())
+		}
+		expected := "\"id\",\"name\"\n\"1\",\"山田太郎\"\n\"2\",\"ｶﾀｶﾅ\"\n"
+		if outStream.String() != expected {
+			t.Errorf("%s %s: expected %q to eq %q", tt.encoding, tt.file, outStream.String(), expected)
+		}
+	}
+}
+
+func TestSniffEncoding(t *testing.T) {
+	tests := []struct {
+		head     string
+		expected string
+	}{
+		// Windows-1252 that is also well formed Shift-JIS
+		{"id,name\n1,na\xEFve\n", "cp1252"},
+		{"id,name\n1,caf\xE9s\n", "cp1252"},
+		{"id,name\n1,\x8E\x52\x93\x63\n", "sjis"},
+		{"id,name\n1,\x8E\x52\xB6\xC0\n", "sjis"},
+		{"id,name\n1,\xE9t\xE9\n", "cp1252"},
+	}
+	for _, tt := range tests {
+		if name, _ := sniffEncoding([]byte(tt.head)); name != tt.expected {
+			t.Errorf("%q: expected %s to eq %s", tt.head, name, tt.expected)
+		}
+	}
+}
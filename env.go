@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// envFlags are the flags that can be given as environment variables, for
+// deployments where the command line is fixed, such as a container image.
+// Each name is that of the flag, the variable CSVLINT_ followed by it.
+var envFlags = []struct {
+	env  string
+	flag string
+	// alias is another name of the flag, which counts as giving it.
+	alias string
+}{
+	{"CSVLINT_DELIMITER", "delimiter", "d"},
+	{"CSVLINT_QUOTE_CHAR", "quote-char", ""},
+	{"CSVLINT_ENCODING", "encoding", ""},
+	{"CSVLINT_NO_HEADER", "no-header", ""},
+	{"CSVLINT_COLUMNS", "select", ""},
+	{"CSVLINT_EXCLUDE", "exclude", ""},
+	{"CSVLINT_REQUIRE_COLUMNS", "require-columns", ""},
+	{"CSVLINT_MAX_COLUMNS", "max-columns", ""},
+	{"CSVLINT_STRICT", "strict", ""},
+}
+
+// applyEnv sets the flags of envFlags that were not given on the command
+// line from their environment variable, when it is set and not empty. A
+// flag set this way counts as given, so it still takes precedence over a
+// -dialect file.
+func applyEnv(flags *flag.FlagSet) error {
+	for _, e := range envFlags {
+		v := os.Getenv(e.env)
+		if v == "" || isFlagSet(flags, e.flag) || (e.alias != "" && isFlagSet(flags, e.alias)) {
+			continue
+		}
+		if err := flags.Set(e.flag, v); err != nil {
+			return fmt.Errorf("invalid %s %q: %s", e.env, v, err)
+		}
+	}
+	return nil
+}
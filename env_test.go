@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_envFlags(t *testing.T) {
+	input := "id;name;city\n1;Ada;London\n"
+	dialect := writeFiles(t, `{"delimiter": ",", "header": false}`)[0]
+	tests := []struct {
+		env      map[string]string
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{map[string]string{"CSVLINT_DELIMITER": ";", "CSVLINT_COLUMNS": "name,id"}, "./csvlint -quote minimal", ExitCodeOK, "name,id\nAda,1\n", ""},
+		{map[string]string{"CSVLINT_DELIMITER": ","}, "./csvlint -quote minimal -d ;", ExitCodeOK, "id,name,city\n1,Ada,London\n", ""},
+		{map[string]string{"CSVLINT_DELIMITER": ";"}, "./csvlint -quote minimal -dialect " + dialect, ExitCodeOK, "id,name,city\n1,Ada,London\n", ""},
+		{map[string]string{"CSVLINT_DELIMITER": ";", "CSVLINT_NO_HEADER": "true", "CSVLINT_COLUMNS": "2"}, "./csvlint -quote minimal", ExitCodeOK, "name\nAda\n", ""},
+		{map[string]string{"CSVLINT_STRICT": "maybe"}, "./csvlint", ExitCodeError, "", "invalid CSVLINT_STRICT \"maybe\": parse error\n"},
+	}
+	for _, tt := range tests {
+		for _, e := range envFlags {
+			t.Setenv(e.env, tt.env[e.env])
+		}
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != tt.status {
+			t.Errorf("%v: expected %d to eq %d: %s", tt.env, status, tt.status, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%v: expected %q to eq %q", tt.env, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%v: expected %q to eq %q", tt.env, errStream.String(), tt.errors)
+		}
+	}
+}
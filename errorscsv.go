@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"sync"
+)
+
+// errorsColumn is the column of -errors-csv describing the problems of a
+// row.
+const errorsColumn = "_errors"
+
+// errorRows writes the rows that fail a check to the -errors-csv file as
+// they were read, followed by the problems found in them. A file processed
+// concurrently with others writes to a buffered one instead, which is
+// merged in the order of the files.
+type errorRows struct {
+	mu      sync.Mutex
+	fp      io.WriteCloser
+	w       *csv.Writer
+	header  bool
+	written int
+
+	// buf, when buffered, holds the rows, and headerRow the header, which
+	// is written by merge unless a file before has one
+	buf       *bytes.Buffer
+	headerRow []string
+}
+
+func newErrorRows(fp io.WriteCloser) *errorRows {
+	return &errorRows{fp: fp, w: csv.NewWriter(fp)}
+}
+
+// buffered returns the errorRows of a file processed concurrently with
+// others.
+func (e *errorRows) buffered() *errorRows {
+	buf := new(bytes.Buffer)
+	return &errorRows{w: csv.NewWriter(buf), buf: buf}
+}
+
+// writeHeader writes the header of the first file, followed by the
+// errors column. The headers of the other files are left out.
+func (e *errorRows) writeHeader(header []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.header {
+		return nil
+	}
+	e.header = true
+	header = append(append([]string(nil), header...), errorsColumn)
+	if e.buf != nil {
+		e.headerRow = header
+		return nil
+	}
+	return e.w.Write(header)
+}
+
+// merge writes the header and the rows of the buffered c.
+func (e *errorRows) merge(c *errorRows) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c.headerRow != nil && !e.header {
+		e.header = true
+		if err := e.w.Write(c.headerRow); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	e.written += c.written
+	_, err := c.buf.WriteTo(e.fp)
+	return err
+}
+
+func (e *errorRows) write(record []string, diags []Diagnostic) error {
+	messages := make([]string, len(diags))
+	for i, d := range diags {
+		messages[i] = d.Message
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.written++
+	return e.w.Write(append(append([]string(nil), record...), strings.Join(messages, "; ")))
+}
+
+func (e *errorRows) close() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		e.fp.Close()
+		return err
+	}
+	return e.fp.Close()
+}
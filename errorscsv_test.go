@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_errorsCSVFlag(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "errors.csv")
+	input := "id,age,email\n1,30,a@example.com\n2,-1,nope\n3,40,b@example.com\n4,x,c@example.com\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal", "-range", "age=0:120", "-validate-email", "email", "-errors-csv", name}
+
+	if status := cli.Run(args); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if outStream.String() != input {
+		t.Errorf("expected %q to eq %q", outStream.String(), input)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", string(b))
+	}
+	if lines[0] != "id,age,email,_errors" {
+		t.Errorf("expected %q to eq %q", lines[0], "id,age,email,_errors")
+	}
+	if !strings.HasPrefix(lines[1], "2,-1,nope,") || !strings.Contains(lines[1], "; ") {
+		t.Errorf("expected %q to hold row 2 and both of its problems", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "4,x,c@example.com,") {
+		t.Errorf("expected %q to hold row 4", lines[2])
+	}
+	if !strings.Contains(errStream.String(), "rows written to -errors-csv: 2\n") {
+		t.Errorf("expected %q to count the rows written", errStream.String())
+	}
+}
+
+// The problems of the steps after the checks, such as -cast and -lookup-missing
+// report, are written with the row they were found in.
+func TestRun_errorsCSVFlagSteps(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "errors.csv")
+	countries := filepath.Join(dir, "countries.csv")
+	if err := os.WriteFile(countries, []byte("code,name\njp,Japan\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := "id,amount,country\n1,2.5,jp\n2,x,jp\n3,4,fr\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-cast", "amount=float", "-lookup", "country=" + countries, "-lookup-missing", "report", "-errors-csv", name}
+
+	if status := cli.Run(args); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "id,amount,country,_errors\n" +
+		"2,x,jp,\"amount: \"\"x\"\" is not a valid float\"\n" +
+		"3,4,fr,\"country: \"\"fr\"\" is not in " + strings.ReplaceAll(countries, `"`, `""`) + "\"\n"
+	if string(b) != expected {
+		t.Errorf("expected %q to eq %q", string(b), expected)
+	}
+}
+
+// Files processed concurrently write their rows in the order of the files.
+func TestRun_errorsCSVFlagFileWorkers(t *testing.T) {
+	var inputs []string
+	var expected strings.Builder
+	expected.WriteString("id,age,_errors\n")
+	for i := 0; i < 8; i++ {
+		var b strings.Builder
+		b.WriteString("id,age\n")
+		for j := 0; j < 50*(8-i); j++ {
+			fmt.Fprintf(&b, "%d,%d\n", j, j%100)
+		}
+		fmt.Fprintf(&b, "%d,-1\n", i)
+		fmt.Fprintf(&expected, "%d,-1,age: -1 is outside 0:120\n", i)
+		inputs = append(inputs, b.String())
+	}
+	files := writeFiles(t, inputs...)
+	name := filepath.Join(t.TempDir(), "errors.csv")
+
+	for _, args := range [][]string{{"-file-workers", "4"}, {"-lint"}} {
+		cli := &CLI{outStream: new(bytes.Buffer), errStream: new(bytes.Buffer)}
+		cli.Run(append(append([]string{"./csvlint", "-range", "age=0:120", "-errors-csv", name}, args...), files...))
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected.String() {
+			t.Errorf("%v: expected %q to eq %q", args, string(b), expected.String())
+		}
+	}
+}
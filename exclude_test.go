@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_excludeFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		input    string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -exclude ssn", "id,ssn,name\n1,123,a\n2,456,b,extra\n", ExitCodeOK, "id,name\n1,a\n2,b,extra\n", ""},
+		{"./csvlint -quote minimal -exclude name,id", "id,ssn,name\n1,123,a\n", ExitCodeOK, "ssn\n123\n", ""},
+		{"./csvlint -quote minimal -no-header -exclude 2", "1,123,a\n2,456\n", ExitCodeOK, "1,a\n2\n", ""},
+		{"./csvlint -exclude email", "id,ssn\n1,2\n", ExitCodeError, "", "unknown column \"email\"\n"},
+		{"./csvlint -no-header -exclude ssn", "1,2\n", ExitCodeError, "", "invalid -exclude \"ssn\": invalid column \"ssn\": columns must be 1-based positions without a header\n"},
+		{"./csvlint -exclude ssn -select id", "id,ssn\n1,2\n", ExitCodeError, "", "-exclude cannot be combined with -select or -columns-regex\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(test.input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestProjectInPlace(t *testing.T) {
+	tests := []struct {
+		record   []string
+		indices  []int
+		expected []string
+	}{
+		{[]string{"a", "b", "c", "d"}, []int{0, 2, 3}, []string{"a", "c", "d"}},
+		{[]string{"a", "b", "c"}, []int{1, 1, 2}, []string{"b", "b", "c"}},
+		{[]string{"1", "x", "n"}, []int{0, 0, 2}, []string{"1", "1", "n"}},
+		{[]string{"1", "n"}, []int{0, 0, 1}, []string{"1", "1", "n"}},
+		{[]string{"a", "b"}, []int{1, 2, 3}, []string{"b", "", ""}},
+		{[]string{"a", "b", "c"}, []int{2, 0}, []string{"c", "a"}},
+	}
+	for _, test := range tests {
+		record := append([]string(nil), test.record...)
+		if got := projectInPlace(record, test.indices); strings.Join(got, ",") != strings.Join(test.expected, ",") || len(got) != len(test.expected) {
+			t.Errorf("%q %v: expected %q to eq %q", test.record, test.indices, got, test.expected)
+		}
+	}
+}
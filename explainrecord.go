@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// recordDump keeps the record of -explain-record, the one at line, to
+// write it one column per line.
+type recordDump struct {
+	line   int
+	header []string
+	record []string
+	found  bool
+}
+
+// contains tells whether the record just read by reader spans the line.
+func (d *recordDump) contains(reader recordReader, record []string) bool {
+	if len(record) == 0 {
+		return false
+	}
+	first, _ := reader.FieldPos(0)
+	last, _ := reader.FieldPos(len(record) - 1)
+	last += strings.Count(record[len(record)-1], "\n")
+	return first <= d.line && d.line <= last
+}
+
+// passed tells whether the record just read by reader starts after the
+// line, so that no later record can span it.
+func (d *recordDump) passed(reader recordReader) bool {
+	first, _ := reader.FieldPos(0)
+	return first > d.line
+}
+
+func (d *recordDump) add(record []string, isHeader bool) {
+	if isHeader {
+		d.header = append([]string(nil), record...)
+		return
+	}
+	d.record = append([]string(nil), record...)
+	d.found = true
+}
+
+// write prints the record as "column: value" lines, with the names
+// aligned. Without a name, a column is shown by its number, and the lines
+// of a value after its first are indented under it.
+func (d *recordDump) write(w io.Writer) error {
+	if !d.found {
+		return fmt.Errorf("-explain-record: no record at line %d", d.line)
+	}
+	names := make([]string, len(d.record))
+	width := 0
+	for i := range d.record {
+		names[i] = strconv.Itoa(i + 1)
+		if i < len(d.header) {
+			names[i] = d.header[i]
+		}
+		if n := runewidth.StringWidth(names[i]); n > width {
+			width = n
+		}
+	}
+	indent := strings.Repeat(" ", width+2)
+	var b strings.Builder
+	for i, v := range d.record {
+		b.WriteString(runewidth.FillRight(names[i], width))
+		b.WriteString(": ")
+		b.WriteString(strings.Replace(v, "\n", "\n"+indent, -1))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_explainRecordFlag(t *testing.T) {
+	input := "id,name,note\n1,alice,short\n2,bob,\"two\nlines\"\n3,carol,x\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -explain-record 2", "id  : 1\nname: alice\nnote: short\n"},
+		{"./csvlint -explain-record 4", "id  : 2\nname: bob\nnote: two\\nlines\n"},
+		{"./csvlint -explain-record 3 -field-newline keep", "id  : 2\nname: bob\nnote: two\n      lines\n"},
+		{"./csvlint -explain-record 5", "id  : 3\nname: carol\nnote: x\n"},
+		{"./csvlint -explain-record 2 -select note,id", "note: short\nid  : 1\n"},
+		{"./csvlint -explain-record 1 -no-header", "1: id\n2: name\n3: note\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_explainRecordFlagStopsReading(t *testing.T) {
+	// the broken quote after the record is never read
+	input := "id,name\n1,alice\n2,\"bob\n"
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+	if status := cli.Run([]string{"./csvlint", "-explain-record", "2"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if expected := "id  : 1\nname: alice\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_explainRecordFlagMissing(t *testing.T) {
+	for _, args := range []string{"./csvlint -explain-record 1", "./csvlint -explain-record 9"} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("id\n1\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", args, status, ExitCodeError)
+		}
+		if !strings.Contains(errStream.String(), "no record at line") {
+			t.Errorf("%s: expected %q to contain %q", args, errStream.String(), "no record at line")
+		}
+	}
+}
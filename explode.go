@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// explodeJSON reads all of r and returns csv in which the column named by
+// opts.ExplodeJSON, holding a JSON object, is replaced by one column per
+// key, named column.key. The keys are the union over all rows in the order
+// they are first seen, which is why the whole input is held in memory.
+// Rows whose value is missing or not an object get empty values and are
+// reported to diag, as are rows that do not parse, which are left out.
+//
+// Every record starts on the line it started on in r, empty lines, which
+// the csv reader skips, making up for those left out, so that later
+// diagnostics and -line-numbers give the lines of the input.
+func explodeJSON(name string, r io.Reader, diag *diagnostics, opts *Options) (io.Reader, error) {
+	cr := csv.NewReader(r)
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	swapQuote := opts.QuoteChar != 0 && opts.QuoteChar != '"'
+
+	type row struct {
+		record []string
+		values map[string]string
+		line   int
+	}
+	var (
+		rows       []row
+		header     []string
+		headerLine int
+		keys       []string
+		known      = map[string]bool{}
+		col        = -1
+	)
+	if opts.NoHeader {
+		var err error
+		if col, err = columnIndex(opts.ExplodeJSON, nil, true); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if _, ok := err.(*csv.ParseError); ok {
+			diag.reportError(name, "parse", err)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		start, _ := cr.FieldPos(0)
+		if col < 0 {
+			if col, err = columnIndex(opts.ExplodeJSON, headerIndex(record), false); err != nil {
+				return nil, err
+			}
+			header, headerLine = record, start
+			continue
+		}
+
+		line := start
+		v := ""
+		if col < len(record) {
+			v = record[col]
+			line, _ = cr.FieldPos(col)
+		}
+		if swapQuote {
+			v = swapRunes(v, opts.QuoteChar, '"')
+		}
+		objKeys, values, err := parseObject(v)
+		if err != nil {
+			diag.report(Diagnostic{File: name, Line: line, Column: col + 1, Rule: "json", Message: fmt.Sprintf("%s: %s", opts.ExplodeJSON, err)})
+		}
+		for _, k := range objKeys {
+			if !known[k] {
+				known[k] = true
+				keys = append(keys, k)
+			}
+		}
+		rows = append(rows, row{record, values, start})
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if cr.Comma != 0 {
+		w.Comma = cr.Comma
+	}
+	// next is the line the next record written starts on
+	next := 1
+	put := func(record []string, line int) error {
+		for ; next < line; next++ {
+			b.WriteByte('\n')
+		}
+		start := b.Len()
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		w.Flush()
+		next += bytes.Count(b.Bytes()[start:], []byte("\n"))
+		return w.Error()
+	}
+	expand := func(record []string, values []string) []string {
+		for len(record) <= col {
+			record = append(record, "")
+		}
+		out := append([]string(nil), record[:col]...)
+		out = append(out, values...)
+		return append(out, record[col+1:]...)
+	}
+	if header != nil {
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = opts.ExplodeJSON + "." + k
+		}
+		if err := put(expand(header, names), headerLine); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range rows {
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = row.values[k]
+			if swapQuote {
+				values[i] = swapRunes(values[i], opts.QuoteChar, '"')
+			}
+		}
+		if err := put(expand(row.record, values), row.line); err != nil {
+			return nil, err
+		}
+	}
+	return &b, nil
+}
+
+// parseObject parses a JSON object, returning its keys in order and its
+// values as text: strings as they are, null as empty and anything else
+// as compact JSON.
+func parseObject(s string) ([]string, map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil, fmt.Errorf("missing JSON object")
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("not a JSON object")
+	}
+	var keys []string
+	values := map[string]string{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %s", err)
+		}
+		key := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %s", err)
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = jsonText(raw)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("invalid JSON: trailing data")
+	}
+	return keys, values, nil
+}
+
+func jsonText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return string(raw)
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_explodeJSONFlag(t *testing.T) {
+	input := "id,attrs,note\n" +
+		"1,\"{\"\"color\"\": \"\"red\"\", \"\"size\"\": 3}\",a\n" +
+		"2,\"{\"\"size\"\": null, \"\"tags\"\": [\"\"x\"\", \"\"y\"\"], \"\"shape\"\": \"\"round\"\"}\",b\n" +
+		"3,not json,c\n" +
+		"4,,d\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	args := strings.Split("./csvlint -quote minimal -explode-json attrs", " ")
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "id,attrs.color,attrs.size,attrs.tags,attrs.shape,note\n" +
+		"1,red,3,,,a\n" +
+		"2,,,\"[\"\"x\"\",\"\"y\"\"]\",round,b\n" +
+		"3,,,,,c\n" +
+		"4,,,,,d\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+
+	expected = "line 4 column 2: attrs: not a JSON object\nline 5 column 2: attrs: missing JSON object\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+// The diagnostics of later checks give the lines of the input, with empty
+// lines and fields over several lines before the row.
+func TestRun_explodeJSONFlagLines(t *testing.T) {
+	input := "\nid,attrs,note\n1,\"{\"\"a\"\": 1}\",\"two\nlines\"\n\n2,x,c\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	cli.Run(strings.Split("./csvlint -quote minimal -explode-json attrs -range id=0:1", " "))
+
+	expected := "line 6 column 2: attrs: not a JSON object\nline 6 column 1: id: 2 is outside 0:1\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+	expected = "id,attrs.a,note\n1,1,two\\nlines\n2,,c\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// fileResult is the buffered output of one input file.
+type fileResult struct {
+	out, errs bytes.Buffer
+	diag      *diagnostics
+	// errorRows are the buffered -errors-csv rows of the file, and
+	// quarantine its -quarantine records
+	errorRows  *errorRows
+	quarantine *quarantine
+	records    int
+	err        error
+}
+
+// transformFile runs transform over the named file, or over the entry of
+// it that opts.ZipEntry names when it is a zip archive.
+func transformFile(name string, w io.Writer, diag *diagnostics, opts *Options) (int, error) {
+	var (
+		fp  io.ReadCloser
+		err error
+	)
+	if isZip(name) {
+		fp, err = openZipEntry(name, opts.ZipEntry)
+	} else {
+		fp, err = os.Open(name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer fp.Close()
+
+	return transform(name, fp, w, diag, opts)
+}
+
+// inputSize returns the size in bytes of the input files, or -1 for stdin
+// or when a size is not known, as for a pipe.
+func inputSize(files []string) int64 {
+	if len(files) == 0 {
+		return -1
+	}
+	var total int64
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		total += info.Size()
+	}
+	return total
+}
+
+// inLineOrder runs fn with a collector that keeps the diagnostics of one
+// input, and then writes them to diag in line order, as those of the files
+// transformed concurrently are.
+func inLineOrder(diag *diagnostics, fn func(diag *diagnostics) (int, error)) (int, error) {
+	var errs bytes.Buffer
+	c := diag.child(&errs)
+	records, err := fn(c)
+	errs.WriteTo(diag.w)
+	c.sortByLine()
+	diag.merge(c)
+	return records, err
+}
+
+// transformFiles transforms every file with at most workers of them in
+// flight, and writes their output and diagnostics in the given order. Only
+// the header of the first file is kept. With one worker, the diagnostics of
+// each file are written as they are found, unless inOrder is set. It
+// returns the number of records written.
+func transformFiles(files []string, w io.Writer, diag *diagnostics, opts *Options, workers int, inOrder bool) (int, error) {
+	rest := *opts
+	rest.SkipHeader = true
+	optsFor := func(i int) *Options {
+		if i == 0 {
+			return opts
+		}
+		return &rest
+	}
+
+	records := 0
+	if workers == 1 {
+		failed := 0
+		for i, name := range files {
+			transform := func(diag *diagnostics) (int, error) {
+				return transformFile(name, w, diag, optsFor(i))
+			}
+			var (
+				n   int
+				err error
+			)
+			if inOrder {
+				n, err = inLineOrder(diag, transform)
+			} else {
+				n, err = transform(diag)
+			}
+			records += n
+			if err != nil {
+				if !reported(err) {
+					diag.reportError(name, "file", err)
+				}
+				failed++
+			}
+		}
+		return records, failedFiles(failed)
+	}
+
+	// sem bounds both the running workers and the results waiting to be
+	// written, so memory stays proportional to workers rather than files.
+	sem := make(chan struct{}, workers)
+	results := make([]chan *fileResult, len(files))
+	for i := range results {
+		results[i] = make(chan *fileResult, 1)
+	}
+
+	go func() {
+		for i, name := range files {
+			sem <- struct{}{}
+			go func(i int, name string) {
+				res := new(fileResult)
+				res.diag = diag.child(&res.errs)
+				o := optsFor(i)
+				if o.errorRows != nil || o.quarantine != nil {
+					buffered := *o
+					if o.errorRows != nil {
+						buffered.errorRows = o.errorRows.buffered()
+						res.errorRows = buffered.errorRows
+					}
+					if o.quarantine != nil {
+						buffered.quarantine = o.quarantine.buffered()
+						res.quarantine = buffered.quarantine
+					}
+					o = &buffered
+				}
+				res.records, res.err = transformFile(name, &res.out, res.diag, o)
+				results[i] <- res
+			}(i, name)
+		}
+	}()
+
+	failed := 0
+	var werr error
+	for i := range files {
+		res := <-results[i]
+		if werr == nil {
+			if _, err := res.out.WriteTo(w); err != nil {
+				werr = err
+			}
+			records += res.records
+		}
+		if res.errorRows != nil && werr == nil {
+			werr = opts.errorRows.merge(res.errorRows)
+		}
+		if res.quarantine != nil && werr == nil {
+			werr = opts.quarantine.merge(res.quarantine)
+		}
+		res.errs.WriteTo(diag.w)
+		res.diag.sortByLine()
+		diag.merge(res.diag)
+		if res.err != nil {
+			if !reported(res.err) {
+				diag.reportError(files[i], "file", res.err)
+			}
+			failed++
+		}
+		<-sem
+	}
+	if werr != nil {
+		return records, werr
+	}
+	return records, failedFiles(failed)
+}
+
+// errFilesFailed is returned when some files could not be processed. The
+// reason for each of them has already been reported as a diagnostic.
+var errFilesFailed = errors.New("some files could not be processed")
+
+func failedFiles(failed int) error {
+	if failed == 0 {
+		return nil
+	}
+	return errFilesFailed
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t testing.TB, contents ...string) []string {
+	dir := t.TempDir()
+	var names []string
+	for i, c := range contents {
+		name := filepath.Join(dir, fmt.Sprintf("%03d.csv", i))
+		if err := os.WriteFile(name, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestRun_fileWorkersFlag(t *testing.T) {
+	files := writeFiles(t, "h1,h2\na,b\n", "h1,h2\nc,d\n")
+	missing := filepath.Join(filepath.Dir(files[0]), "missing.csv")
+	files = []string{files[0], missing, files[1]}
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := append([]string{"./csvlint", "-file-workers", "2"}, files...)
+
+	status := cli.Run(args)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+
+	expected := "\"h1\",\"h2\"\n\"a\",\"b\"\n\"c\",\"d\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+
+	if !strings.HasPrefix(errStream.String(), missing+": open: ") {
+		t.Errorf("expected %q to be attributed to %q", errStream.String(), missing)
+	}
+}
+
+func TestRun_fileWorkersFlag_skipHeader(t *testing.T) {
+	files := writeFiles(t, "h\na\n", "h\nb\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+	args := append([]string{"./csvlint", "-skip-header", "-file-workers", "4"}, files...)
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "\"a\"\n\"b\"\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func BenchmarkRun_fileWorkers(b *testing.B) {
+	var contents []string
+	for i := 0; i < 200; i++ {
+		contents = append(contents, strings.Repeat("a b,\"c\nd\",e\n", 50))
+	}
+	files := writeFiles(b, contents...)
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			args := append([]string{"./csvlint", "-file-workers", fmt.Sprint(workers)}, files...)
+			for i := 0; i < b.N; i++ {
+				cli := &CLI{outStream: new(bytes.Buffer), errStream: new(bytes.Buffer)}
+				cli.Run(args)
+			}
+		})
+	}
+}
+
+func TestRun_lintFlag(t *testing.T) {
+	files := writeFiles(t,
+		"id,age\n1,200\n2,\" \"\n",
+		"id,age\n1,30\n",
+		"id,age\n1,x\r\n2,-1\n",
+	)
+	expected := files[0] + ": line 2 column 2: age: 200 is outside 0:120\n" +
+		files[0] + ": line 3 column 2: whitespace-only field\n" +
+		files[0] + ": line 3 column 2: age: \"\" is not a number\n" +
+		files[2] + ": line 2 column 2: age: \"x\" is not a number\n" +
+		files[2] + ": line 2: line ends with CRLF, most lines end with LF\n" +
+		files[2] + ": line 3 column 2: age: -1 is outside 0:120\n"
+	// one worker reports the diagnostics in line order too
+	for _, workers := range []string{"3", "1"} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+		args := append(strings.Split("./csvlint -lint -file-workers "+workers+" -check-whitespace-only -check-line-endings -range age=0:120", " "), files...)
+
+		status := cli.Run(args)
+		if status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", workers, status, ExitCodeError)
+		}
+		if outStream.Len() != 0 {
+			t.Errorf("%s: expected no output, got %q", workers, outStream.String())
+		}
+		if !strings.HasPrefix(errStream.String(), expected) {
+			t.Errorf("%s: expected %q to start with %q", workers, errStream.String(), expected)
+		}
+	}
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader("id,age\n1,x\r\n2,-1\n"), outStream: outStream, errStream: errStream}
+	cli.Run(strings.Split("./csvlint -lint -check-line-endings -range age=0:120", " "))
+	expected = "line 2 column 2: age: \"x\" is not a number\n" +
+		"line 2: line ends with CRLF, most lines end with LF\n" +
+		"line 3 column 2: age: -1 is outside 0:120\n"
+	if !strings.HasPrefix(errStream.String(), expected) {
+		t.Errorf("expected %q to start with %q", errStream.String(), expected)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// flattenReader is -flatten-multiline: a best-effort repair of records
+// broken over several lines by a newline that was not quoted. A line with
+// fewer than fields fields is joined with the following lines, a space
+// taking the place of each line break, as long as the joined record does
+// not get more fields than that. Every join is reported.
+//
+// Each line break taken out is written back as an empty line after the
+// record, which the csv reader skips, so that the lines of later
+// diagnostics still match the input. At most maxFlattenLines lines are
+// joined into a record, or read ahead for a quoted field, so that a stray
+// quote does not hold the rest of the input in memory.
+type flattenReader struct {
+	r      *bufio.Reader
+	comma  rune
+	fields int
+	name   string
+	diag   *diagnostics
+
+	line int
+	out  []byte
+	// ahead is a logical line read but not joined.
+	ahead *flattenLine
+	// open tells that the last physical line read ended inside quotes.
+	open bool
+	err  error
+}
+
+// maxFlattenLines is the most physical lines of a record -flatten-multiline
+// joins or reads ahead.
+const maxFlattenLines = 100
+
+// flattenLine is a logical line: the physical lines of a record, with
+// those of a quoted field that spans them.
+type flattenLine struct {
+	b      []byte
+	lines  int
+	fields int
+	// partial tells that the line starts or ends inside a quoted field
+	// cut at maxFlattenLines, so that its fields are not known.
+	partial bool
+}
+
+func newFlattenReader(r io.Reader, comma rune, fields int, name string, diag *diagnostics) *flattenReader {
+	if comma == 0 {
+		comma = ','
+	}
+	return &flattenReader{r: bufio.NewReader(r), comma: comma, fields: fields, name: name, diag: diag}
+}
+
+func (f *flattenReader) Read(p []byte) (int, error) {
+	for len(f.out) == 0 {
+		if f.err != nil && f.ahead == nil {
+			return 0, f.err
+		}
+		f.fill()
+	}
+	n := copy(p, f.out)
+	f.out = f.out[n:]
+	return n, nil
+}
+
+// fill puts the next record, joined if needed, into out.
+func (f *flattenReader) fill() {
+	cur := f.next()
+	if cur == nil {
+		return
+	}
+	first := f.line + 1
+	f.line += cur.lines
+	n, joins := cur.fields, 0
+	for n < f.fields && !cur.partial {
+		next := f.next()
+		if next == nil {
+			break
+		}
+		if next.partial || len(trimEOL(next.b)) == 0 || n+next.fields-1 > f.fields || f.line-first+1+next.lines > maxFlattenLines {
+			f.ahead = next
+			break
+		}
+		cur.b = append(append(trimEOL(cur.b), ' '), next.b...)
+		n += next.fields - 1
+		f.line += next.lines
+		joins++
+	}
+	if joins > 0 {
+		f.diag.report(Diagnostic{File: f.name, Line: first, Rule: "flatten", Message: fmt.Sprintf("joined lines %d to %d into a record of %d fields", first, f.line, n)})
+		if !bytes.HasSuffix(cur.b, []byte("\n")) {
+			cur.b = append(cur.b, '\n')
+		}
+		cur.b = append(cur.b, bytes.Repeat([]byte("\n"), joins)...)
+	}
+	f.out = cur.b
+}
+
+// next returns the next logical line, or nil at the end. The fields and
+// quotes are counted as each physical line is read, so that a long quoted
+// field is scanned once.
+func (f *flattenReader) next() *flattenLine {
+	if f.ahead != nil {
+		line := f.ahead
+		f.ahead = nil
+		return line
+	}
+	line := &flattenLine{fields: 1, partial: f.open}
+	for {
+		b, err := f.r.ReadBytes('\n')
+		line.b = append(line.b, b...)
+		if len(b) > 0 {
+			line.lines++
+		}
+		f.open = f.scan(b, &line.fields, f.open)
+		if err != nil {
+			f.err = err
+			break
+		}
+		if !f.open {
+			break
+		}
+		if line.lines == maxFlattenLines {
+			line.partial = true
+			break
+		}
+	}
+	if len(line.b) == 0 {
+		return nil
+	}
+	return line
+}
+
+// scan adds the fields of a physical line to fields, starting inside quotes
+// if quoted, and tells whether it ends inside quotes.
+func (f *flattenReader) scan(b []byte, fields *int, quoted bool) bool {
+	for _, c := range string(trimEOL(b)) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == f.comma && !quoted:
+			*fields++
+		}
+	}
+	return quoted
+}
+
+func trimEOL(line []byte) []byte {
+	return bytes.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_flattenMultilineFlag(t *testing.T) {
+	input := "id,note,city\n1,first\nhalf,Paris\n2,\"quoted\nnote\",Rome\n3,a\nb\nc,Oslo\n4,short\n5,x,Lima\n6,too\nmany,a,b\n"
+	tests := []struct {
+		args     string
+		expected string
+		errors   string
+	}{
+		{
+			"./csvlint -quote minimal -field-newline keep -flatten-multiline 3",
+			"id,note,city\n1,first half,Paris\n2,\"quoted\nnote\",Rome\n3,a b c,Oslo\n4,short\n5,x,Lima\n6,too\nmany,a,b\n",
+			"line 2: joined lines 2 to 3 into a record of 3 fields\n" +
+				"line 6: joined lines 6 to 8 into a record of 3 fields\n",
+		},
+		{
+			"./csvlint -quote minimal -flatten-multiline 3 -abort-on-field-count-change",
+			"id,note,city\n1,first half,Paris\n2,quoted\\nnote,Rome\n3,a b c,Oslo\n",
+			"line 2: joined lines 2 to 3 into a record of 3 fields\n" +
+				"line 6: joined lines 6 to 8 into a record of 3 fields\n" +
+				"line 9: record has 2 fields instead of 3\n",
+		},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		cli.Run(strings.Split(tt.args, " "))
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
+
+// A run of short lines, or a stray quote, is taken in at most
+// maxFlattenLines lines at a time.
+func TestRun_flattenMultilineFlagLimit(t *testing.T) {
+	lines := strings.Repeat("x\n", 150)
+	tests := []struct {
+		input    string
+		expected string
+		errors   string
+	}{
+		{"a,b\n" + lines, "a,b\nx" + strings.Repeat(" x", 99) + "\nx" + strings.Repeat(" x", 49) + "\n", "line 2: joined lines 2 to 101 into a record of 1 fields\nline 102: joined lines 102 to 151 into a record of 1 fields\n"},
+		{"a,b\n\"x\n" + lines + "y\",z\n", "a,b\n\"x\n" + lines + "y\",z\n", ""},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(tt.input), outStream: outStream, errStream: errStream}
+
+		cli.Run(strings.Split("./csvlint -quote minimal -field-newline keep -flatten-multiline 2", " "))
+		if errStream.String() != tt.errors {
+			t.Errorf("expected %q to eq %q", errStream.String(), tt.errors)
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("expected %q to eq %q", outStream.String(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// emailPattern matches the addresses people mean by an email address: a
+// local part of the characters RFC 5322 allows unquoted, and a domain of
+// at least two labels. Quoted local parts and IP literals are rejected.
+var emailPattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+(\\.[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+)*" +
+	"@[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)+$")
+
+func validEmail(v string) bool {
+	return len(v) <= 254 && emailPattern.MatchString(v)
+}
+
+// validURL accepts absolute URLs with a host, such as https://example.com/a.
+func validURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.Scheme != "" && u.Host != "" && !strings.ContainsAny(v, " \t")
+}
+
+// formatCheck reports the values of the -validate-email, -validate-url or
+// -check-numeric columns that are not well formed.
+type formatCheck struct {
+	flag    string
+	rule    string
+	what    string
+	valid   func(string) bool
+	columns []string
+}
+
+func newEmailCheck(columns []string) *formatCheck {
+	return &formatCheck{flag: "-validate-email", rule: "email", what: "an email address", valid: validEmail, columns: columns}
+}
+
+func newURLCheck(columns []string) *formatCheck {
+	return &formatCheck{flag: "-validate-url", rule: "url", what: "a URL", valid: validURL, columns: columns}
+}
+
+// bind resolves the columns in header.
+func (c *formatCheck) bind(header []string, noHeader bool) ([]int, error) {
+	return columnIndices(c.flag, c.columns, header, noHeader)
+}
+
+// check reports the fields of record that are not well formed. indices
+// are the bound columns. Empty fields are not checked.
+func (c *formatCheck) check(name string, record []string, reader recordReader, diag *diagnostics, indices []int) {
+	for i, n := range indices {
+		if n >= len(record) {
+			continue
+		}
+		v := strings.TrimSpace(record[n])
+		if v == "" || c.valid(v) {
+			continue
+		}
+		line, _ := reader.FieldPos(n)
+		diag.report(Diagnostic{File: name, Line: line, Column: n + 1, Rule: c.rule, Message: fmt.Sprintf("%s: %q is not %s", c.columns[i], v, c.what)})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidEmail(t *testing.T) {
+	valid := []string{"ada@example.com", "a.b+tag@mail.example.co.uk", "o'brien@example.org", "x_y@a-b.io"}
+	wrong := []string{"ada", "ada@example", "@example.com", "ada@.com", "a..b@example.com", ".ada@example.com", "ada@exa_mple.com", "ada@-example.com", "a b@example.com"}
+	for _, v := range valid {
+		if !validEmail(v) {
+			t.Errorf("expected %q to be an email address", v)
+		}
+	}
+	for _, v := range wrong {
+		if validEmail(v) {
+			t.Errorf("expected %q not to be an email address", v)
+		}
+	}
+}
+
+func TestValidURL(t *testing.T) {
+	valid := []string{"https://example.com", "http://example.com:8080/a?b=c#d", "ftp://user@host/file"}
+	wrong := []string{"example.com", "/a/b", "https://", "mailto:ada@example.com", "http://exa mple.com", "://example.com"}
+	for _, v := range valid {
+		if !validURL(v) {
+			t.Errorf("expected %q to be a URL", v)
+		}
+	}
+	for _, v := range wrong {
+		if validURL(v) {
+			t.Errorf("expected %q not to be a URL", v)
+		}
+	}
+}
+
+func TestRun_validateFlags(t *testing.T) {
+	input := "id,email,site\n1,ada@example.com,https://example.com\n2,bob@,example.com\n3,,\n"
+	tests := []struct {
+		args   string
+		status int
+		errors string
+	}{
+		{"./csvlint -validate-email email -validate-url site", ExitCodeOK,
+			"line 3 column 2: email: \"bob@\" is not an email address\n" +
+				"line 3 column 3: site: \"example.com\" is not a URL\n"},
+		{"./csvlint -validate-url site -strict", ExitCodeError,
+			"line 3 column 3: site: \"example.com\" is not a URL\n"},
+		{"./csvlint -no-header -skip-header -validate-email 2", ExitCodeOK,
+			"line 1 column 2: 2: \"email\" is not an email address\n" +
+				"line 3 column 2: 2: \"bob@\" is not an email address\n"},
+		{"./csvlint -validate-email mail", ExitCodeError, "-validate-email: unknown column \"mail\"\n"},
+	}
+	for _, test := range tests {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: new(bytes.Buffer), errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// rowHash returns the first 16 hex digits of a SHA-256 over the fields of
+// record at indices, or over all fields when indices is nil. Every field is
+// length prefixed so that moving text between fields changes the hash.
+func rowHash(record []string, indices []int) string {
+	h := sha256.New()
+	var n [binary.MaxVarintLen64]byte
+	field := func(v string) {
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(v)))])
+		h.Write([]byte(v))
+	}
+	if indices == nil {
+		for _, v := range record {
+			field(v)
+		}
+	} else {
+		for _, i := range indices {
+			v := ""
+			if i < len(record) {
+				v = record[i]
+			}
+			field(v)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// resolveHashCols maps the -hash-cols columns to their index in header.
+func resolveHashCols(cols []string, header []string, noHeader bool) ([]int, error) {
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	return columnIndices("", cols, header, noHeader)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Header cases of -header-case.
+const (
+	HeaderLower = "lower"
+	HeaderUpper = "upper"
+	HeaderSnake = "snake"
+	HeaderCamel = "camel"
+)
+
+// headerWords splits a column name into words at every run of characters
+// other than letters and digits, and where the case changes: "FirstName"
+// and "first-name" are both "first" and "name", and "userID2Name" is
+// "user", "ID2" and "Name".
+func headerWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words, word = append(words, string(word)), nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			// an upper case letter starts a word after a lower case one,
+			// or ends an acronym when a lower case letter follows it
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) && nextLower || unicode.IsUpper(prev) && nextLower {
+				words, word = append(words, string(word)), nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// headerCase returns name in the case.
+func headerCase(name, mode string) string {
+	switch mode {
+	case HeaderLower:
+		return strings.ToLower(name)
+	case HeaderUpper:
+		return strings.ToUpper(name)
+	case HeaderSnake:
+		return strings.ToLower(strings.Join(headerWords(name), "_"))
+	}
+	words := headerWords(name)
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		words[i] = w
+	}
+	return strings.Join(words, "")
+}
+
+// changeHeaderCase returns the header with every name in the case. Two
+// different names that end up the same are an error, as the columns could
+// no longer be told apart.
+func changeHeaderCase(header []string, mode string) ([]string, error) {
+	out := make([]string, len(header))
+	from := make(map[string]string, len(header))
+	for i, name := range header {
+		out[i] = headerCase(name, mode)
+		if prev, ok := from[out[i]]; ok && prev != name {
+			return nil, fmt.Errorf("-header-case %s turns both %q and %q into %q", mode, prev, name, out[i])
+		}
+		from[out[i]] = name
+	}
+	return out, nil
+}
+
+// renameRegex returns the header with the -rename-regex substitutions
+// applied to every name. Like with changeHeaderCase, two names ending up
+// the same are an error.
+func renameRegex(header []string, renames []regexReplace) ([]string, error) {
+	out := make([]string, len(header))
+	from := make(map[string]string, len(header))
+	for i, name := range header {
+		out[i] = replaceRegex(name, renames)
+		if prev, ok := from[out[i]]; ok && prev != name {
+			return nil, fmt.Errorf("-rename-regex turns both %q and %q into %q", prev, name, out[i])
+		}
+		from[out[i]] = name
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHeaderCase(t *testing.T) {
+	tests := []struct {
+		name                       string
+		lower, upper, snake, camel string
+	}{
+		{"First Name", "first name", "FIRST NAME", "first_name", "firstName"},
+		{"first_name", "first_name", "FIRST_NAME", "first_name", "firstName"},
+		{"FirstName", "firstname", "FIRSTNAME", "first_name", "firstName"},
+		{"zip-code", "zip-code", "ZIP-CODE", "zip_code", "zipCode"},
+		{"HTTPServer", "httpserver", "HTTPSERVER", "http_server", "httpServer"},
+		{"userID2Name", "userid2name", "USERID2NAME", "user_id2_name", "userId2Name"},
+		{" Ünit  Price ", " ünit  price ", " ÜNIT  PRICE ", "ünit_price", "ünitPrice"},
+	}
+	for _, test := range tests {
+		for mode, expected := range map[string]string{HeaderLower: test.lower, HeaderUpper: test.upper, HeaderSnake: test.snake, HeaderCamel: test.camel} {
+			if got := headerCase(test.name, mode); got != expected {
+				t.Errorf("%s %q: expected %q to eq %q", mode, test.name, got, expected)
+			}
+		}
+	}
+}
+
+func TestRun_headerCaseFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		input    string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -header-case snake", "First Name,ZipCode\nAda Lovelace,AB-1\n", ExitCodeOK, "first_name,zip_code\nAda Lovelace,AB-1\n", ""},
+		{"./csvlint -quote minimal -header-case camel -select ZipCode:Postal_Code", "First Name,ZipCode\nAda,AB-1\n", ExitCodeOK, "postalCode\nAB-1\n", ""},
+		{"./csvlint -quote minimal -header-case upper -no-header -select 1:id", "1\n", ExitCodeOK, "ID\n1\n", ""},
+		{"./csvlint -header-case snake", "First Name,first_name\n1,2\n", ExitCodeError, "", "-header-case snake turns both \"First Name\" and \"first_name\" into \"first_name\"\n"},
+		{"./csvlint -header-case title", "a\n", ExitCodeError, "", "invalid -header-case \"title\": must be lower, upper, snake or camel\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(test.input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_renameRegexFlag(t *testing.T) {
+	input := "col_id,col_first name,total\n1,Ada,3\n"
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{[]string{"-rename-regex", "^col_="}, ExitCodeOK, "id,first name,total\n1,Ada,3\n", ""},
+		{[]string{"-rename-regex", "^col_=", "-rename-regex", " =_"}, ExitCodeOK, "id,first_name,total\n1,Ada,3\n", ""},
+		{[]string{"-rename-regex", "^col_(.*)=${1}_x", "-header-case", "upper"}, ExitCodeOK, "ID_X,FIRST NAME_X,TOTAL\n1,Ada,3\n", ""},
+		{[]string{"-rename-regex", "^col_=", "-select", "col_id:col_x,total", "-sort", "x"}, ExitCodeOK, "x,total\n1,3\n", ""},
+		{[]string{"-rename-regex", "^.*_.*=dup"}, ExitCodeError, "", "-rename-regex turns both \"col_id\" and \"col_first name\" into \"dup\"\n"},
+		{[]string{"-rename-regex", "(="}, ExitCodeError, "", "invalid -rename-regex \"(=\": error parsing regexp: missing closing ): `(`\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		args := append([]string{"./csvlint", "-quote", "minimal"}, test.args...)
+		status := cli.Run(args)
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// fieldHistogram counts the data rows of an input by their number of
+// fields, for -field-histogram.
+type fieldHistogram map[int]int
+
+// report adds a summary counter for every field count, the most common
+// first, so "rows with 5 fields: 9980" leads and the odd rows follow.
+func (h fieldHistogram) report(diag *diagnostics) {
+	counts := make([]int, 0, len(h))
+	for n := range h {
+		counts = append(counts, n)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if h[counts[i]] != h[counts[j]] {
+			return h[counts[i]] > h[counts[j]]
+		}
+		return counts[i] < counts[j]
+	})
+	for _, n := range counts {
+		noun := "fields"
+		if n == 1 {
+			noun = "field"
+		}
+		diag.count(fmt.Sprintf("rows with %d %s", n, noun), h[n])
+	}
+}
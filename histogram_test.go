@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_fieldHistogramFlag(t *testing.T) {
+	input := "a,b,c\n1,2,3\n4,5\n6,7,8\n9\n10,11\n12,13,14\n"
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"./csvlint", "-field-histogram"},
+			"rows with 3 fields: 3\nrows with 2 fields: 2\nrows with 1 field: 1\n",
+		},
+		{
+			[]string{"./csvlint", "-field-histogram", "-no-header"},
+			"rows with 3 fields: 4\nrows with 2 fields: 2\nrows with 1 field: 1\n",
+		},
+		{
+			[]string{"./csvlint", "-field-histogram", "-report", "json"},
+			"{\n  \"diagnostics\": [],\n  \"summary\": {\n    \"rows with 1 field\": 1,\n    \"rows with 2 fields\": 2,\n    \"rows with 3 fields\": 3\n  }\n}\n",
+		},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(test.args); status != ExitCodeOK {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, ExitCodeOK)
+		}
+		if errStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.expected)
+		}
+	}
+}
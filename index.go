@@ -0,0 +1,62 @@
+package main
+
+import "strconv"
+
+// defaultIndexColumn is the name of the -add-index column when none is
+// given.
+const defaultIndexColumn = "_row"
+
+// indexValue is the name of the -add-index column. It can be given
+// without a value, as -add-index, for the default.
+type indexValue string
+
+func (v *indexValue) String() string {
+	return string(*v)
+}
+
+func (v *indexValue) Set(s string) error {
+	if s == "true" {
+		s = defaultIndexColumn
+	}
+	*v = indexValue(s)
+	return nil
+}
+
+func (v *indexValue) IsBoolFlag() bool { return true }
+
+// rowIndexer numbers the data rows for -add-index, by their position in
+// the input when original is set, or among the rows written. One indexer
+// serves all the inputs, so that the positions run on from one file to the
+// next.
+type rowIndexer struct {
+	column   string
+	original bool
+	written  int
+	// offset is the number of data rows of the inputs before this one.
+	offset int
+}
+
+// prepend returns record after the index column, whose value is its name
+// in the header. n is the position of the row in the input.
+func (x *rowIndexer) prepend(record []string, isHeader bool, n int) []string {
+	v := x.column
+	if !isHeader {
+		if x.original {
+			n += x.offset
+		} else {
+			x.written++
+			n = x.written
+		}
+		v = strconv.Itoa(n)
+	}
+	return append([]string{v}, record...)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
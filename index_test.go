@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_addIndexFlag(t *testing.T) {
+	input := "id,name\n7,a\n8,b\n9,c\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -add-index", ExitCodeOK, "_row,id,name\n1,7,a\n2,8,b\n3,9,c\n", ""},
+		{"./csvlint -quote minimal -add-index=n -select name", ExitCodeOK, "n,name\n1,a\n2,b\n3,c\n", ""},
+		{"./csvlint -quote minimal -add-index -rows 2-3", ExitCodeOK, "_row,id,name\n1,8,b\n2,9,c\n", ""},
+		{"./csvlint -quote minimal -add-index -index-original -rows 2-3", ExitCodeOK, "_row,id,name\n2,8,b\n3,9,c\n", ""},
+		{"./csvlint -quote minimal -add-index -skip-header", ExitCodeOK, "1,7,a\n2,8,b\n3,9,c\n", ""},
+		{"./csvlint -quote minimal -add-index -index-original -no-header -rows 2", ExitCodeOK, "2,7,a\n", ""},
+		{"./csvlint -quote minimal -add-index=id", ExitCodeError, "", "-add-index: the header already has a column \"id\"\n"},
+		{"./csvlint -add-index=", ExitCodeError, "", "-add-index needs a column name, and -index-original needs -add-index\n"},
+		{"./csvlint -index-original", ExitCodeError, "", "-add-index needs a column name, and -index-original needs -add-index\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_addIndexSample(t *testing.T) {
+	input := "id\n1\n2\n3\n4\n5\n6\n"
+	for _, original := range []bool{false, true} {
+		args := []string{"./csvlint", "-quote", "minimal", "-add-index", "-sample", "3", "-seed", "1"}
+		if original {
+			args = append(args, "-index-original")
+		}
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+		if status := cli.Run(args); status != ExitCodeOK {
+			t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream)
+		}
+		lines := strings.Split(strings.TrimSuffix(outStream.String(), "\n"), "\n")
+		if len(lines) != 4 || lines[0] != "_row,id" {
+			t.Fatalf("unexpected output %q", outStream.String())
+		}
+		for i, line := range lines[1:] {
+			fields := strings.Split(line, ",")
+			expected := fields[1]
+			if !original {
+				expected = string(rune('1' + i))
+			}
+			if fields[0] != expected {
+				t.Errorf("%v: expected %q to eq %q", original, fields[0], expected)
+			}
+		}
+	}
+}
+
+func TestRun_addIndexFiles(t *testing.T) {
+	files := writeFiles(t, "id\n7\n8\n", "id\n9\n10\n")
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{[]string{"-quote", "minimal", "-add-index", files[0], files[1]}, ExitCodeOK, "_row,id\n1,7\n2,8\n3,9\n4,10\n", ""},
+		{[]string{"-quote", "minimal", "-add-index", "-index-original", "-rows", "2", files[0], files[1]}, ExitCodeOK, "_row,id\n2,8\n4,10\n", ""},
+		{[]string{"-add-index", "-file-workers", "2", files[0], files[1]}, ExitCodeError, "", "-add-index numbers the rows across the inputs in order and cannot be combined with -file-workers\n"},
+		{[]string{"-lint", "-add-index", "-file-workers", "2", files[0], files[1]}, ExitCodeError, "", "-add-index numbers the rows across the inputs in order and cannot be combined with -file-workers\n"},
+		// -lint reads the files one after the other for -add-index
+		{[]string{"-lint", "-add-index", files[0], files[1]}, ExitCodeOK, "", ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+
+		status := cli.Run(append([]string{"./csvlint"}, test.args...))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// inPlaceValue is the -in-place flag: set alone, or set to the suffix of a
+// backup of the original, as in -i=.bak or, as sed takes it, -i.bak.
+type inPlaceValue struct {
+	enabled bool
+	suffix  string
+}
+
+func (v *inPlaceValue) String() string {
+	return v.suffix
+}
+
+func (v *inPlaceValue) Set(s string) error {
+	v.enabled = s != "false"
+	v.suffix = ""
+	if s != "true" && s != "false" {
+		v.suffix = s
+	}
+	return nil
+}
+
+func (v *inPlaceValue) IsBoolFlag() bool { return true }
+
+// inPlaceSuffixArgs returns args with the flags that are -i followed by a
+// suffix, such as -i.bak, written as -i=.bak for the flag set. The suffix
+// must not start with a letter or a digit, so that a misspelled flag such
+// as -inplace is still refused.
+func inPlaceSuffixArgs(flags *flag.FlagSet, args []string) []string {
+	var out []string
+	scanFlags(flags, args, func(i int) {
+		name := strings.TrimPrefix(args[i][1:], "-")
+		if len(name) < 2 || name[0] != 'i' || isASCIILetter(name[1]) || name[1] >= '0' && name[1] <= '9' || name[1] == '=' || name[1] == '-' || flags.Lookup(name) != nil {
+			return
+		}
+		if out == nil {
+			out = append([]string(nil), args...)
+		}
+		out[i] = "-i=" + name[1:]
+	})
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// createInPlaceTemp creates the file the output for name is written to,
+// next to it so that it can be renamed over it, with the same permissions.
+func createInPlaceTemp(name string) (string, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	fp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+	if err := fp.Chmod(info.Mode().Perm()); err != nil {
+		os.Remove(fp.Name())
+		return "", err
+	}
+	return fp.Name(), nil
+}
+
+// replaceInPlace renames tmp over name, first keeping the original as
+// name+suffix when suffix is not empty. The original stays in place until
+// the rename, which is atomic.
+func replaceInPlace(tmp, name, suffix string) error {
+	if suffix != "" {
+		backup := name + suffix
+		os.Remove(backup)
+		// a copy, where the file system has no hard links
+		if err := os.Link(name, backup); err != nil {
+			if err := copyFile(name, backup); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(tmp, name)
+}
+
+// copyFile copies the file src to dst, with the same permissions.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
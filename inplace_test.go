@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun_inPlaceFlag(t *testing.T) {
+	const original = "a,b\n \t,2\n"
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		backup   string
+	}{
+		{[]string{"-in-place"}, ExitCodeOK, "a,b\n,2\n", ""},
+		{[]string{"-i=.bak"}, ExitCodeOK, "a,b\n,2\n", ".bak"},
+		{[]string{"-i.orig"}, ExitCodeOK, "a,b\n,2\n", ".orig"},
+		{[]string{"-in-place=~"}, ExitCodeOK, "a,b\n,2\n", "~"},
+		{[]string{"-i", "-strict", "-check-whitespace-only"}, ExitCodeError, original, ""},
+		{[]string{"-i", "-select", "c"}, ExitCodeError, original, ""},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		name := filepath.Join(dir, "in.csv")
+		if err := os.WriteFile(name, []byte(original), 0600); err != nil {
+			t.Fatal(err)
+		}
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+
+		args := append([]string{"./csvlint", "-quote", "minimal", "-fix-whitespace-only"}, test.args...)
+		if status := cli.Run(append(args, name)); status != test.status {
+			t.Errorf("%v: expected %d to eq %d: %s", test.args, status, test.status, errStream.String())
+		}
+		if outStream.Len() != 0 {
+			t.Errorf("%v: expected no output, got %q", test.args, outStream.String())
+		}
+
+		if b, err := os.ReadFile(name); err != nil || string(b) != test.expected {
+			t.Errorf("%v: expected %q to eq %q (%v)", test.args, b, test.expected, err)
+		}
+		if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%v: expected mode 0600 to be kept: %v", test.args, err)
+		}
+		left := []string{"in.csv"}
+		if test.backup != "" {
+			if b, err := os.ReadFile(name + test.backup); err != nil || string(b) != original {
+				t.Errorf("%v: expected the backup %q to eq %q (%v)", test.args, b, original, err)
+			}
+			left = append(left, "in.csv"+test.backup)
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != len(left) {
+			t.Errorf("%v: expected only %v to be left, got %d files", test.args, left, len(entries))
+		}
+	}
+}
+
+func TestRun_inPlaceFlag_invalid(t *testing.T) {
+	files := writeFiles(t, "a\n", "b\n")
+	for _, args := range [][]string{
+		{"-i"},
+		{"-i", files[0], files[1]},
+		{"-i", "-o", files[1], files[0]},
+		{"-inplace", files[0]},
+	} {
+		cli := &CLI{outStream: new(bytes.Buffer), errStream: new(bytes.Buffer)}
+		if status := cli.Run(append([]string{"./csvlint"}, args...)); status != ExitCodeError {
+			t.Errorf("%v: expected %d to eq %d", args, status, ExitCodeError)
+		}
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "in.csv"), filepath.Join(dir, "in.csv.bak")
+	if err := os.WriteFile(src, []byte("a,b\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(dst); err != nil || string(b) != "a,b\n" {
+		t.Errorf("expected %q to eq %q (%v)", b, "a,b\n", err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be kept: %v", err)
+	}
+	if err := copyFile(src, dst); err == nil {
+		t.Error("expected an existing backup not to be overwritten")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// stripInvisible removes the format characters of v, Unicode category Cf:
+// zero-width spaces and joiners, the word joiner, soft hyphens, direction
+// marks and byte order marks anywhere in the field. It returns how many it
+// removed.
+func stripInvisible(v string) (string, int) {
+	n := 0
+	v = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			n++
+			return -1
+		}
+		return r
+	}, v)
+	return v, n
+}
+
+// invisibleCounts counts, by column, the characters -strip-invisible
+// removed from an input.
+type invisibleCounts struct {
+	names  []string
+	counts []int
+}
+
+func (c *invisibleCounts) add(i, n int) {
+	for len(c.counts) <= i {
+		c.counts = append(c.counts, 0)
+	}
+	c.counts[i] += n
+}
+
+// report adds a summary counter for every column with characters removed,
+// in the order of the columns.
+func (c *invisibleCounts) report(diag *diagnostics) {
+	for i, n := range c.counts {
+		if n == 0 {
+			continue
+		}
+		col := strconv.Itoa(i + 1)
+		if i < len(c.names) {
+			col = c.names[i]
+		}
+		diag.count("invisible characters removed from "+col, n)
+	}
+}
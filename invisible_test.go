@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_stripInvisibleFlag(t *testing.T) {
+	input := "id,na\u200Bme\nA\u200B1,b\u00ADo\u200Db\n\uFEFFA2,\u2060x\u200E\n"
+	tests := []struct {
+		args     string
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -strip-invisible", "id,name\nA1,bob\nA2,x\n", "invisible characters removed from id: 2\ninvisible characters removed from name: 4\n"},
+		{"./csvlint -quote minimal -strip-invisible -select id", "id\nA1\nA2\n", "invisible characters removed from id: 2\n"},
+		{"./csvlint -quote minimal -strip-invisible -no-header -no-transform-cols 1", "id,name\nA\u200B1,bob\n\uFEFFA2,x\n", "invisible characters removed from 2: 5\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
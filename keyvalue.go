@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// keyValueWriter writes a KEY=VALUE line per row, as in a .env or
+// properties file, from the key and value columns, by default the first
+// two. The header only names the columns.
+type keyValueWriter struct {
+	key, value string
+	noHeader   bool
+
+	indices []int
+}
+
+// bind resolves the key and value columns in the header of the output.
+func (k *keyValueWriter) bind(header []string) error {
+	k.indices = []int{0, 1}
+	index := headerIndex(header)
+	for i, col := range []string{k.key, k.value} {
+		if col == "" {
+			continue
+		}
+		n, err := columnIndex(col, index, k.noHeader)
+		if err != nil {
+			return fmt.Errorf("-keyvalue: %s", err)
+		}
+		k.indices[i] = n
+	}
+	return nil
+}
+
+// write writes the line of a data row. A row without both columns, or
+// whose key is empty or has white space or '=' in it, is reported and
+// left out.
+func (k *keyValueWriter) write(w io.Writer, name string, record []string, isHeader bool, line int, diag *diagnostics, opts *Options) error {
+	if k.indices == nil {
+		var header []string
+		if isHeader {
+			header = record
+		}
+		if err := k.bind(header); err != nil {
+			return err
+		}
+	}
+	if isHeader {
+		return nil
+	}
+	ki, vi := k.indices[0], k.indices[1]
+	if ki >= len(record) || vi >= len(record) {
+		diag.report(Diagnostic{File: name, Line: line, Rule: "keyvalue", Message: "the row is too short for the key and value"})
+		return nil
+	}
+	key := record[ki]
+	if key == "" || strings.ContainsAny(key, " \t\r\n=") {
+		diag.report(Diagnostic{File: name, Line: line, Column: ki + 1, Rule: "keyvalue", Message: fmt.Sprintf("%q is not a valid key", key)})
+		return nil
+	}
+	_, err := io.WriteString(w, key+"="+quoteEnvValue(record[vi])+opts.lineEnding())
+	return err
+}
+
+// envSpecial are the characters a value is quoted for, as a shell or a
+// dotenv parser would otherwise read them.
+const envSpecial = " \t\r\n\"'\\$`#;&|<>()"
+
+// quoteEnvValue double quotes v when it has white space or characters
+// special to a shell, escaping the quotes, backslashes, dollars, backticks
+// and newlines in it.
+func quoteEnvValue(v string) string {
+	if !strings.ContainsAny(v, envSpecial) {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(v) + `"`
+}
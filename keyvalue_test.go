@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQuoteEnvValue(t *testing.T) {
+	tests := []struct {
+		value, expected string
+	}{
+		{"plain", "plain"},
+		{"", ""},
+		{"a=b", "a=b"},
+		{"two words", `"two words"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"$HOME", `"\$HOME"`},
+		{"a\nb", `"a\nb"`},
+		{`C:\tmp`, `"C:\\tmp"`},
+	}
+	for _, test := range tests {
+		if got := quoteEnvValue(test.value); got != test.expected {
+			t.Errorf("%q: expected %q to eq %q", test.value, got, test.expected)
+		}
+	}
+}
+
+func TestRun_keyValueFlag(t *testing.T) {
+	input := "name,value,note\nHOST,db.local,x\nGREETING,hello world,y\nbad key,1,z\nSHORT\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -keyvalue", ExitCodeOK, "HOST=db.local\nGREETING=\"hello world\"\n", "line 4 column 1: \"bad key\" is not a valid key\nline 5: the row is too short for the key and value\n"},
+		{"./csvlint -keyvalue -kv-key name -kv-value note", ExitCodeOK, "HOST=x\nGREETING=y\n", "line 4 column 1: \"bad key\" is not a valid key\nline 5: the row is too short for the key and value\n"},
+		{"./csvlint -keyvalue -no-header -kv-key 3 -kv-value 1 -rows 1-3", ExitCodeOK, "note=name\nx=HOST\ny=GREETING\n", ""},
+		{"./csvlint -keyvalue -kv-key missing", ExitCodeError, "", "-keyvalue: unknown column \"missing\"\n"},
+		{"./csvlint -kv-key name", ExitCodeError, "", "-kv-key and -kv-value need -keyvalue\n"},
+		{"./csvlint -keyvalue -tsv", ExitCodeError, "", "-keyvalue cannot be combined with other output formats, -sort, -diff, -partition-by, -split-rows, -split-bytes, -file-workers or -check-idempotent\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// maxEndingLines is how many line numbers of each line ending are kept
+// for -check-line-endings.
+const maxEndingLines = 1000
+
+// lineEndings scans the bytes read through it for -check-line-endings,
+// before the csv reader normalizes CRLF away.
+type lineEndings struct {
+	r      io.Reader
+	line   int
+	prevCR bool
+
+	lf, crlf           int
+	lfLines, crlfLines []int
+}
+
+func (e *lineEndings) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	for _, c := range p[:n] {
+		if c == '\n' {
+			e.line++
+			if e.prevCR {
+				e.crlf++
+				if len(e.crlfLines) < maxEndingLines {
+					e.crlfLines = append(e.crlfLines, e.line)
+				}
+			} else {
+				e.lf++
+				if len(e.lfLines) < maxEndingLines {
+					e.lfLines = append(e.lfLines, e.line)
+				}
+			}
+		}
+		e.prevCR = c == '\r'
+	}
+	return n, err
+}
+
+// report counts the line endings seen and, if they are mixed, reports the
+// lines that use the less common one.
+func (e *lineEndings) report(name string, diag *diagnostics) {
+	prefix := ""
+	if name != "" {
+		prefix = name + ": "
+	}
+	diag.count(prefix+"LF line endings", e.lf)
+	diag.count(prefix+"CRLF line endings", e.crlf)
+	if e.lf == 0 || e.crlf == 0 {
+		return
+	}
+
+	kind, lines, total, other := "CRLF", e.crlfLines, e.crlf, "LF"
+	if e.lf < e.crlf {
+		kind, lines, total, other = "LF", e.lfLines, e.lf, "CRLF"
+	}
+	for _, line := range lines {
+		diag.report(Diagnostic{File: name, Line: line, Rule: "line-endings", Message: fmt.Sprintf("line ends with %s, most lines end with %s", kind, other)})
+	}
+	if n := total - len(lines); n > 0 {
+		diag.report(Diagnostic{File: name, Rule: "line-endings", Message: fmt.Sprintf("%d more lines end with %s", n, kind)})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_checkLineEndingsFlag(t *testing.T) {
+	tests := []struct {
+		input    string
+		status   int
+		expected string
+	}{
+		{"a\nb\nc\n", ExitCodeOK, "LF line endings: 3\nCRLF line endings: 0\n"},
+		{"a\r\nb\r\n", ExitCodeOK, "LF line endings: 0\nCRLF line endings: 2\n"},
+		{
+			"a\r\nb\nc\r\n\"d\ne\"\r\n",
+			ExitCodeError,
+			"line 2: line ends with LF, most lines end with CRLF\nline 4: line ends with LF, most lines end with CRLF\nLF line endings: 2\nCRLF line endings: 3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(tt.input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split("./csvlint -check-line-endings -strict", " "))
+		if status != tt.status {
+			t.Errorf("%q: expected %d to eq %d", tt.input, status, tt.status)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%q: expected %q to eq %q", tt.input, errStream.String(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isWhitespaceOnly reports whether v is not empty but consists of Unicode
+// white space alone, including no-break and ideographic spaces.
+func isWhitespaceOnly(v string) bool {
+	return v != "" && strings.TrimFunc(v, unicode.IsSpace) == ""
+}
+
+// lintRecord runs the per-field checks enabled in opts over a data record
+// and applies their fixes in place, except to the fields in keep.
+func lintRecord(name string, record []string, reader recordReader, diag *diagnostics, keep map[int]bool, opts *Options) {
+	for i, v := range record {
+		if (opts.CheckWhitespaceOnly || opts.FixWhitespaceOnly) && isWhitespaceOnly(v) {
+			if opts.CheckWhitespaceOnly {
+				line, _ := reader.FieldPos(i)
+				diag.report(Diagnostic{File: name, Line: line, Column: i + 1, Rule: "whitespace-only", Message: "whitespace-only field"})
+			}
+			if opts.FixWhitespaceOnly && !keep[i] {
+				record[i] = ""
+				continue
+			}
+		}
+		if opts.CheckSmartChars {
+			if found := findSmartChars(v); len(found) > 0 {
+				line, _ := reader.FieldPos(i)
+				diag.report(Diagnostic{File: name, Line: line, Column: i + 1, Rule: "smartchars", Message: smartCharsMessage(found)})
+			}
+		}
+		if opts.FixSmartChars && !keep[i] {
+			record[i] = smartCharReplacer.Replace(record[i])
+		}
+	}
+}
+
+// resolveKeep maps the -no-transform-cols columns to their index.
+func resolveKeep(cols []string, header []string, noHeader bool) (map[int]bool, error) {
+	index := headerIndex(header)
+	keep := make(map[int]bool, len(cols))
+	for _, name := range cols {
+		n, err := columnIndex(name, index, noHeader)
+		if err != nil {
+			return nil, err
+		}
+		keep[n] = true
+	}
+	return keep, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Policies for values that a -lookup table does not map.
+const (
+	LookupKeep   = "keep"
+	LookupBlank  = "blank"
+	LookupReport = "report"
+)
+
+// lookup replaces the values of a column with those mapped by a
+// translation table.
+type lookup struct {
+	column string
+	file   string
+	table  map[string]string
+}
+
+// loadLookup parses a col=map.csv spec and reads the map, a csv file whose
+// every row is a key and the value it maps to.
+func loadLookup(spec string) (lookup, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 || i == len(spec)-1 {
+		return lookup{}, fmt.Errorf("invalid lookup %q: expected col=map.csv", spec)
+	}
+	l := lookup{column: spec[:i], file: spec[i+1:], table: map[string]string{}}
+
+	f, err := os.Open(l.file)
+	if err != nil {
+		return lookup{}, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return lookup{}, fmt.Errorf("%s: %s", l.file, err)
+		}
+		if v, ok := l.table[record[0]]; ok && v != record[1] {
+			line, _ := r.FieldPos(0)
+			return lookup{}, fmt.Errorf("%s: line %d: %q is mapped to both %q and %q", l.file, line, record[0], v, record[1])
+		}
+		l.table[record[0]] = record[1]
+	}
+	return l, nil
+}
+
+// bindLookups resolves the columns of lookups.
+func bindLookups(lookups []lookup, header []string, noHeader bool) ([]int, error) {
+	index := headerIndex(header)
+	indices := make([]int, len(lookups))
+	for i, l := range lookups {
+		n, err := columnIndex(l.column, index, noHeader)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %q: %s", l.column, err)
+		}
+		indices[i] = n
+	}
+	return indices, nil
+}
+
+// applyLookups maps the fields of record at indices through their tables.
+// Unmapped values are handled as policy says; pos gives the input line and
+// 1-based column of a field for reporting them.
+func applyLookups(name string, record []string, lookups []lookup, indices []int, policy string, pos func(field int) (int, int), diag *diagnostics) {
+	for i, l := range lookups {
+		n := indices[i]
+		if n >= len(record) {
+			continue
+		}
+		if v, ok := l.table[record[n]]; ok {
+			record[n] = v
+			continue
+		}
+		switch policy {
+		case LookupBlank:
+			record[n] = ""
+		case LookupReport:
+			line, column := pos(n)
+			diag.report(Diagnostic{File: name, Line: line, Column: column, Rule: "lookup", Message: fmt.Sprintf("%s: %q is not in %s", l.column, record[n], l.file)})
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_lookupFlag(t *testing.T) {
+	maps := writeFiles(t, "JP,Japan\nFR,France\n", "JP,Japan\nJP,Nippon\n", "JP\n")
+	input := "name,country\nAda,FR\nBob,JP\nCy,XX\n"
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{
+			[]string{"-lookup", "country=" + maps[0]},
+			ExitCodeOK, "name,country\nAda,France\nBob,Japan\nCy,XX\n", "",
+		},
+		{
+			[]string{"-lookup", "country=" + maps[0], "-lookup-missing", "blank"},
+			ExitCodeOK, "name,country\nAda,France\nBob,Japan\nCy,\n", "",
+		},
+		{
+			[]string{"-lookup", "country=" + maps[0], "-lookup-missing", "report", "-strict"},
+			ExitCodeError, "name,country\nAda,France\nBob,Japan\nCy,XX\n", "line 4 column 2: country: \"XX\" is not in " + maps[0] + "\n",
+		},
+		{
+			[]string{"-lookup", "c=" + maps[0], "-select", "country:c", "-lookup-missing", "report", "-rule", `c=="Japan" => c="JPN"`},
+			ExitCodeOK, "c\nFrance\nJPN\nXX\n", "line 4 column 2: c: \"XX\" is not in " + maps[0] + "\n",
+		},
+		{
+			[]string{"-lookup", "2=" + maps[0], "-no-header"},
+			ExitCodeOK, "name,country\nAda,France\nBob,Japan\nCy,XX\n", "",
+		},
+		{
+			[]string{"-lookup", "country=" + maps[1]},
+			ExitCodeError, "", maps[1] + ": line 2: \"JP\" is mapped to both \"Japan\" and \"Nippon\"\n",
+		},
+		{
+			[]string{"-lookup", "country=" + maps[2]},
+			ExitCodeError, "", maps[2] + ": record on line 1: wrong number of fields\n",
+		},
+		{[]string{"-lookup", "country"}, ExitCodeError, "", "invalid lookup \"country\": expected col=map.csv\n"},
+		{[]string{"-lookup", "city=" + maps[0]}, ExitCodeError, "", "lookup \"city\": unknown column \"city\"\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		args := append([]string{"./csvlint", "-quote", "minimal"}, test.args...)
+		status := cli.Run(args)
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// digestWriter passes writes through to w while hashing and counting them.
+type digestWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func newDigestWriter(w io.Writer) *digestWriter {
+	return &digestWriter{w: w, h: sha256.New()}
+}
+
+func (d *digestWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.h.Write(p[:n])
+	d.n += int64(n)
+	return n, err
+}
+
+// Manifest describes the output of a run so that consumers can verify they
+// received all of it.
+type Manifest struct {
+	Records int    `json:"records"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+	// Compressed reports whether Bytes and SHA256 are over the gzip stream.
+	Compressed bool `json:"compressed"`
+}
+
+func newManifest(records int, d *digestWriter, compressed bool) *Manifest {
+	return &Manifest{
+		Records:    records,
+		Bytes:      d.n,
+		SHA256:     hex.EncodeToString(d.h.Sum(nil)),
+		Compressed: compressed,
+	}
+}
+
+func writeManifest(name string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(b, '\n'), 0644)
+}
+
+func loadManifest(name string) (*Manifest, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return m, nil
+}
+
+// diff describes every field in which got differs from m, one per line,
+// or returns "" when they match.
+func (m *Manifest) diff(got *Manifest) string {
+	var b strings.Builder
+	field := func(key string, want, got interface{}) {
+		if want != got {
+			fmt.Fprintf(&b, "  %s: manifest %v, output %v\n", key, want, got)
+		}
+	}
+	field("compressed", m.Compressed, got.Compressed)
+	field("records", m.Records, got.Records)
+	field("bytes", m.Bytes, got.Bytes)
+	field("sha256", m.SHA256, got.SHA256)
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readManifest(t *testing.T, name string) *Manifest {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(b, m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRun_manifestFlag(t *testing.T) {
+	dir := t.TempDir()
+	out, manifest := filepath.Join(dir, "out.csv"), filepath.Join(dir, "manifest.json")
+	inStream := strings.NewReader("a,b\nc,d\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-o", out, "-manifest", manifest}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	written, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := readManifest(t, manifest)
+	if m.Records != 2 || m.Bytes != int64(len(written)) || m.SHA256 != sha256Hex(written) || m.Compressed {
+		t.Errorf("manifest %+v does not describe %q", m, written)
+	}
+}
+
+func TestRun_manifestFlag_gzip(t *testing.T) {
+	dir := t.TempDir()
+	out, manifest := filepath.Join(dir, "out.csv.gz"), filepath.Join(dir, "manifest.json")
+
+	for _, uncompressed := range []bool{false, true} {
+		inStream := strings.NewReader("a,b\n")
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+		args := []string{"./csvlint", "-o", out, "-gzip-out", "-manifest", manifest}
+		if uncompressed {
+			args = append(args, "-manifest-uncompressed")
+		}
+
+		status := cli.Run(args)
+		if status != ExitCodeOK {
+			t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+		}
+
+		written, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uncompressed {
+			zr, err := gzip.NewReader(bytes.NewReader(written))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written, err = io.ReadAll(zr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m := readManifest(t, manifest)
+		if m.SHA256 != sha256Hex(written) || m.Compressed == uncompressed {
+			t.Errorf("manifest %+v does not describe %q", m, written)
+		}
+	}
+}
+
+func TestRun_verifyFlag(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.json")
+	run := func(input string, args ...string) (int, string) {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+		return cli.Run(append([]string{"./csvlint"}, args...)), errStream.String()
+	}
+
+	if status, errs := run("a,b\n1,2\n", "-manifest", manifest); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errs)
+	}
+	if status, errs := run("a,b\n1,2\n", "-verify", manifest); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errs)
+	}
+
+	m := readManifest(t, manifest)
+	status, errs := run("a,b\n1,3\n4,5\n", "-verify", manifest)
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	got := sha256Hex([]byte("\"a\",\"b\"\n\"1\",\"3\"\n\"4\",\"5\"\n"))
+	expected := "output does not match " + manifest + ":\n  records: manifest 2, output 3\n  bytes: manifest 16, output 24\n  sha256: manifest " + m.SHA256 + ", output " + got + "\n"
+	if errs != expected {
+		t.Errorf("expected %q to eq %q", errs, expected)
+	}
+
+	if status, errs := run("a,b\n1,2\n", "-verify", manifest, "-gzip-out", "-manifest-uncompressed"); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errs)
+	}
+	if status, errs := run("a,b\n1,2\n", "-verify", manifest, "-gzip-out"); status != ExitCodeError || !strings.HasPrefix(errs, "output does not match "+manifest+":\n  compressed: manifest false, output true\n") {
+		t.Errorf("expected a compressed mismatch, got %d: %s", status, errs)
+	}
+	if status, _ := run("a,b\n", "-verify", filepath.Join(dir, "missing.json")); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+}
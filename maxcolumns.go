@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errTooManyColumns is returned by transform when a record has more fields
+// than -max-columns. The record has already been reported.
+var errTooManyColumns = errors.New("too many columns")
+
+// errFieldCountChanged is returned by transform when
+// -abort-on-field-count-change meets a record whose width differs from the
+// first one's. The record has already been reported.
+var errFieldCountChanged = errors.New("field count changed")
+
+// checkMaxColumns reports a record with more than max fields, which usually
+// means the delimiter is wrong, and returns errTooManyColumns.
+func checkMaxColumns(name string, record []string, reader recordReader, max int, diag *diagnostics) error {
+	if len(record) <= max {
+		return nil
+	}
+	line, _ := reader.FieldPos(max)
+	diag.report(Diagnostic{File: name, Line: line, Column: max + 1, Rule: "max-columns", Message: fmt.Sprintf("record has %d fields, more than the limit of %d", len(record), max)})
+	return errTooManyColumns
+}
+
+// checkFieldCount reports a record that does not have want fields and
+// returns errFieldCountChanged.
+func checkFieldCount(name string, record []string, reader recordReader, want int, diag *diagnostics) error {
+	if len(record) == want {
+		return nil
+	}
+	line, _ := reader.FieldPos(0)
+	diag.report(Diagnostic{File: name, Line: line, Rule: "field-count", Message: fmt.Sprintf("record has %d fields instead of %d", len(record), want)})
+	return errFieldCountChanged
+}
+
+// reported tells whether err stopped an input after it was reported as a
+// diagnostic, so that it is not reported again.
+func reported(err error) bool {
+	return err == errMissingColumns || err == errTooManyColumns || err == errFieldCountChanged || err == errAvroValue || err == errParquetValue || err == errEmptyInput || err == errCastValue
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRun_maxColumnsFlag(t *testing.T) {
+	input := "a,b,c\n1,2,3\n4,5,6,7\n8,9,10\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -max-columns 3", ExitCodeError, "a,b,c\n1,2,3\n", "line 3 column 4: record has 4 fields, more than the limit of 3\n"},
+		{"./csvlint -quote minimal -max-columns 2", ExitCodeError, "", "line 1 column 3: record has 3 fields, more than the limit of 2\n"},
+		{"./csvlint -quote minimal -max-columns 4", ExitCodeOK, "a,b,c\n1,2,3\n4,5,6,7\n8,9,10\n", ""},
+		{"./csvlint -max-columns -1", ExitCodeError, "", "-max-columns must not be negative\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_maxColumnsFiles(t *testing.T) {
+	files := writeFiles(t, "a,b\n1,2,3\n", "a,b\n4,5\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+
+	args := append([]string{"./csvlint", "-quote", "minimal", "-max-columns", "2"}, files...)
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	if expected := "a,b\n4,5\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	if expected := files[0] + ": line 2 column 3: record has 3 fields, more than the limit of 2\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_abortOnFieldCountChangeFlag(t *testing.T) {
+	input := "a,b,c\n1,2,3\n4,5\n6,7,8\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -abort-on-field-count-change", ExitCodeError, "a,b,c\n1,2,3\n", "line 3: record has 2 fields instead of 3\n"},
+		{"./csvlint -quote minimal -abort-on-field-count-change -pad -quarantine " + os.DevNull, ExitCodeError, "a,b,c\n1,2,3\n", "line 3: record has 2 fields instead of 3\nquarantined rows: 0\npassed rows: 1\n"},
+		{"./csvlint -quote minimal -abort-on-field-count-change -no-header -rows 1", ExitCodeOK, "a,b,c\n", ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// recordOverhead approximates the memory a kept record takes besides its
+// fields, and fieldOverhead that of each field besides its bytes.
+const (
+	recordOverhead = 64
+	fieldOverhead  = 16
+)
+
+// recordSize estimates the memory record takes once kept.
+func recordSize(record []string) int64 {
+	n := int64(recordOverhead)
+	for _, v := range record {
+		n += int64(len(v)) + fieldOverhead
+	}
+	return n
+}
+
+// memoryLimit is -max-memory: it adds up an estimate of the bytes the
+// buffering operations keep, and stops them once it is exceeded. A nil
+// limit allows everything.
+type memoryLimit struct {
+	max  int64
+	spec string
+	used int64
+}
+
+// grow accounts for n more bytes kept by what, and fails when they take
+// the total over the limit.
+func (m *memoryLimit) grow(what string, n int64) error {
+	if m == nil {
+		return nil
+	}
+	if atomic.AddInt64(&m.used, n) > m.max {
+		return fmt.Errorf("%s would take more memory than -max-memory %s", what, m.spec)
+	}
+	return nil
+}
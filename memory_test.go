@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRun_maxMemoryFlag(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,name\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, "%d,name%d\n", 100-i, i)
+	}
+	files := writeFiles(t, input.String())
+
+	tests := []struct {
+		args   []string
+		status int
+		errors string
+	}{
+		{[]string{"-max-memory", "1K", "-pretty"}, ExitCodeError, "-pretty would take more memory than -max-memory 1K\n"},
+		{[]string{"-max-memory", "1K", "-values", "name"}, ExitCodeError, "-values would take more memory than -max-memory 1K\n"},
+		{[]string{"-max-memory", "1K", "-count-by", "name"}, ExitCodeError, "-count-by would take more memory than -max-memory 1K\n"},
+		{[]string{"-max-memory", "1K", "-keys-not-in", files[0], "-key", "name"}, ExitCodeError, files[0] + ": -keys-not-in would take more memory than -max-memory 1K\n"},
+		{[]string{"-max-memory", "1K", "-diff", files[0], "-key", "id"}, ExitCodeError, files[0] + ": -diff would take more memory than -max-memory 1K\n"},
+		{[]string{"-max-memory", "1M", "-pretty"}, ExitCodeOK, ""},
+		{[]string{"-max-memory", "none", "-pretty"}, ExitCodeError, "invalid -max-memory \"none\"\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input.String()), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(append([]string{"./csvlint"}, tt.args...)); status != tt.status {
+			t.Errorf("%v: expected %d to eq %d", tt.args, status, tt.status)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%v: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
+
+func TestRun_maxMemoryFlagSort(t *testing.T) {
+	var input, expected strings.Builder
+	input.WriteString("id\n")
+	expected.WriteString("id\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, "%d\n", 100-i)
+		fmt.Fprintf(&expected, "%d\n", i+1)
+	}
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input.String()), outStream: outStream, errStream: errStream}
+
+	// the rows take more than 1K, so they are sorted on disk
+	if status := cli.Run([]string{"./csvlint", "-quote", "minimal", "-max-memory", "1K", "-sort", "id:n"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if outStream.String() != expected.String() {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected.String())
+	}
+}
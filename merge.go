@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mergeSpec is one -merge: columns whose fields are joined into a new
+// column of the given name.
+type mergeSpec struct {
+	columns []string
+	name    string
+	spec    string
+}
+
+// mergesValue collects repeatable a,b,c=name flags.
+type mergesValue []mergeSpec
+
+func (m *mergesValue) String() string {
+	var specs []string
+	for _, s := range *m {
+		specs = append(specs, s.spec)
+	}
+	return strings.Join(specs, " ")
+}
+
+func (m *mergesValue) Set(v string) error {
+	s, err := parseMergeSpec("merge", v)
+	if err != nil {
+		return err
+	}
+	*m = append(*m, s)
+	return nil
+}
+
+// coalescesValue collects repeatable a,b,c=name flags of -coalesce.
+type coalescesValue []mergeSpec
+
+func (m *coalescesValue) String() string {
+	return (*mergesValue)(m).String()
+}
+
+func (m *coalescesValue) Set(v string) error {
+	s, err := parseMergeSpec("coalesce", v)
+	if err != nil {
+		return err
+	}
+	*m = append(*m, s)
+	return nil
+}
+
+func parseMergeSpec(flag, v string) (mergeSpec, error) {
+	i := strings.LastIndex(v, "=")
+	if i <= 0 || i == len(v)-1 {
+		return mergeSpec{}, fmt.Errorf("expected a,b,c=name, got %q", v)
+	}
+	s := mergeSpec{columns: strings.Split(v[:i], ","), name: v[i+1:], spec: v}
+	for _, col := range s.columns {
+		if col == "" {
+			return mergeSpec{}, fmt.Errorf("invalid -%s %q: empty column", flag, v)
+		}
+	}
+	return s, nil
+}
+
+// boundMerge is a -merge or -coalesce resolved in the header it applies
+// to.
+type boundMerge struct {
+	sources []int
+	name    string
+	drop    bool
+	// coalesce takes the first field of the sources that is not empty
+	// instead of joining them
+	coalesce bool
+	// width is that of the header, to which short rows are padded so
+	// that the new column lines up. It is 0 without a header.
+	width int
+}
+
+// bindMerges resolves the merges one after the other, each in the header
+// left by those before it, and returns that of the last one. Without a
+// header the columns are numbers and the returned header is nil.
+func bindMerges(merges []mergeSpec, header []string, noHeader, drop bool) ([]boundMerge, []string, error) {
+	return bindCombined("-merge", merges, header, noHeader, drop, false)
+}
+
+// bindCoalesces is bindMerges for -coalesce.
+func bindCoalesces(coalesces []mergeSpec, header []string, noHeader, drop bool) ([]boundMerge, []string, error) {
+	return bindCombined("-coalesce", coalesces, header, noHeader, drop, true)
+}
+
+func bindCombined(flag string, merges []mergeSpec, header []string, noHeader, drop, coalesce bool) ([]boundMerge, []string, error) {
+	bound := make([]boundMerge, len(merges))
+	for i, m := range merges {
+		sources, err := columnIndices(flag, m.columns, header, noHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+		b := boundMerge{sources: sources, name: m.name, drop: drop, coalesce: coalesce, width: len(header)}
+		if !noHeader {
+			header = b.apply(header, "", true)
+			for _, name := range header[:len(header)-1] {
+				if name == m.name {
+					return nil, nil, fmt.Errorf("%s: the header already has a column %q", flag, m.name)
+				}
+			}
+		}
+		bound[i] = b
+	}
+	return bound, header, nil
+}
+
+// apply returns record, less the sources when they are dropped, followed
+// by their fields joined with sep, or the first of them not empty with
+// coalesce, or by the name in the header. The fields missing from a short
+// record are empty.
+func (b boundMerge) apply(record []string, sep string, isHeader bool) []string {
+	for len(record) < b.width {
+		record = append(record, "")
+	}
+	merged := b.name
+	if !isHeader && b.coalesce {
+		merged = ""
+		for _, n := range b.sources {
+			if v := field(record, n); v != "" {
+				merged = v
+				break
+			}
+		}
+	} else if !isHeader {
+		parts := make([]string, len(b.sources))
+		for i, n := range b.sources {
+			parts[i] = field(record, n)
+		}
+		merged = strings.Join(parts, sep)
+	}
+	if !b.drop {
+		return append(record[:len(record):len(record)], merged)
+	}
+	out := make([]string, 0, len(record)+1)
+	for i, v := range record {
+		if !containsInt(b.sources, i) {
+			out = append(out, v)
+		}
+	}
+	return append(out, merged)
+}
+
+func applyMerges(record []string, merges []boundMerge, sep string, isHeader bool) []string {
+	for _, m := range merges {
+		record = m.apply(record, sep, isHeader)
+	}
+	return record
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
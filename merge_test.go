@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_mergeFlag(t *testing.T) {
+	input := "first,last,city\nAda,Lovelace,London\nAlan\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -quote minimal -merge first,last=name", "first,last,city,name\nAda,Lovelace,London,Ada Lovelace\nAlan,,,Alan \n"},
+		{"./csvlint -quote minimal -merge last,first=name -merge-sep , -merge-drop", "city,name\nLondon,\"Lovelace,Ada\"\n,\",Alan\"\n"},
+		{"./csvlint -quote minimal -merge first,last=name -merge-drop -merge name,city=key -merge-sep /", "key\nAda/Lovelace/London\nAlan//\n"},
+		{"./csvlint -quote minimal -merge first,last=name -merge-drop -hash-column h -hash-cols name -header-case upper", "CITY,NAME,h\nLondon,Ada Lovelace,535b674bbc371669\n,Alan ,2071f898c852e472\n"},
+		{"./csvlint -quote minimal -no-header -merge 1,3=x -merge-drop", "last,first city\nLovelace,Ada London\nAlan \n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_mergeFlagErrors(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -merge first,last=city", "-merge: the header already has a column \"city\"\n"},
+		{"./csvlint -merge first,nick=name", "-merge: unknown column \"nick\"\n"},
+		{"./csvlint -merge-drop", "-merge-sep and -merge-drop need -merge\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("first,last,city\nAda,Lovelace,London\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_coalesceFlag(t *testing.T) {
+	input := "mobile,home,work\n,555-1,555-2\n555-0,,555-2\n,,\n,,555-2\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -quote minimal -coalesce mobile,home,work=phone", "mobile,home,work,phone\n,555-1,555-2,555-1\n555-0,,555-2,555-0\n,,,\n,,555-2,555-2\n"},
+		{"./csvlint -quote minimal -coalesce work,home=phone -coalesce-drop", "mobile,phone\n,555-2\n555-0,555-2\n,\n,555-2\n"},
+		{"./csvlint -quote minimal -merge home,work=both -merge-sep / -merge-drop -coalesce mobile,both=phone -coalesce-drop", "phone\n555-1/555-2\n555-0\n/\n/555-2\n"},
+		{"./csvlint -quote minimal -no-header -coalesce 2,1=x -coalesce-drop", "work,home\n555-2,555-1\n555-2,555-0\n,\n555-2,\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -coalesce home,work=mobile", "-coalesce: the header already has a column \"mobile\"\n"},
+		{"./csvlint -coalesce home,fax=phone", "-coalesce: unknown column \"fax\"\n"},
+		{"./csvlint -coalesce-drop", "-coalesce-drop needs -coalesce\n"},
+	} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newlineValue is -field-newline, the policy for newlines inside fields: one
+// of the Newline policies for all of them, and cr=MODE or lf=MODE for
+// carriage returns or line feeds alone, separated by commas, as in
+// "remove,lf=keep". With part set to "cr" or "lf", it is the -cr-handling
+// or -lf-handling alias, which takes a MODE alone.
+type newlineValue struct {
+	opts *Options
+	part string
+}
+
+func (v newlineValue) String() string {
+	if v.opts == nil {
+		return ""
+	}
+	switch v.part {
+	case "cr":
+		return v.opts.CRHandling
+	case "lf":
+		return v.opts.LFHandling
+	}
+	var items []string
+	if v.opts.FieldNewline != "" {
+		items = append(items, v.opts.FieldNewline)
+	}
+	if v.opts.CRHandling != "" {
+		items = append(items, "cr="+v.opts.CRHandling)
+	}
+	if v.opts.LFHandling != "" {
+		items = append(items, "lf="+v.opts.LFHandling)
+	}
+	return strings.Join(items, ",")
+}
+
+func (v newlineValue) Set(s string) error {
+	if v.part != "" {
+		return v.setPart(v.part, s)
+	}
+	for _, item := range strings.Split(s, ",") {
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			part := item[:i]
+			if part != "cr" && part != "lf" {
+				return fmt.Errorf("expected cr=MODE or lf=MODE, got %q", item)
+			}
+			if err := v.setPart(part, item[i+1:]); err != nil {
+				return err
+			}
+			continue
+		}
+		switch item {
+		case NewlineEscape, NewlineSpace, NewlineRemove, NewlineKeep:
+		default:
+			return fmt.Errorf("expected escape, space, remove or keep, or cr=MODE or lf=MODE, got %q", item)
+		}
+		v.opts.FieldNewline = item
+	}
+	return nil
+}
+
+// setPart sets how carriage returns or line feeds alone are written.
+func (v newlineValue) setPart(part, mode string) error {
+	switch mode {
+	case NewlineEscape, NewlineRemove, NewlineKeep:
+	default:
+		return fmt.Errorf("expected a %s mode of escape, remove or keep, got %q", part, mode)
+	}
+	if part == "cr" {
+		v.opts.CRHandling = mode
+	} else {
+		v.opts.LFHandling = mode
+	}
+	return nil
+}
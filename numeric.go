@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// numericLocale is how numbers are written in a -numeric-locale: the
+// character ending the integer part and those grouping its digits by
+// thousands.
+type numericLocale struct {
+	decimal   string
+	thousands string
+}
+
+var numericLocales = map[string]numericLocale{
+	"en": {decimal: ".", thousands: ","},
+	"de": {decimal: ",", thousands: "."},
+	// French groups digits with a space, which is often a no-break or a
+	// narrow no-break space.
+	"fr": {decimal: ",", thousands: "   "},
+}
+
+// numericLocaleNames returns the accepted -numeric-locale values.
+func numericLocaleNames() []string {
+	var names []string
+	for name := range numericLocales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// numberPattern returns the regexp matching the numbers of l: an optional
+// sign, then an integer part, either without separators or with every
+// group of three digits separated, and an optional fraction, or a
+// fraction alone.
+func (l numericLocale) numberPattern() *regexp.Regexp {
+	d := regexp.QuoteMeta(l.decimal)
+	t := "[" + regexp.QuoteMeta(l.thousands) + "]"
+	return regexp.MustCompile(`^[+-]?(([0-9]+|[0-9]{1,3}(` + t + `[0-9]{3})+)(` + d + `[0-9]+)?|` + d + `[0-9]+)$`)
+}
+
+// numericCheck reports the values of -check-numeric columns that are not
+// numbers in the locale.
+type numericCheck struct {
+	*formatCheck
+	locale string
+}
+
+func newNumericCheck(columns []string, locale string) (*numericCheck, error) {
+	l, ok := numericLocales[locale]
+	if !ok {
+		return nil, fmt.Errorf("invalid -numeric-locale %q: must be %s", locale, strings.Join(numericLocaleNames(), ", "))
+	}
+	valid := l.numberPattern().MatchString
+	return &numericCheck{formatCheck: &formatCheck{flag: "-check-numeric", rule: "numeric", what: "a number in the " + locale + " locale", valid: valid, columns: columns}, locale: locale}, nil
+}
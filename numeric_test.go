@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNumericLocale_numberPattern(t *testing.T) {
+	tests := []struct {
+		locale string
+		valid  []string
+		wrong  []string
+	}{
+		{"en", []string{"1234", "-1,234.56", "+0.5", ".5", "12,345,678"}, []string{"1.234,56", "12,34", "1,2345", "1.", "+", "1e5", "1 234"}},
+		{"de", []string{"1234,56", "1.234,56", "-12.345", ",5"}, []string{"1,234.56", "1.23", "1.234.5", "12,"}},
+		{"fr", []string{"1 234,56", "1 234", "1 234 567,8", "1234,5"}, []string{"1,234.56", "1 23", "1.234,5"}},
+	}
+	for _, test := range tests {
+		re := numericLocales[test.locale].numberPattern()
+		for _, v := range test.valid {
+			if !re.MatchString(v) {
+				t.Errorf("%s: expected %q to be a number", test.locale, v)
+			}
+		}
+		for _, v := range test.wrong {
+			if re.MatchString(v) {
+				t.Errorf("%s: expected %q not to be a number", test.locale, v)
+			}
+		}
+	}
+}
+
+func TestRun_checkNumericFlag(t *testing.T) {
+	input := "id,price,qty\n1,\"1.234,56\",3\n2,\"1,234.56\",\n3,12,x\n"
+	tests := []struct {
+		args   string
+		status int
+		errors string
+	}{
+		{"./csvlint -check-numeric price -check-numeric qty -numeric-locale de", ExitCodeOK,
+			"line 3 column 2: price: \"1,234.56\" is not a number in the de locale\n" +
+				"line 4 column 3: qty: \"x\" is not a number in the de locale\n"},
+		{"./csvlint -check-numeric price -strict", ExitCodeError,
+			"line 2 column 2: price: \"1.234,56\" is not a number in the en locale\n"},
+		{"./csvlint -check-numeric total", ExitCodeError, "-check-numeric: unknown column \"total\"\n"},
+		{"./csvlint -check-numeric price -numeric-locale jp", ExitCodeError, "invalid -numeric-locale \"jp\": must be de, en, fr\n"},
+		{"./csvlint -numeric-locale de", ExitCodeError, "-numeric-locale needs -check-numeric\n"},
+	}
+	for _, test := range tests {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: new(bytes.Buffer), errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
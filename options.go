@@ -0,0 +1,727 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Options holds the settings that control how records are normalized.
+type Options struct {
+	RemoveTab     bool
+	RemoveNewline bool
+	RemoveSpace   bool
+	TSV           bool
+	SkipHeader    bool
+	NoHeader      bool
+
+	// FieldNewline, one of the Newline policies, sets how any newline inside
+	// a field is written, overriding RemoveNewline, which stands for
+	// NewlineRemove. TSVNewline overrides both for TSV output.
+	FieldNewline string
+	TSVNewline   string
+	// CRHandling and LFHandling, NewlineEscape, NewlineRemove or
+	// NewlineKeep, set how carriage returns and line feeds inside fields
+	// are written, overriding the policy for both. All four are set by
+	// -field-newline, of which the other newline flags are aliases.
+	CRHandling string
+	LFHandling string
+
+	// ZipEntry names the entry read from input files that are zip
+	// archives. When empty, an archive must hold a single file.
+	ZipEntry string
+
+	// PreserveComments copies lines starting with '#' to the output, with
+	// the '#' replaced by CommentPrefix. An empty CommentPrefix drops them.
+	PreserveComments bool
+	CommentPrefix    string
+
+	// CheckWhitespaceOnly reports fields made of white space alone and
+	// FixWhitespaceOnly empties them.
+	CheckWhitespaceOnly bool
+	FixWhitespaceOnly   bool
+	// CheckSmartChars reports the smartChars and FixSmartChars replaces
+	// them with ASCII.
+	CheckSmartChars bool
+	FixSmartChars   bool
+	// CheckBOM reports the inputs starting with a UTF-8 byte order mark
+	// and StripBOM removes it.
+	CheckBOM bool
+	StripBOM bool
+	// StripInvisible removes the format characters, such as zero-width
+	// spaces, from every field and counts them by column.
+	StripInvisible bool
+
+	// Pad extends rows shorter than the header to its width. Fill gives
+	// the value of missing columns by name, and implies Pad.
+	Pad  bool
+	Fill map[string]string
+
+	// PadTo extends every record, the header too, to at least this many
+	// fields, and PadTruncate cuts longer ones to it.
+	PadTo       int
+	PadTruncate bool
+
+	// Sample keeps only a random sample of this many data rows of each
+	// input, chosen with Seed.
+	Sample int
+	Seed   int64
+
+	// DedupHeaderRows drops data rows that repeat the header.
+	DedupHeaderRows bool
+
+	// Rows, when set, keeps only these data rows, and reading stops after
+	// the last of them.
+	Rows rowSet
+
+	// Select projects and renames columns when it is not empty.
+	// ColumnsRegex adds the other header columns whose name matches it.
+	// ProjectionOrder tells whether they are written as listed or in
+	// their order in the input.
+	Select          []selectColumn
+	ColumnsRegex    *regexp.Regexp
+	ProjectionOrder string
+	// Exclude drops these columns and keeps the others in order.
+	Exclude []string
+	// CleanText repairs invalid UTF-8, normalizes to NFC, removes
+	// zero-width characters, folds smart characters and trims every field.
+	CleanText bool
+
+	// RenameRegex are substitutions applied in order to every header name,
+	// before HeaderCase.
+	RenameRegex []regexReplace
+
+	// HeaderCase, one of the header cases, rewrites the names of the
+	// header written.
+	HeaderCase string
+
+	// FieldHistogram counts the data rows by their number of fields.
+	FieldHistogram bool
+	// MaxColumns, when not zero, is the most fields a record may have.
+	MaxColumns int
+	// AbortOnFieldCountChange stops at the first record whose width differs
+	// from the first record's.
+	AbortOnFieldCountChange bool
+	// RequireColumns must all be in the header.
+	RequireColumns []string
+	// CheckLineEndings reports mixed LF and CRLF line endings.
+	CheckLineEndings bool
+	// Ranges are the -range checks. With RangeSkipEmpty empty values are
+	// not checked.
+	Ranges         []numRange
+	RangeSkipEmpty bool
+	// UniqueKey are the columns whose fields, together, must not be the
+	// same in two data rows of an input.
+	UniqueKey []string
+	// Numeric, when set, reports values of its columns that are not
+	// numbers written in its locale.
+	Numeric *numericCheck
+	// Formats, set by -validate-email and -validate-url, report values of
+	// their columns that are not well formed.
+	Formats []*formatCheck
+	// Dates, when set by -since or -until, leaves out the data rows whose
+	// -date-col is outside the window.
+	Dates *dateWindow
+	// Strict makes any reported problem fail the run.
+	Strict bool
+
+	// FlattenMultiline, when set, is the number of fields of a record:
+	// lines with fewer are joined with the following ones to get them.
+	FlattenMultiline int
+	// ExplodeJSON names a column holding a JSON object that is replaced by
+	// a column per key.
+	ExplodeJSON string
+
+	// Lookups replace column values through translation tables, before the
+	// rules run. LookupMissing, one of the Lookup policies, says what
+	// happens to values a table does not map.
+	Lookups       []lookup
+	LookupMissing string
+
+	// Rules are the -rule conditional transforms, in order.
+	Rules []rule
+
+	// Casts rewrite the values of columns in the canonical form of a type,
+	// after the rules. A float is written with FloatPrecision digits after
+	// the point, or as few as needed when it is negative.
+	Casts          []cast
+	FloatPrecision int
+
+	// Pseudonymize are the columns whose values are replaced by their
+	// HMAC-SHA256 keyed with PseudonymizeSalt, after the casts, written as
+	// PseudonymizeEncoding. RandomSalt is set when the salt was not given.
+	Pseudonymize         []string
+	PseudonymizeSalt     []byte
+	PseudonymizeEncoding string
+	RandomSalt           bool
+
+	// Splits are the -split columns, whose fields are split on SplitSep
+	// into new columns in their place, or after them with SplitKeep.
+	Splits    []splitSpec
+	SplitSep  string
+	SplitKeep bool
+
+	// Merges are the -merge columns appended to every row, joining the
+	// fields of their sources with MergeSep. With MergeDrop the sources are
+	// left out.
+	Merges    []mergeSpec
+	MergeSep  string
+	MergeDrop bool
+
+	// Coalesces are the -coalesce columns appended after the merges, each
+	// taking the first field of its sources that is not empty. With
+	// CoalesceDrop the sources are left out.
+	Coalesces    []mergeSpec
+	CoalesceDrop bool
+
+	// Retab, RetabTrim or RetabAlign, removes the spaces aligning the
+	// columns of a tab separated input, and aligns the -tsv output again
+	// for RetabAlign.
+	Retab string
+
+	// LineNumbers prepends the input line of every record to the rows of
+	// -pretty, -preview and -retab align.
+	LineNumbers bool
+
+	// AbortOnEmpty, EmptyRows or EmptyFile, fails an input without data
+	// rows or without any record.
+	AbortOnEmpty string
+
+	// AddIndex is the name of a column put before every row with its
+	// position among the data rows written, or in the input with
+	// IndexOriginal.
+	AddIndex      string
+	IndexOriginal bool
+
+	// HashColumn is the name of a column appended to every row with a
+	// hash of its HashCols, or of all fields when HashCols is empty.
+	HashColumn string
+	HashCols   []string
+
+	// NBSPReplacement is substituted for every U+00A0 no-break space.
+	NBSPReplacement string
+
+	// Comma is the field delimiter of the input. Zero means ','.
+	Comma rune
+	// QuoteChar is the quote character of the input. Zero means '"'.
+	QuoteChar rune
+	// Encoding is the input encoding, one of the encodings keys or
+	// "auto". Empty means UTF-8.
+	Encoding string
+	Verbose  bool
+
+	// Delimiter separates csv output fields, ',' when empty. Quote is one
+	// of the Quote policies. Empty fields are written as NullToken.
+	Delimiter string
+	Quote     string
+	CRLF      bool
+	BOM       bool
+	NullToken string
+	// EscapeDelimiter, with QuoteNone, is written before delimiters and
+	// itself inside fields.
+	EscapeDelimiter string
+	// PGCopy writes the text format of PostgreSQL's COPY instead of csv.
+	PGCopy bool
+
+	// EscapeControl renders control characters inside fields as escapes.
+	EscapeControl bool
+	// TrimCols and CollapseCols are input columns whose fields are trimmed,
+	// or have runs of white space collapsed, as RemoveSpace does for all.
+	TrimCols     []string
+	CollapseCols []string
+	// Case holds the -upper, -lower and -title columns, whose data fields
+	// change case after they are trimmed and collapsed.
+	Case caseCols
+	// ReplaceRegex are the -replace-regex substitutions, applied to the
+	// data fields of ReplaceRegexCols, or of every column when it is empty.
+	ReplaceRegex     []regexReplace
+	ReplaceRegexCols []string
+	// NoTransformCols are input columns left out of every field transform.
+	NoTransformCols []string
+
+	// PartitionBy names the column whose value picks the file each row is
+	// written to, through partitions, which Run sets up.
+	PartitionBy string
+	partitions  *partitioner
+
+	// pretty, when set by -pretty, keeps the records to write them as an
+	// aligned table at the end.
+	pretty *prettyTable
+
+	// record, when set by -explain-record, keeps the record at one line
+	// to write it vertically at the end.
+	record *recordDump
+
+	// quarantine, when set by -quarantine, receives the raw input of the
+	// records that fail to parse or fail a check, which are then left out.
+	quarantine *quarantine
+
+	// aligned, when set by -retab align, keeps the rows to write them as
+	// TSV with their columns lined up at the end.
+	aligned *tsvAligner
+
+	// schema, when set by -preview-schema, prints the column types
+	// inferred from the first rows written.
+	schema *schemaPreview
+
+	// rules, when set by -rule-summary, counts the data rows checked.
+	rules *ruleSummary
+
+	// errorRows, when set by -errors-csv, receives the records that fail a
+	// check with the problems found in them. They are still written.
+	errorRows *errorRows
+
+	// keyValue, when set by -keyvalue, writes a KEY=VALUE line per row.
+	keyValue *keyValueWriter
+
+	// avro, when set by -avro, encodes the rows as Avro records.
+	avro *avroWriter
+
+	// sorter, when set by -sort, keeps the data rows to write them sorted
+	// at the end.
+	sorter *sorter
+
+	// memory, when set by -max-memory, bounds what -sort, -diff, -pretty
+	// and the other outputs that hold rows keep in memory.
+	memory *memoryLimit
+
+	// diff, when set by -diff, leaves out the rows that another file has
+	// unchanged and adds their status to the others.
+	diff *differ
+
+	// parquet, when set by -parquet, writes the rows to a Parquet file.
+	parquet *parquetWriter
+
+	// yaml, when set by -yaml, writes the rows as YAML mappings.
+	yaml *yamlWriter
+
+	// values, when set by -values, collects the distinct values of a
+	// column instead of writing the rows.
+	values *distinctValues
+
+	// read, when set by -timing, counts the bytes read from the inputs.
+	read *byteCounter
+	// checkpoint, when set by -checkpoint, saves how far the input has
+	// been processed, and skips what a resumed run already did.
+	checkpoint *checkpoint
+
+	// counts, when set by -count-by, tallies the rows by group instead of
+	// writing them.
+	counts *groupCounter
+	// concat, when set by -group-by, keeps a row per group to write them
+	// with the -concat columns joined at the end.
+	concat *groupConcat
+	// collect, when set, is handed every record instead of any output,
+	// to read the other file of -diff and -keys-not-in.
+	collect func(record []string, isHeader bool, line int) error
+	// keys are the -unique-key keys of all the inputs.
+	keys *keySet
+	// index, set by -add-index, numbers the rows of all the inputs.
+	index *rowIndexer
+
+	// types, when set by -ddl, infers the column types instead of writing
+	// the rows.
+	types *typeInference
+
+	// density, when set by -density, counts the values of every row
+	// instead of writing it.
+	density *density
+}
+
+// Orders of the projected columns.
+const (
+	ProjectionList   = "list"
+	ProjectionSource = "source"
+)
+
+// Newline policies for newlines inside fields.
+const (
+	NewlineEscape = "escape"
+	NewlineRemove = "remove"
+	NewlineSpace  = "space"
+	NewlineKeep   = "keep"
+)
+
+// newlinePolicy returns how newlines inside fields are written.
+func (o *Options) newlinePolicy() string {
+	if o.TSV && o.TSVNewline != "" {
+		return o.TSVNewline
+	}
+	if o.FieldNewline != "" {
+		return o.FieldNewline
+	}
+	if o.RemoveNewline {
+		return NewlineRemove
+	}
+	if o.PGCopy {
+		// printPgCopy escapes the newlines the way COPY reads them back
+		return NewlineKeep
+	}
+	return NewlineEscape
+}
+
+// newlineHandling returns how carriage returns and line feeds inside
+// fields are written.
+func (o *Options) newlineHandling() (cr, lf string) {
+	cr, lf = o.CRHandling, o.LFHandling
+	if cr == "" {
+		cr = o.newlinePolicy()
+	}
+	if lf == "" {
+		lf = o.newlinePolicy()
+	}
+	return cr, lf
+}
+
+// Quoting policies for csv output.
+const (
+	QuoteAll     = "all"
+	QuoteMinimal = "minimal"
+	QuoteNone    = "none"
+)
+
+// outputDelimiter returns the separator written between fields.
+func (o *Options) outputDelimiter() string {
+	if o.TSV {
+		return "\t"
+	}
+	if o.Delimiter != "" {
+		return o.Delimiter
+	}
+	return ","
+}
+
+// lineEnding returns the terminator written after every record.
+func (o *Options) lineEnding() string {
+	if o.CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// String describes the effective configuration, with the transforms in the
+// order they are applied to every record.
+func (o *Options) String() string {
+	var b strings.Builder
+	yesNo := func(v bool) string {
+		if v {
+			return "yes"
+		}
+		return "no"
+	}
+	line := func(key string, value interface{}) {
+		fmt.Fprintf(&b, "  %-14s %v\n", key+":", value)
+	}
+
+	b.WriteString("input:\n")
+	encoding := o.Encoding
+	if encoding == "" {
+		encoding = "utf8"
+	}
+	line("encoding", encoding)
+	comma := o.Comma
+	if comma == 0 {
+		comma = ','
+	}
+	line("delimiter", fmt.Sprintf("%q", comma))
+	quote := o.QuoteChar
+	if quote == 0 {
+		quote = '"'
+	}
+	line("quote", fmt.Sprintf("%q", quote))
+	line("header", yesNo(!o.NoHeader))
+	if o.PreserveComments {
+		line("comments", fmt.Sprintf("preserved, written with prefix %q", o.CommentPrefix))
+	} else {
+		line("comments", "read as records")
+	}
+
+	b.WriteString("output:\n")
+	if o.TSV {
+		line("format", "tsv")
+	} else {
+		line("format", "csv")
+		line("delimiter", fmt.Sprintf("%q", o.outputDelimiter()))
+		line("quoting", o.Quote)
+		if o.EscapeDelimiter != "" {
+			line("escape", fmt.Sprintf("%q", o.EscapeDelimiter))
+		}
+	}
+	if o.CRLF {
+		line("line ending", "CRLF")
+	} else {
+		line("line ending", "LF")
+	}
+	line("bom", yesNo(o.BOM))
+	line("null token", fmt.Sprintf("%q", o.NullToken))
+	line("header row", yesNo(!o.SkipHeader && (!o.NoHeader || selectHeader(o.Select) != nil)))
+	if o.PartitionBy != "" {
+		line("partition by", o.PartitionBy)
+	}
+
+	var steps []string
+	if o.FixWhitespaceOnly {
+		steps = append(steps, "empty whitespace-only fields")
+	}
+	if o.StripBOM {
+		steps = append(steps, "remove a UTF-8 byte order mark at the start of every input")
+	}
+	if o.StripInvisible {
+		steps = append(steps, "remove invisible format characters, such as zero-width spaces and joiners and byte order marks")
+	}
+	if o.CleanText {
+		steps = append(steps, "clean text: replace invalid UTF-8 with U+FFFD, normalize to NFC, remove zero-width characters and replace smart quotes, dashes and ellipses with ASCII")
+	}
+	if o.FixSmartChars {
+		steps = append(steps, "replace smart quotes, dashes and ellipses with ASCII")
+	}
+	if o.Pad || len(o.Fill) > 0 {
+		step := "pad short rows to the header width"
+		if len(o.Fill) > 0 {
+			var fills []string
+			for col, v := range o.Fill {
+				fills = append(fills, fmt.Sprintf("%s=%q", col, v))
+			}
+			sort.Strings(fills)
+			step += ", filling " + strings.Join(fills, ", ")
+		}
+		steps = append(steps, step)
+	}
+	if o.PadTo > 0 {
+		step := fmt.Sprintf("pad rows to %d fields", o.PadTo)
+		if o.PadTruncate {
+			step = fmt.Sprintf("pad or cut rows to %d fields", o.PadTo)
+		}
+		steps = append(steps, step)
+	}
+	if o.DedupHeaderRows {
+		steps = append(steps, "drop rows that repeat the header")
+	}
+	if o.Rows != nil {
+		var rows []string
+		for _, r := range o.Rows {
+			if r.first == r.last {
+				rows = append(rows, fmt.Sprint(r.first))
+			} else {
+				rows = append(rows, fmt.Sprintf("%d-%d", r.first, r.last))
+			}
+		}
+		steps = append(steps, "keep data rows "+strings.Join(rows, ","))
+	}
+	if o.FlattenMultiline > 0 {
+		steps = append(steps, fmt.Sprintf("join lines with fewer than %d fields", o.FlattenMultiline))
+	}
+	if o.ExplodeJSON != "" {
+		steps = append(steps, fmt.Sprintf("explode the JSON object in %q", o.ExplodeJSON))
+	}
+	if len(o.Select) > 0 {
+		var cols []string
+		for _, col := range o.Select {
+			cols = append(cols, col.source+" as "+fmt.Sprintf("%q", col.target))
+		}
+		steps = append(steps, "select "+strings.Join(cols, ", "))
+	}
+	if o.ColumnsRegex != nil {
+		steps = append(steps, fmt.Sprintf("select the other columns matching %q", o.ColumnsRegex))
+	}
+	if len(o.Exclude) > 0 {
+		steps = append(steps, "drop the columns "+strings.Join(o.Exclude, ", "))
+	}
+	for _, r := range o.RenameRegex {
+		steps = append(steps, fmt.Sprintf("rename the header names replacing %q with %q", r.re, r.repl))
+	}
+	if o.HeaderCase != "" {
+		steps = append(steps, "write the header names in "+o.HeaderCase+" case")
+	}
+	if o.ProjectionOrder == ProjectionSource && (len(o.Select) > 0 || o.ColumnsRegex != nil) {
+		steps = append(steps, "write the selected columns in input order")
+	}
+	steps = append(steps, fmt.Sprintf("replace no-break spaces with %q", o.NBSPReplacement))
+	if o.RemoveTab {
+		steps = append(steps, "remove tabs")
+	}
+	cr, lf := o.newlineHandling()
+	if cr == lf {
+		switch cr {
+		case NewlineRemove:
+			steps = append(steps, "remove newlines")
+		case NewlineSpace:
+			steps = append(steps, "replace newlines with spaces")
+		case NewlineKeep:
+		default:
+			steps = append(steps, `escape newlines as \n and \r`)
+		}
+	} else {
+		for _, h := range []struct{ name, handling string }{{"carriage returns", cr}, {"line feeds", lf}} {
+			switch h.handling {
+			case NewlineEscape, NewlineRemove:
+				steps = append(steps, h.handling+" "+h.name)
+			case NewlineSpace:
+				steps = append(steps, "replace "+h.name+" with spaces")
+			}
+		}
+	}
+	if o.RemoveSpace {
+		steps = append(steps, "collapse runs of white space and trim")
+	}
+	if o.CleanText && !o.RemoveSpace {
+		steps = append(steps, "trim white space")
+	}
+	if !o.RemoveSpace {
+		if len(o.CollapseCols) > 0 {
+			steps = append(steps, "collapse runs of white space in "+strings.Join(o.CollapseCols, ", "))
+		}
+		if len(o.TrimCols) > 0 {
+			steps = append(steps, "trim "+strings.Join(o.TrimCols, ", "))
+		}
+	}
+	for _, c := range []struct {
+		name string
+		cols []string
+	}{{"upper", o.Case.upper}, {"lower", o.Case.lower}, {"title", o.Case.title}} {
+		if len(c.cols) > 0 {
+			steps = append(steps, c.name+" case "+strings.Join(c.cols, ", "))
+		}
+	}
+	for _, r := range o.ReplaceRegex {
+		step := fmt.Sprintf("replace %q with %q", r.re, r.repl)
+		if len(o.ReplaceRegexCols) > 0 {
+			step += " in " + strings.Join(o.ReplaceRegexCols, ", ")
+		}
+		steps = append(steps, step)
+	}
+	if o.EscapeControl {
+		steps = append(steps, "escape control characters")
+	}
+	for _, l := range o.Lookups {
+		step := fmt.Sprintf("map %s through %s", l.column, l.file)
+		switch o.LookupMissing {
+		case LookupBlank:
+			step += ", emptying unmapped values"
+		case LookupReport:
+			step += ", reporting unmapped values"
+		}
+		steps = append(steps, step)
+	}
+	for _, r := range o.Rules {
+		steps = append(steps, "rule "+r.spec)
+	}
+	for _, c := range o.Casts {
+		step := fmt.Sprintf("cast %s to %s", c.column, c.typ)
+		if c.layout != "" {
+			step += " read as " + c.layout
+		} else if c.typ == CastFloat && o.FloatPrecision >= 0 {
+			step += fmt.Sprintf(" with %d decimals", o.FloatPrecision)
+		}
+		steps = append(steps, step)
+	}
+	if len(o.Pseudonymize) > 0 {
+		salt := "the given salt"
+		if o.RandomSalt {
+			salt = "a random salt"
+		}
+		steps = append(steps, fmt.Sprintf("replace %s with their %s HMAC-SHA256 keyed with %s", strings.Join(o.Pseudonymize, ", "), o.PseudonymizeEncoding, salt))
+	}
+	for _, sp := range o.Splits {
+		step := fmt.Sprintf("split %s on %q into %s", sp.column, o.SplitSep, strings.Join(sp.names, ", "))
+		if o.SplitKeep {
+			step += ", keeping it"
+		}
+		steps = append(steps, step)
+	}
+	for _, m := range o.Merges {
+		step := fmt.Sprintf("append %q joining %s with %q", m.name, strings.Join(m.columns, ", "), o.MergeSep)
+		if o.MergeDrop {
+			step += ", dropping them"
+		}
+		steps = append(steps, step)
+	}
+	for _, m := range o.Coalesces {
+		step := fmt.Sprintf("append %q with the first of %s not empty", m.name, strings.Join(m.columns, ", "))
+		if o.CoalesceDrop {
+			step += ", dropping them"
+		}
+		steps = append(steps, step)
+	}
+	if o.HashColumn != "" {
+		cols := "all columns"
+		if len(o.HashCols) > 0 {
+			cols = strings.Join(o.HashCols, ", ")
+		}
+		steps = append(steps, fmt.Sprintf("append %q with a hash of %s", o.HashColumn, cols))
+	}
+	if o.Retab != "" && o.Comma == '\t' {
+		steps = append(steps, "trim the spaces aligning the tab separated fields")
+	}
+	if o.AddIndex != "" {
+		position := "output"
+		if o.IndexOriginal {
+			position = "input"
+		}
+		steps = append(steps, fmt.Sprintf("prepend %q with the %s position of every row", o.AddIndex, position))
+	}
+	if o.Sample > 0 {
+		steps = append(steps, fmt.Sprintf("sample %d rows with seed %d", o.Sample, o.Seed))
+	}
+	if len(o.NoTransformCols) > 0 {
+		steps = append(steps, "leave "+strings.Join(o.NoTransformCols, ", ")+" out of the steps above")
+	}
+	b.WriteString("transforms:\n")
+	for i, step := range steps {
+		fmt.Fprintf(&b, "  %d. %s\n", i+1, step)
+	}
+
+	var checks []string
+	if o.CheckWhitespaceOnly {
+		checks = append(checks, "whitespace-only fields")
+	}
+	if o.CheckBOM {
+		checks = append(checks, "inputs starting with a UTF-8 byte order mark")
+	}
+	if o.CheckSmartChars {
+		checks = append(checks, "smart quotes, dashes, ellipses and no-break spaces")
+	}
+	if o.FieldHistogram {
+		checks = append(checks, "count rows by number of fields")
+	}
+	if o.AbortOnFieldCountChange {
+		checks = append(checks, "stop when the number of fields changes")
+	}
+	if o.MaxColumns > 0 {
+		checks = append(checks, fmt.Sprintf("at most %d fields per record", o.MaxColumns))
+	}
+	if len(o.RequireColumns) > 0 {
+		checks = append(checks, "required columns "+strings.Join(o.RequireColumns, ", "))
+	}
+	if o.CheckLineEndings {
+		checks = append(checks, "mixed line endings")
+	}
+	if o.Numeric != nil {
+		checks = append(checks, fmt.Sprintf("numbers in the %s locale in %s", o.Numeric.locale, strings.Join(o.Numeric.columns, ", ")))
+	}
+	for _, c := range o.Formats {
+		checks = append(checks, fmt.Sprintf("%s in %s", c.what, strings.Join(c.columns, ", ")))
+	}
+	for _, c := range o.Ranges {
+		check := "range " + c.spec
+		if o.RangeSkipEmpty {
+			check += ", empty values skipped"
+		}
+		checks = append(checks, check)
+	}
+	if len(o.UniqueKey) > 0 {
+		checks = append(checks, "unique key "+strings.Join(o.UniqueKey, ", "))
+	}
+	b.WriteString("checks:\n")
+	if len(checks) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, check := range checks {
+		fmt.Fprintf(&b, "  %s\n", check)
+	}
+	if o.Strict {
+		b.WriteString("  any problem fails the run\n")
+	}
+	return b.String()
+}
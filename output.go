@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// output is the destination records are written to. It optionally
+// compresses the stream and keeps a digest of either the compressed or the
+// uncompressed bytes.
+type output struct {
+	w      io.Writer
+	file   *os.File
+	gz     *gzip.Writer
+	digest *digestWriter
+}
+
+// openOutput creates name, or uses stdout when name is empty. A digest is
+// computed when hash is set, over the bytes written to the destination or,
+// with hashUncompressed, over the bytes before gzip compression.
+func openOutput(stdout io.Writer, name string, gzipOut, hash, hashUncompressed bool) (*output, error) {
+	o := &output{w: stdout}
+	if name != "" {
+		fp, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		o.file = fp
+		o.w = fp
+	}
+
+	if hash && !(gzipOut && hashUncompressed) {
+		o.digest = newDigestWriter(o.w)
+		o.w = o.digest
+	}
+	if gzipOut {
+		o.gz = gzip.NewWriter(o.w)
+		o.w = o.gz
+	}
+	if hash && gzipOut && hashUncompressed {
+		o.digest = newDigestWriter(o.w)
+		o.w = o.digest
+	}
+	return o, nil
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+// Close flushes the compressor and closes the file, if any.
+func (o *output) Close() error {
+	var err error
+	if o.gz != nil {
+		err = o.gz.Close()
+	}
+	if o.file != nil {
+		if cerr := o.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// appendOutput opens name for appending, creating it if needed. A gzip
+// output gets a new gzip member, which readers treat as a continuation.
+func appendOutput(name string, gzipOut bool) (*output, error) {
+	fp, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	o := &output{w: fp, file: fp}
+	if gzipOut {
+		o.gz = gzip.NewWriter(fp)
+		o.w = o.gz
+	}
+	return o, nil
+}
+
+// noTrailingNewline writes everything to w except a line ending at the
+// very end, for -no-trailing-newline. A line ending is held back until more
+// output follows it, and dropped if none does.
+type noTrailingNewline struct {
+	w       io.WriteCloser
+	ending  []byte
+	pending []byte
+}
+
+func (n *noTrailingNewline) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	data := append(n.pending, p...)
+	hold := 0
+	if bytes.HasSuffix(data, n.ending) {
+		hold = len(n.ending)
+	} else if last := data[len(data)-1]; len(n.ending) > 1 && last == n.ending[0] {
+		// The start of a CRLF that continues in the next write.
+		hold = 1
+	}
+	if _, err := n.w.Write(data[:len(data)-hold]); err != nil {
+		return 0, err
+	}
+	n.pending = append([]byte(nil), data[len(data)-hold:]...)
+	return len(p), nil
+}
+
+// Close drops a held back line ending and closes w.
+func (n *noTrailingNewline) Close() error {
+	if len(n.pending) > 0 && !bytes.Equal(n.pending, n.ending) {
+		if _, err := n.w.Write(n.pending); err != nil {
+			return err
+		}
+	}
+	n.pending = nil
+	return n.w.Close()
+}
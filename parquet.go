@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/encoding"
+)
+
+// errParquetValue is returned by transform when, with -strict, a value does
+// not fit the type of its -parquet-schema column. The value has already
+// been reported.
+var errParquetValue = errors.New("value does not fit the parquet schema")
+
+// parquetColumn is a column of the Parquet file, of one of the inferred
+// types.
+type parquetColumn struct {
+	name string
+	typ  string
+}
+
+// parquetWriter writes rows as a Parquet file. With a schema, rows are
+// converted as they come and written a row group at a time; without one,
+// every row is kept until the input is done and the column types are
+// inferred from them.
+type parquetWriter struct {
+	columns []parquetColumn
+	index   []int
+	infer   *typeInference
+	kept    [][]string
+	// origins are the input and line of every kept row
+	origins  []rowOrigin
+	null     string
+	rowGroup int
+	memory   *memoryLimit
+
+	w       *parquet.Writer
+	pending []parquet.Row
+	skipped int
+}
+
+func newParquetWriter(schemaFile string, rowGroup int, null string) (*parquetWriter, error) {
+	p := &parquetWriter{rowGroup: rowGroup, null: null}
+	if schemaFile == "" {
+		p.infer = new(typeInference)
+		return p, nil
+	}
+	var err error
+	if p.columns, err = loadParquetSchema(schemaFile); err != nil {
+		return nil, err
+	}
+	for i := range p.columns {
+		p.index = append(p.index, i)
+	}
+	return p, nil
+}
+
+// loadParquetSchema reads a -parquet-schema file: a column name and one of
+// the -ddl types per line. Blank lines and lines starting with '#' are
+// ignored.
+func loadParquetSchema(name string) ([]parquetColumn, error) {
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var columns []parquetColumn
+	seen := map[string]bool{}
+	s := bufio.NewScanner(fp)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexAny(line, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected a column name and a type", name, n)
+		}
+		col := parquetColumn{name: strings.TrimSpace(line[:i]), typ: strings.ToUpper(line[i+1:])}
+		if _, ok := parquetTypes[col.typ]; !ok {
+			return nil, fmt.Errorf("%s:%d: unknown type %q: must be one of %s", name, n, line[i+1:], strings.Join(inferredTypes, ", "))
+		}
+		if seen[col.name] {
+			return nil, fmt.Errorf("%s:%d: duplicate column %q", name, n, col.name)
+		}
+		seen[col.name] = true
+		columns = append(columns, col)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%s: the schema has no columns", name)
+	}
+	return columns, nil
+}
+
+// parquetTypes maps the inferred types to Parquet logical types.
+var parquetTypes = map[string]parquet.Node{
+	TypeInteger: parquet.Int(64),
+	TypeNumeric: parquet.Leaf(parquet.DoubleType),
+	TypeBoolean: parquet.Leaf(parquet.BooleanType),
+	TypeDate:    parquet.Date(),
+	TypeText:    parquet.String(),
+}
+
+// start creates the writer for the schema. Without -parquet-schema it is
+// called once the types are inferred.
+func (p *parquetWriter) start(w io.Writer) {
+	p.w = parquet.NewWriter(w,
+		parquet.NewSchema("csv", parquetGroup(p.columns)),
+		parquet.Compression(&parquet.Snappy),
+		parquet.MaxRowsPerRowGroup(int64(p.rowGroup)),
+		parquet.CreatedBy(Name, Version, ""),
+	)
+}
+
+// add converts a row, or keeps it for later when the types are inferred. A
+// value that does not fit its column is reported and the row skipped, or
+// with strict the input stopped.
+func (p *parquetWriter) add(name string, record []string, isHeader bool, line int, diag *diagnostics, strict bool) error {
+	if p.infer != nil {
+		if isHeader {
+			for i, v := range record {
+				for _, prev := range record[:i] {
+					if v == prev {
+						return fmt.Errorf("duplicate column %q cannot be written to parquet", v)
+					}
+				}
+			}
+		}
+		p.infer.add(p.nullify(record), isHeader)
+		if !isHeader {
+			p.kept = append(p.kept, record)
+			p.origins = append(p.origins, rowOrigin{name, line})
+			return p.memory.grow("-parquet without -parquet-schema", recordSize(record))
+		}
+		return nil
+	}
+	if isHeader {
+		index := headerIndex(record)
+		for i, c := range p.columns {
+			n, ok := index[c.name]
+			if !ok {
+				return fmt.Errorf("parquet column %q is not a column", c.name)
+			}
+			p.index[i] = n
+		}
+		return nil
+	}
+
+	row, col, ok := p.convert(record)
+	if !ok {
+		c := p.columns[col]
+		diag.report(Diagnostic{File: name, Line: line, Column: p.index[col] + 1, Rule: "parquet", Message: fmt.Sprintf("%s: %q is not a valid %s", c.name, record[p.index[col]], c.typ)})
+		if strict {
+			return errParquetValue
+		}
+		p.skipped++
+		return nil
+	}
+	p.pending = append(p.pending, row)
+	if len(p.pending) == p.rowGroup {
+		return p.writePending()
+	}
+	return nil
+}
+
+// nullify returns record with the values that stand for null emptied, so
+// they do not take part in the type inference.
+func (p *parquetWriter) nullify(record []string) []string {
+	if p.null == "" {
+		return record
+	}
+	out := make([]string, len(record))
+	for i, v := range record {
+		if v != p.null {
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// convert turns record into a Parquet row. On failure it returns the
+// index of the column whose value does not fit.
+func (p *parquetWriter) convert(record []string) (parquet.Row, int, bool) {
+	row := make(parquet.Row, len(p.columns))
+	for i, c := range p.columns {
+		v := ""
+		if p.index[i] < len(record) {
+			v = record[p.index[i]]
+		}
+		if v == "" || v == p.null {
+			row[i] = parquet.NullValue().Level(0, 0, i)
+			continue
+		}
+		value, ok := parquetValue(v, c.typ)
+		if !ok {
+			return nil, i, false
+		}
+		row[i] = value.Level(0, 1, i)
+	}
+	return row, 0, true
+}
+
+// parquetValue converts v, which is not empty, to typ.
+func parquetValue(v, typ string) (parquet.Value, bool) {
+	if !fitsType(v, typ) {
+		return parquet.Value{}, false
+	}
+	switch typ {
+	case TypeInteger:
+		n, _ := parseInt(v)
+		return parquet.Int64Value(n), true
+	case TypeNumeric:
+		// out of range values are ±Inf, as the inferred type allows them
+		x, _ := strconv.ParseFloat(v, 64)
+		return parquet.DoubleValue(x), true
+	case TypeBoolean:
+		b, _ := parseBool(v)
+		return parquet.BooleanValue(b), true
+	case TypeDate:
+		d, _ := parseDate(v, ddlDateLayouts)
+		return parquet.Int32Value(int32(d.Unix() / 86400)), true
+	}
+	return parquet.ByteArrayValue([]byte(v)), true
+}
+
+func (p *parquetWriter) writePending() error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	_, err := p.w.WriteRows(p.pending)
+	p.pending = p.pending[:0]
+	if err != nil {
+		return err
+	}
+	// Flush ends the row group, so it is never held for longer than
+	// -parquet-row-group rows.
+	return p.w.Flush()
+}
+
+// rowOrigin is where a kept row was read.
+type rowOrigin struct {
+	name string
+	line int
+}
+
+// finish writes the rows still pending, or all of them once their types
+// are inferred, and the file footer to w. A kept value that does not fit
+// its inferred type is reported and fails the output, as no row may be
+// left out of it silently.
+func (p *parquetWriter) finish(w io.Writer, diag *diagnostics) error {
+	if p.infer != nil {
+		names, types := p.infer.columns()
+		for i := range names {
+			p.columns = append(p.columns, parquetColumn{name: names[i], typ: types[i]})
+			p.index = append(p.index, i)
+		}
+		if len(p.columns) == 0 {
+			return errors.New("no columns to write to parquet")
+		}
+		p.start(w)
+		for r, record := range p.kept {
+			row, col, ok := p.convert(record)
+			if !ok {
+				c := p.columns[col]
+				diag.report(Diagnostic{File: p.origins[r].name, Line: p.origins[r].line, Column: col + 1, Rule: "parquet", Message: fmt.Sprintf("%s: %q is not a valid %s", c.name, record[col], c.typ)})
+				return errParquetValue
+			}
+			p.pending = append(p.pending, row)
+			if len(p.pending) == p.rowGroup {
+				if err := p.writePending(); err != nil {
+					return err
+				}
+			}
+		}
+		p.kept, p.origins = nil, nil
+	}
+	if err := p.writePending(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
+
+// parquetGroup is the root of the schema. Unlike parquet.Group, it keeps
+// the columns in their order in the input, and all of them are optional.
+type parquetGroup []parquetColumn
+
+func (g parquetGroup) ID() int                     { return 0 }
+func (g parquetGroup) String() string              { return fmt.Sprint(g.Fields()) }
+func (g parquetGroup) Type() parquet.Type          { return parquet.Group{}.Type() }
+func (g parquetGroup) Optional() bool              { return false }
+func (g parquetGroup) Repeated() bool              { return false }
+func (g parquetGroup) Required() bool              { return true }
+func (g parquetGroup) Leaf() bool                  { return false }
+func (g parquetGroup) Encoding() encoding.Encoding { return nil }
+func (g parquetGroup) Compression() compress.Codec { return nil }
+func (g parquetGroup) GoType() reflect.Type        { return reflect.TypeOf(map[string]interface{}{}) }
+func (g parquetGroup) Fields() []parquet.Field {
+	fields := make([]parquet.Field, len(g))
+	for i, c := range g {
+		fields[i] = parquetField{Node: parquet.Optional(parquetTypes[c.typ]), name: c.name}
+	}
+	return fields
+}
+
+type parquetField struct {
+	parquet.Node
+	name string
+}
+
+func (f parquetField) Name() string { return f.name }
+
+func (f parquetField) Value(base reflect.Value) reflect.Value {
+	return base.MapIndex(reflect.ValueOf(f.name))
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// readParquet returns the column names and types, the number of row groups
+// and the rows of a Parquet file.
+func readParquet(t *testing.T, name string) (columns []string, groups int, rows [][]interface{}) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range f.Schema().Fields() {
+		columns = append(columns, field.Name()+" "+field.Type().String())
+	}
+	r := parquet.NewReader(f)
+	buf := make([]parquet.Row, 10)
+	for {
+		n, err := r.ReadRows(buf)
+		for _, row := range buf[:n] {
+			var values []interface{}
+			for _, v := range row {
+				switch {
+				case v.IsNull():
+					values = append(values, nil)
+				case v.Kind() == parquet.ByteArray:
+					values = append(values, string(v.ByteArray()))
+				case v.Kind() == parquet.Int64:
+					values = append(values, v.Int64())
+				case v.Kind() == parquet.Int32:
+					values = append(values, v.Int32())
+				case v.Kind() == parquet.Double:
+					values = append(values, v.Double())
+				case v.Kind() == parquet.Boolean:
+					values = append(values, v.Boolean())
+				}
+			}
+			rows = append(rows, values)
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return columns, len(f.RowGroups()), rows
+}
+
+func TestRun_parquetFlag(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.txt")
+	if err := os.WriteFile(schema, []byte("# columns\nid integer\nfull name TEXT\nscore NUMERIC\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := "score,id,full name,day,ok\n9.5,1,Ada,2024-01-02,true\nNA,2,Bob,,FALSE\n1.5,x,Cy,2024-01-03,true\n"
+
+	cases := []struct {
+		args     []string
+		columns  []string
+		groups   int
+		rows     [][]interface{}
+		errs     string
+		exitCode int
+	}{
+		{
+			args:    []string{"-null-token", "NA", "-parquet-row-group", "2"},
+			columns: []string{"score DOUBLE", "id STRING", "full name STRING", "day DATE", "ok BOOLEAN"},
+			groups:  2,
+			rows: [][]interface{}{
+				{9.5, "1", "Ada", int32(19724), true},
+				{nil, "2", "Bob", nil, false},
+				{1.5, "x", "Cy", int32(19725), true},
+			},
+		},
+		{
+			args:    []string{"-parquet-schema", schema, "-null-token", "NA"},
+			columns: []string{"id INT(64,true)", "full name STRING", "score DOUBLE"},
+			groups:  1,
+			rows: [][]interface{}{
+				{int64(1), "Ada", 9.5},
+				{int64(2), "Bob", nil},
+			},
+			errs: "line 4 column 2: id: \"x\" is not a valid INTEGER\nskipped rows: 1\n",
+		},
+		{
+			args:     []string{"-parquet-schema", schema, "-strict"},
+			errs:     "line 3 column 1: score: \"NA\" is not a valid NUMERIC\n",
+			exitCode: ExitCodeError,
+		},
+	}
+
+	for i, c := range cases {
+		out := filepath.Join(dir, "out.parquet")
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+		args := append([]string{"./csvlint", "-parquet", out}, c.args...)
+		if status := cli.Run(args); status != c.exitCode {
+			t.Fatalf("%d: expected %d to eq %d: %s", i, status, c.exitCode, errStream)
+		}
+		if errStream.String() != c.errs {
+			t.Errorf("%d: expected %q to eq %q", i, errStream.String(), c.errs)
+		}
+		if outStream.Len() != 0 {
+			t.Errorf("%d: expected no output, got %q", i, outStream.String())
+		}
+		if c.exitCode != ExitCodeOK {
+			continue
+		}
+		columns, groups, rows := readParquet(t, out)
+		if !reflect.DeepEqual(columns, c.columns) {
+			t.Errorf("%d: expected %q to eq %q", i, columns, c.columns)
+		}
+		if groups != c.groups {
+			t.Errorf("%d: expected %d row groups to eq %d", i, groups, c.groups)
+		}
+		if !reflect.DeepEqual(rows, c.rows) {
+			t.Errorf("%d: expected %v to eq %v", i, rows, c.rows)
+		}
+	}
+}
+
+func TestRun_parquetFlagErrors(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.txt")
+	if err := os.WriteFile(schema, []byte("id BIGINT\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.parquet")
+
+	cases := []struct {
+		args     []string
+		input    string
+		expected string
+	}{
+		{[]string{"-parquet", out, "-tsv"}, "a\n1\n", "-parquet cannot be combined with other output formats, -lint, -output, -gzip-out, -manifest, -verify, -partition-by, -split-rows, -split-bytes, -file-workers, -check-idempotent, -sample or -no-trailing-newline\n"},
+		{[]string{"-parquet-schema", schema}, "a\n1\n", "-parquet-schema and -parquet-row-group need -parquet\n"},
+		{[]string{"-parquet", out, "-parquet-row-group", "0"}, "a\n1\n", "-parquet-row-group must be at least 1\n"},
+		{[]string{"-parquet", out, "-parquet-schema", schema}, "a\n1\n", schema + ":1: unknown type \"BIGINT\": must be one of INTEGER, NUMERIC, BOOLEAN, DATE, TEXT\n"},
+		{[]string{"-parquet", out}, "a,a\n1,2\n", "duplicate column \"a\" cannot be written to parquet\n"},
+	}
+
+	for i, c := range cases {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(c.input), outStream: new(bytes.Buffer), errStream: errStream}
+		if status := cli.Run(append([]string{"./csvlint"}, c.args...)); status != ExitCodeError {
+			t.Errorf("%d: expected %d to eq %d", i, status, ExitCodeError)
+		}
+		if errStream.String() != c.expected {
+			t.Errorf("%d: expected %q to eq %q", i, errStream.String(), c.expected)
+		}
+	}
+}
+
+func TestParquetWriter_finishMismatch(t *testing.T) {
+	p, err := newParquetWriter("", 100, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errStream := new(bytes.Buffer)
+	diag, err := newDiagnostics(errStream, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.add("in.csv", []string{"a"}, true, 1, diag, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.add("in.csv", []string{"10"}, false, 2, diag, false); err != nil {
+		t.Fatal(err)
+	}
+	// a row the inference did not see
+	p.kept = append(p.kept, []string{"x"})
+	p.origins = append(p.origins, rowOrigin{"in.csv", 3})
+
+	if err := p.finish(new(bytes.Buffer), diag); err != errParquetValue {
+		t.Errorf("expected %v to eq %v", err, errParquetValue)
+	}
+	diag.flush()
+	expected := "in.csv: line 3 column 1: a: \"x\" is not a valid INTEGER\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// partitioner writes every row to a file named after the value of its
+// partition column. Only maxOpen files are kept open; the least recently
+// used one is closed to make room and reopened for appending when its
+// partition comes up again.
+type partitioner struct {
+	base    string
+	gzipOut bool
+	maxOpen int
+	bom     bool
+
+	header []byte
+	lru    *list.List               // of *partition, most recent first
+	open   map[string]*list.Element // by file name
+	rows   map[string]int           // by file name
+	names  []string                 // file names in first-seen order
+}
+
+type partition struct {
+	name string
+	out  *output
+}
+
+func newPartitioner(base string, gzipOut bool, maxOpen int) *partitioner {
+	return &partitioner{
+		base:    base,
+		gzipOut: gzipOut,
+		maxOpen: maxOpen,
+		lru:     list.New(),
+		open:    map[string]*list.Element{},
+		rows:    map[string]int{},
+	}
+}
+
+// partitionTag makes value safe to use in a file name. Values that had to
+// be changed get a short hash so that they cannot collide with each other.
+func partitionTag(value string) string {
+	tag := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, value)
+	if tag == value && tag != "" {
+		return tag
+	}
+	sum := sha256.Sum256([]byte(value))
+	return tag + "-" + hex.EncodeToString(sum[:4])
+}
+
+// setHeader sets the line written at the top of every new partition file.
+func (p *partitioner) setHeader(line []byte) {
+	p.header = append([]byte{}, line...)
+}
+
+// write appends line to the partition of value.
+func (p *partitioner) write(value string, line []byte) error {
+	name := siblingName(p.base, partitionTag(value))
+
+	var part *partition
+	if e, ok := p.open[name]; ok {
+		p.lru.MoveToFront(e)
+		part = e.Value.(*partition)
+	} else {
+		if p.lru.Len() >= p.maxOpen {
+			if err := p.evict(); err != nil {
+				return err
+			}
+		}
+
+		_, seen := p.rows[name]
+		var out *output
+		var err error
+		if seen {
+			out, err = appendOutput(name, p.gzipOut)
+		} else {
+			out, err = openOutput(nil, name, p.gzipOut, false, false)
+		}
+		if err != nil {
+			return err
+		}
+		if !seen {
+			p.names = append(p.names, name)
+			p.rows[name] = 0
+			if p.bom {
+				if _, err := out.Write([]byte("\uFEFF")); err != nil {
+					return err
+				}
+			}
+			if p.header != nil {
+				if _, err := out.Write(p.header); err != nil {
+					return err
+				}
+			}
+		}
+
+		part = &partition{name: name, out: out}
+		p.open[name] = p.lru.PushFront(part)
+	}
+
+	if _, err := part.out.Write(line); err != nil {
+		return err
+	}
+	p.rows[name]++
+	return nil
+}
+
+// evict closes the least recently used partition file.
+func (p *partitioner) evict() error {
+	e := p.lru.Back()
+	part := p.lru.Remove(e).(*partition)
+	delete(p.open, part.name)
+	return part.out.Close()
+}
+
+// Close closes every open partition file.
+func (p *partitioner) Close() error {
+	var err error
+	for p.lru.Len() > 0 {
+		if cerr := p.evict(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
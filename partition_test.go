@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_partitionByFlag(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "events.csv")
+	inStream := strings.NewReader("day,n\n2016-01-01,1\n2016-01-02,2\n2016-01-01,3\n../x,4\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal", "-partition-by", "day", "-partition-max-open", "1", "-o", out}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := map[string]string{
+		"2016-01-01": "day,n\n2016-01-01,1\n2016-01-01,3\n",
+		"2016-01-02": "day,n\n2016-01-02,2\n",
+		"../x":       "day,n\n../x,4\n",
+	}
+	for value, e := range expected {
+		name := siblingName(out, partitionTag(value))
+		if filepath.Dir(name) != filepath.Dir(out) {
+			t.Errorf("partition %q escaped the output directory: %s", value, name)
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != e {
+			t.Errorf("partition %q: expected %q to eq %q", value, b, e)
+		}
+	}
+
+	if outStream.Len() != 0 {
+		t.Errorf("expected nothing on stdout, got %q", outStream.String())
+	}
+	summary := "rows in " + siblingName(out, "2016-01-01") + ": 2"
+	if !strings.Contains(errStream.String(), summary) {
+		t.Errorf("expected %q to contain %q", errStream.String(), summary)
+	}
+}
+
+func TestPartitionTag(t *testing.T) {
+	if tag := partitionTag("tenant-1"); tag != "tenant-1" {
+		t.Errorf("expected a safe value to be kept, got %q", tag)
+	}
+	if partitionTag("a/b") == partitionTag("a_b") {
+		t.Error("expected sanitized values not to collide")
+	}
+	if tag := partitionTag(""); tag == "" {
+		t.Error("expected a tag for an empty value")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"strings"
+)
+
+// pgNull is how the text format of PostgreSQL's COPY writes a NULL.
+const pgNull = `\N`
+
+// printPgCopy writes row in the text format of COPY, for -pgcopy: fields
+// are never quoted, and backslashes, tabs, newlines and the delimiter
+// inside them are escaped with a backslash. Empty fields, and those equal
+// to the null token, are written as \N.
+func printPgCopy(w io.Writer, row []string, opts *Options) error {
+	delim := opts.outputDelimiter()
+	args := []string{`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`}
+	if delim != "\t" {
+		args = append(args, delim, `\`+delim)
+	}
+	r := strings.NewReplacer(args...)
+
+	sep := ""
+	for _, cell := range row {
+		if cell == "" || opts.NullToken != "" && cell == opts.NullToken {
+			cell = pgNull
+		} else {
+			cell = r.Replace(cell)
+		}
+		if _, err := io.WriteString(w, sep+cell); err != nil {
+			return err
+		}
+		sep = delim
+	}
+	_, err := io.WriteString(w, opts.lineEnding())
+	return err
+}
+
+// validPgDelimiter tells whether COPY accepts d as a delimiter: a single
+// ASCII character that its escapes and line endings do not use.
+func validPgDelimiter(d string) bool {
+	if len(d) != 1 || d[0] >= 0x80 {
+		return false
+	}
+	return !strings.ContainsAny(d, "\\\r\n.abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_pgcopyFlag(t *testing.T) {
+	input := "id,path,note\n1,C:\\tmp,\"two\nlines\"\n2,,a\tb\n3,NULL,\\N\n"
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"./csvlint", "-pgcopy"}, "1\tC:\\\\tmp\ttwo\\nlines\n2\t\\N\ta\\tb\n3\tNULL\t\\\\N\n"},
+		{[]string{"./csvlint", "-pgcopy", "-null-token", "NULL", "-output-delimiter", "|", "-field-newline", "space"}, "1|C:\\\\tmp|two lines\n2|\\N|a\\tb\n3|\\N|\\\\N\n"},
+		{[]string{"./csvlint", "-pgcopy", "-output-delimiter", ":"}, "1:C\\:\\\\tmp:two\\nlines\n2:\\N:a\\tb\n3:NULL:\\\\N\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(tt.args); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -pgcopy -tsv", "-pgcopy cannot be combined with other output formats, -preset, -quote, -escape-delimiter or -check-idempotent\n"},
+		{"./csvlint -pgcopy -quote none", "-pgcopy cannot be combined with other output formats, -preset, -quote, -escape-delimiter or -check-idempotent\n"},
+		{"./csvlint -pgcopy -output-delimiter ::", "invalid -output-delimiter \"::\" for -pgcopy: must be a single ASCII character other than a letter, a digit, a backslash, a period or a line ending\n"},
+	} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+// The header binds the columns of -sort, -group-by and -diff, and is still
+// left out of the COPY rows.
+func TestRun_pgcopyFlagBindsHeader(t *testing.T) {
+	files := writeFiles(t, "k,v\n1,a\n2,b\n", "k,v\n2,b\n1,x\n3,c\n")
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-pgcopy", "-sort", "k", files[1]}, "1\tx\n2\tb\n3\tc\n"},
+		{[]string{"-pgcopy", "-group-by", "k", "-concat", "v", files[1]}, "2\tb\n1\tx\n3\tc\n"},
+		{[]string{"-pgcopy", "-diff", files[0], "-key", "k", files[1]}, "1\tx\tchanged\n3\tc\tadded\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(append([]string{"./csvlint"}, tt.args...)); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
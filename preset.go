@@ -0,0 +1,31 @@
+package main
+
+import "fmt"
+
+// presets bundle the output settings that suit a particular consumer.
+var presets = map[string]Options{
+	// Excel only recognizes UTF-8 with a byte order mark, and is happiest
+	// with every field quoted and Windows line endings.
+	"excel": {Delimiter: ",", Quote: QuoteAll, CRLF: true, BOM: true},
+	// Minimal quoting keeps diffs readable; git prefers LF and no BOM.
+	"git": {Delimiter: ",", Quote: QuoteMinimal},
+	// For COPY ... WITH (FORMAT csv, NULL '\N'): empty fields are written
+	// as \N, so that a NULL does not depend on how a field is quoted, and
+	// COPY reads a field that is \N itself, which is quoted, as text.
+	"postgres": {Delimiter: ",", Quote: QuoteMinimal, NullToken: `\N`},
+}
+
+// applyPreset overwrites the output settings of opts with the named preset.
+func applyPreset(opts *Options, name string) error {
+	p, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q", name)
+	}
+	opts.TSV = false
+	opts.Delimiter = p.Delimiter
+	opts.Quote = p.Quote
+	opts.CRLF = p.CRLF
+	opts.BOM = p.BOM
+	opts.NullToken = p.NullToken
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+	"golang.org/x/term"
+)
+
+// prettyTable keeps the records for -pretty and writes them as a table
+// with aligned columns. It has to hold the whole output to size them.
+type prettyTable struct {
+	mu    sync.Mutex
+	rows  [][]string
+	limit int
+	// wrap, when set, is the widest a column gets: longer fields are
+	// wrapped onto more lines instead.
+	wrap   int
+	memory *memoryLimit
+}
+
+// numberLine prepends line to record for -line-numbers, as the first
+// cell. Line 0, for a header that was not read from the input, leaves the
+// cell empty.
+func numberLine(record []string, line int) []string {
+	cell := ""
+	if line > 0 {
+		cell = strconv.Itoa(line)
+	}
+	return append([]string{cell}, record...)
+}
+
+// more is shown in place of the columns left out to fit the width limit.
+const more = "…"
+
+// terminalWidth returns the width of w if it is a terminal, or 0. It is
+// replaced in tests.
+var terminalWidth = func(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(int(f.Fd())) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+func (p *prettyTable) add(record []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rows = append(p.rows, append([]string(nil), record...))
+	return p.memory.grow("-pretty", recordSize(record))
+}
+
+// write prints the table. When the rows are wider than limit, the
+// trailing columns that do not fit are replaced by a single "…" column,
+// and a first column too wide on its own is cut.
+func (p *prettyTable) write(w io.Writer, opts *Options) error {
+	if p.wrap > 0 {
+		return p.writeWrapped(w, opts)
+	}
+	var widths []int
+	for _, row := range p.rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := runewidth.StringWidth(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	shown, truncated := len(widths), false
+	if p.limit > 0 && tableWidth(widths) > p.limit {
+		truncated = true
+		room := p.limit - 2 - runewidth.StringWidth(more)
+		for shown > 0 && tableWidth(widths[:shown]) > room {
+			shown--
+		}
+		if shown == 0 {
+			// Keep the first column, cut to what is left.
+			shown = 1
+			widths[0] = room
+			if widths[0] < 1 {
+				widths[0] = 1
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range p.rows {
+		b.Reset()
+		for i := 0; i < shown; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(runewidth.FillRight(runewidth.Truncate(cell, widths[i], more), widths[i]))
+		}
+		if truncated {
+			b.WriteString("  " + more)
+		}
+		line := strings.TrimRight(b.String(), " ") + opts.lineEnding()
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWrapped prints the table with the fields wider than wrap split
+// over several lines, each row taking as many lines as its longest field.
+func (p *prettyTable) writeWrapped(w io.Writer, opts *Options) error {
+	rows := make([][][]string, len(p.rows))
+	var widths []int
+	for r, row := range p.rows {
+		rows[r] = make([][]string, len(row))
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			rows[r][i] = wrapCell(cell, p.wrap)
+			for _, line := range rows[r][i] {
+				if n := runewidth.StringWidth(line); n > widths[i] {
+					widths[i] = n
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		height := 1
+		for _, lines := range row {
+			if len(lines) > height {
+				height = len(lines)
+			}
+		}
+		for j := 0; j < height; j++ {
+			b.Reset()
+			for i := range widths {
+				cell := ""
+				if i < len(row) && j < len(row[i]) {
+					cell = row[i][j]
+				}
+				if i > 0 {
+					b.WriteString("  ")
+				}
+				b.WriteString(runewidth.FillRight(cell, widths[i]))
+			}
+			line := strings.TrimRight(b.String(), " ") + opts.lineEnding()
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wrapCell splits cell into lines no wider than width. It breaks at
+// spaces and newlines, and between grapheme clusters when a word is wider
+// than a line on its own.
+func wrapCell(cell string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(cell, "\n") {
+		line := ""
+		for i, word := range strings.Split(para, " ") {
+			if i > 0 && runewidth.StringWidth(line+" "+word) <= width {
+				line += " " + word
+				continue
+			}
+			if i > 0 {
+				lines = append(lines, line)
+			}
+			for runewidth.StringWidth(word) > width {
+				head := graphemePrefix(word, width)
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// graphemePrefix returns the longest run of whole grapheme clusters at the
+// start of s that fits in width, and at least one.
+func graphemePrefix(s string, width int) string {
+	g := uniseg.NewGraphemes(s)
+	end, used := 0, 0
+	for g.Next() {
+		n := runewidth.StringWidth(g.Str())
+		if end > 0 && used+n > width {
+			break
+		}
+		_, end = g.Positions()
+		used += n
+	}
+	return s[:end]
+}
+
+// tableWidth is the width of columns of the given widths separated by two
+// spaces.
+func tableWidth(widths []int) int {
+	total := 0
+	for i, n := range widths {
+		if i > 0 {
+			total += 2
+		}
+		total += n
+	}
+	return total
+}
+
+// previewValue is the number of rows -preview shows. It can be given
+// without a value, as -preview, for the default.
+type previewValue int
+
+const defaultPreviewRows = 10
+
+func (p *previewValue) String() string {
+	return strconv.Itoa(int(*p))
+}
+
+func (p *previewValue) Set(v string) error {
+	if v == "true" {
+		*p = defaultPreviewRows
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return fmt.Errorf("expected a number of rows, got %q", v)
+	}
+	*p = previewValue(n)
+	return nil
+}
+
+func (p *previewValue) IsBoolFlag() bool { return true }
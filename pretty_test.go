@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRun_prettyFlag(t *testing.T) {
+	input := "id,name,city,note\n1,山田太郎,東京,first\n22,Bob,Paris,\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{
+			"./csvlint -pretty -limit-width 0",
+			"" +
+				"id  name      city   note\n" +
+				"1   山田太郎  東京   first\n" +
+				"22  Bob       Paris\n",
+		},
+		{
+			"./csvlint -pretty -limit-width 20",
+			"" +
+				"id  name      …\n" +
+				"1   山田太郎  …\n" +
+				"22  Bob       …\n",
+		},
+		{
+			"./csvlint -pretty -limit-width 5 -select name",
+			"" +
+				"n…  …\n" +
+				"…   …\n" +
+				"B…  …\n",
+		},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_prettyFlag_terminalWidth(t *testing.T) {
+	defer func(f func(w io.Writer) int) { terminalWidth = f }(terminalWidth)
+	terminalWidth = func(io.Writer) int { return 6 }
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader("a,b,c\n1,2,3\n"), outStream: outStream, errStream: errStream}
+
+	status := cli.Run(strings.Split("./csvlint -pretty", " "))
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	expected := "a  …\n1  …\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_previewFlag(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,name\n")
+	for i := 1; i <= 20; i++ {
+		input.WriteString(strconv.Itoa(i) + ",n" + strconv.Itoa(i) + "\n")
+	}
+	// an unparseable row past the preview is never read
+	input.WriteString("21,\"unterminated\n")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-preview=2"}, "id  name\n1   n1\n2   n2\n"},
+		{[]string{"-preview=2", "-limit-width", "6"}, "id  …\n1   …\n2   …\n"},
+		{[]string{"-preview", "-select", "name"}, "name\nn1\nn2\nn3\nn4\nn5\nn6\nn7\nn8\nn9\nn10\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input.String()), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(append([]string{"./csvlint", "-strict"}, tt.args...)); status != ExitCodeOK {
+			t.Errorf("%v: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.Len() != 0 {
+			t.Errorf("%v: expected no output, got %q", tt.args, outStream.String())
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%v: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+
+	errStream := new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input.String()), outStream: new(bytes.Buffer), errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-preview=0"}); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+}
+
+func TestRun_wrapFlag(t *testing.T) {
+	input := "id,note,city\n1,the quick brown fox,東京都千代田区\n22,short,Paris\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{
+			"./csvlint -pretty -wrap 10",
+			"" +
+				"id  note       city\n" +
+				"1   the quick  東京都千代\n" +
+				"    brown fox  田区\n" +
+				"22  short      Paris\n",
+		},
+		{
+			"./csvlint -pretty -wrap 3 -select id,note",
+			"" +
+				"id  not\n" +
+				"    e\n" +
+				"1   the\n" +
+				"    qui\n" +
+				"    ck\n" +
+				"    bro\n" +
+				"    wn\n" +
+				"    fox\n" +
+				"22  sho\n" +
+				"    rt\n",
+		},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_wrapFlagErrors(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -pretty -wrap 10 -limit-width 20", "-wrap cannot be combined with -limit-width\n"},
+		{"./csvlint -wrap 10", "-wrap needs -pretty or -preview\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("a\n1\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_lineNumbersFlag(t *testing.T) {
+	input := "id,note\n1,\"two\nlines\"\n2,b\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -pretty -limit-width 0 -line-numbers -field-newline space", "1  id  note\n2  1   two lines\n4  2   b\n"},
+		{"./csvlint -tsv -retab align -line-numbers -field-newline space", "1\tid\tnote\n2\t1 \ttwo lines\n4\t2 \tb\n"},
+		{"./csvlint -pretty -limit-width 0 -line-numbers -no-header -select 2:x -field-newline space", "   x\n1  note\n2  two lines\n4  b\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -line-numbers", "-line-numbers needs -pretty, -preview or -retab align\n"},
+		{"./csvlint -pretty -line-numbers -sample 1", "-line-numbers cannot be combined with -sample, which writes the rows once the input is done\n"},
+	} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
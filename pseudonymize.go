@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Encodings of -pseudonymize-encoding.
+const (
+	PseudonymizeHex    = "hex"
+	PseudonymizeBase64 = "base64"
+)
+
+// bindPseudonymize resolves the -pseudonymize columns.
+func bindPseudonymize(cols []string, header []string, noHeader bool) ([]int, error) {
+	return columnIndices("-pseudonymize", cols, header, noHeader)
+}
+
+// pseudonymize replaces the fields of record at indices with their
+// HMAC-SHA256 keyed with salt, so that equal values stay equal to each
+// other but cannot be read back without the salt. Empty fields stay empty.
+func pseudonymize(record []string, indices []int, salt []byte, encoding string) {
+	for _, n := range indices {
+		if n >= len(record) || record[n] == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(record[n]))
+		if encoding == PseudonymizeBase64 {
+			record[n] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		} else {
+			record[n] = hex.EncodeToString(mac.Sum(nil))
+		}
+	}
+}
+
+// randomSalt returns the salt of -pseudonymize when none is given, a new
+// one on every run.
+func randomSalt() ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_pseudonymizeFlag(t *testing.T) {
+	input := "id,email\n1,ada@example.com\n2,bob@example.com\n3,ada@example.com\n4,\n"
+	ada := "02499ca50786c7344cb6060012cb26cef23df47a15e968f558eed4652b0a0330"
+	bob := "3ee1d0650dc9ad001cb06b725dd88915905f760d8ecd7cc86a9d7880ccfcb236"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -pseudonymize email -pseudonymize-salt s3cret", ExitCodeOK, "id,email\n1," + ada + "\n2," + bob + "\n3," + ada + "\n4,\n", ""},
+		{"./csvlint -quote minimal -pseudonymize email -pseudonymize-salt s3cret -pseudonymize-encoding base64 -rows 1", ExitCodeOK, "id,email\n1,AkmcpQeGxzRMtgYAEssmzvI99HoV6Wj1WO7UZSsKAzA=\n", ""},
+		{"./csvlint -quote minimal -pseudonymize 2 -pseudonymize-salt s3cret -no-header -rows 2", ExitCodeOK, "1," + ada + "\n", ""},
+		{"./csvlint -quote minimal -pseudonymize mail -pseudonymize-salt s3cret -select id,email:mail -rows 2", ExitCodeOK, "id,mail\n2," + bob + "\n", ""},
+		{"./csvlint -pseudonymize name", ExitCodeError, "", "-pseudonymize: unknown column \"name\"\n"},
+		{"./csvlint -pseudonymize-salt s3cret", ExitCodeError, "", "-pseudonymize-salt and -pseudonymize-encoding need -pseudonymize\n"},
+		{"./csvlint -pseudonymize email -pseudonymize-encoding base32", ExitCodeError, "", "invalid -pseudonymize-encoding \"base32\": must be hex or base64\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_pseudonymizeRandomSalt(t *testing.T) {
+	var runs []string
+	for i := 0; i < 2; i++ {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("email\nada@example.com\nada@example.com\n"), outStream: outStream, errStream: errStream}
+		if status := cli.Run([]string{"./csvlint", "-quote", "minimal", "-pseudonymize", "email"}); status != ExitCodeOK {
+			t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+		}
+		rows := strings.Split(outStream.String(), "\n")
+		if len(rows) != 4 || rows[1] != rows[2] || len(rows[1]) != 64 {
+			t.Fatalf("expected two equal hashes, got %q", outStream.String())
+		}
+		runs = append(runs, rows[1])
+	}
+	if runs[0] == runs[1] {
+		t.Errorf("expected a different salt on every run, got %q twice", runs[0])
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// quarantine collects the raw input of rejected records for -quarantine,
+// in the encoding of the input. A file processed concurrently with others
+// writes to a buffered one instead, which is merged in the order of the
+// files.
+type quarantine struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+	// buf, when buffered, holds the records
+	buf *bytes.Buffer
+}
+
+// buffered returns the quarantine of a file processed concurrently with
+// others.
+func (q *quarantine) buffered() *quarantine {
+	return &quarantine{buf: new(bytes.Buffer)}
+}
+
+func (q *quarantine) write(raw []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(raw) > 0 && raw[len(raw)-1] != '\n' {
+		raw = append(raw, '\n')
+	}
+	if q.buf != nil {
+		q.buf.Write(raw)
+		return nil
+	}
+	_, err := q.w.Write(raw)
+	return err
+}
+
+// merge writes the records of the buffered c.
+func (q *quarantine) merge(c *quarantine) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, err := c.buf.WriteTo(q.w)
+	return err
+}
+
+// close closes the file, once: Run closes it to see the error, and the
+// deferred close covers the runs that fail before.
+func (q *quarantine) close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.w == nil {
+		return nil
+	}
+	err := q.w.Close()
+	q.w = nil
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_quarantineFlag(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "rejected.csv")
+	input := "id,age\n1,30\n2,\"-1\"\n3,\"multi\nline\"\n4,40\n"
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal", "-range", "age=0:120", "-quarantine", name}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "id,age\n1,30\n4,40\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "2,\"-1\"\n3,\"multi\nline\"\n"
+	if string(b) != expected {
+		t.Errorf("expected %q to eq %q", string(b), expected)
+	}
+	for _, count := range []string{"quarantined rows: 2\n", "passed rows: 2\n"} {
+		if !strings.Contains(errStream.String(), count) {
+			t.Errorf("expected %q to contain %q", errStream.String(), count)
+		}
+	}
+}
+
+// The rows are quarantined in the encoding of the input.
+func TestRun_quarantineFlagEncoding(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "rejected.csv")
+	// two rows of kanji in Shift-JIS
+	input := "id,name\n1,\x8e\x52\x93\x63\n-2,\x91\xbe\x98\x59\n"
+
+	for _, enc := range []string{"sjis", "auto"} {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: new(bytes.Buffer), errStream: errStream}
+		args := []string{"./csvlint", "-encoding", enc, "-range", "id=0:9", "-quarantine", name}
+		if status := cli.Run(args); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", enc, status, ExitCodeOK, errStream.String())
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "-2,\x91\xbe\x98\x59\n"; string(b) != expected {
+			t.Errorf("%s: expected %q to eq %q", enc, string(b), expected)
+		}
+	}
+}
+
+// Files processed concurrently quarantine their rows in the order of the
+// files.
+func TestRun_quarantineFlagFileWorkers(t *testing.T) {
+	var inputs []string
+	var expected strings.Builder
+	for i := 0; i < 8; i++ {
+		var b strings.Builder
+		b.WriteString("id,age\n")
+		for j := 0; j < 50*(8-i); j++ {
+			fmt.Fprintf(&b, "%d,%d\n", j, j%100)
+		}
+		fmt.Fprintf(&b, "%d,-1\n", i)
+		fmt.Fprintf(&expected, "%d,-1\n", i)
+		inputs = append(inputs, b.String())
+	}
+	files := writeFiles(t, inputs...)
+	name := filepath.Join(t.TempDir(), "rejected.csv")
+
+	cli := &CLI{outStream: new(bytes.Buffer), errStream: new(bytes.Buffer)}
+	args := []string{"./csvlint", "-file-workers", "4", "-range", "age=0:120", "-quarantine", name}
+	if status := cli.Run(append(args, files...)); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected.String() {
+		t.Errorf("expected %q to eq %q", string(b), expected.String())
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// numRange is one -range check: the values of column must be numbers
+// between min and max inclusive.
+type numRange struct {
+	column   string
+	min, max float64
+	spec     string
+}
+
+// rangesValue collects repeatable col=MIN:MAX flags. Either bound may be
+// left out.
+type rangesValue []numRange
+
+func (r *rangesValue) String() string {
+	var specs []string
+	for _, c := range *r {
+		specs = append(specs, c.spec)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (r *rangesValue) Set(v string) error {
+	i := strings.LastIndex(v, "=")
+	j := strings.LastIndex(v, ":")
+	if i <= 0 || j < i {
+		return fmt.Errorf("expected col=MIN:MAX, got %q", v)
+	}
+	c := numRange{column: v[:i], min: math.Inf(-1), max: math.Inf(1), spec: v}
+	var ok bool
+	if lo := v[i+1 : j]; lo != "" {
+		if c.min, ok = parseFloat(lo); !ok {
+			return fmt.Errorf("invalid minimum in %q", v)
+		}
+	}
+	if hi := v[j+1:]; hi != "" {
+		if c.max, ok = parseFloat(hi); !ok {
+			return fmt.Errorf("invalid maximum in %q", v)
+		}
+	}
+	if c.min > c.max {
+		return fmt.Errorf("invalid range %q: minimum is greater than maximum", v)
+	}
+	*r = append(*r, c)
+	return nil
+}
+
+// resolveRanges maps the columns of the -range checks to their index.
+func resolveRanges(ranges []numRange, header []string, noHeader bool) ([]int, error) {
+	index := headerIndex(header)
+	indices := make([]int, len(ranges))
+	for i, c := range ranges {
+		n, err := columnIndex(c.column, index, noHeader)
+		if err != nil {
+			return nil, err
+		}
+		indices[i] = n
+	}
+	return indices, nil
+}
+
+// checkRanges reports the fields of record that are not decimal numbers, as
+// -ddl reads them, or fall
+// outside their -range. indices are the resolved columns of opts.Ranges.
+func checkRanges(name string, record []string, reader recordReader, diag *diagnostics, indices []int, opts *Options) {
+	for i, c := range opts.Ranges {
+		n := indices[i]
+		v := ""
+		if n < len(record) {
+			v = strings.TrimSpace(record[n])
+		}
+		if v == "" && opts.RangeSkipEmpty {
+			continue
+		}
+		line := 0
+		if n < len(record) {
+			line, _ = reader.FieldPos(n)
+		}
+		// decimal numbers only: NaN would pass any range
+		f, ok := parseFloat(v)
+		if !ok {
+			diag.report(Diagnostic{File: name, Line: line, Column: n + 1, Rule: "number", Message: fmt.Sprintf("%s: %q is not a number", c.column, v)})
+			continue
+		}
+		if f < c.min || f > c.max {
+			diag.report(Diagnostic{File: name, Line: line, Column: n + 1, Rule: "range", Message: fmt.Sprintf("%s: %s is outside %s", c.column, v, c.spec[len(c.column)+1:])})
+		}
+	}
+}
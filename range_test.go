@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_rangeFlag(t *testing.T) {
+	input := "id,age\n1,30\n2,-1\n3,abc\n4,\n5,120\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{
+			"./csvlint -range age=0:120",
+			ExitCodeOK,
+			"line 3 column 2: age: -1 is outside 0:120\nline 4 column 2: age: \"abc\" is not a number\nline 5 column 2: age: \"\" is not a number\n",
+		},
+		{
+			"./csvlint -range age=0: -range-skip-empty -strict",
+			ExitCodeError,
+			"line 3 column 2: age: -1 is outside 0:\nline 4 column 2: age: \"abc\" is not a number\n",
+		},
+		{"./csvlint -range age=-10:200 -range-skip-empty -strict", ExitCodeError, "line 4 column 2: age: \"abc\" is not a number\n"},
+		{"./csvlint -range id=1:5 -strict", ExitCodeOK, ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if errStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.expected)
+		}
+	}
+}
+
+func TestRangesValue_Set(t *testing.T) {
+	for _, v := range []string{"age", "age=1", "=1:2", "age=a:2", "age=3:2", "age=NaN:", "age=:Inf", "age=0x1:2"} {
+		var r rangesValue
+		if err := r.Set(v); err == nil {
+			t.Errorf("expected %q to be rejected", v)
+		}
+	}
+}
+
+// NaN, Inf and hex numbers are not decimal numbers, and NaN would fall in
+// any range.
+func TestRun_rangeFlagDecimal(t *testing.T) {
+	input := "v\nNaN\nInf\n0x10\n1e1\n"
+	errStream := new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: new(bytes.Buffer), errStream: errStream}
+	cli.Run([]string{"./csvlint", "-range", "v=0:100"})
+
+	expected := "line 2 column 1: v: \"NaN\" is not a number\nline 3 column 1: v: \"Inf\" is not a number\nline 4 column 1: v: \"0x10\" is not a number\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+)
+
+// recordReader is the part of csv.Reader that transform relies on.
+type recordReader interface {
+	Read() (record []string, err error)
+	FieldPos(field int) (line, column int)
+}
+
+// rawReader splits csv input into the raw bytes of each record, following
+// the quoting rules csv.Reader applies with LazyQuotes, so that the exact
+// input of a record is available alongside its parsed fields.
+type rawReader struct {
+	r       *bufio.Reader
+	comma   rune
+	comment rune
+	line    int
+}
+
+func newRawReader(r io.Reader, comma, comment rune) *rawReader {
+	if comma == 0 {
+		comma = ','
+	}
+	return &rawReader{r: bufio.NewReader(r), comma: comma, comment: comment}
+}
+
+// next returns the raw bytes of the next record, including its line
+// terminator, and the line it starts on. A line starting with the comment
+// character is returned on its own with isComment set.
+func (r *rawReader) next() (raw []byte, line int, isComment bool, err error) {
+	line = r.line + 1
+	inQuote, fieldStart := false, true
+
+	for {
+		b, err := r.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			if len(raw) > 0 {
+				return raw, line, false, nil
+			}
+			return nil, line, false, err
+		}
+		r.line++
+
+		if len(raw) == 0 && r.comment != 0 {
+			if c, _ := utf8.DecodeRune(b); c == r.comment {
+				return b, line, true, nil
+			}
+		}
+		raw = append(raw, b...)
+
+		for i := 0; i < len(b); {
+			c, size := utf8.DecodeRune(b[i:])
+			switch {
+			case inQuote && c == '"':
+				if i+1 < len(b) && b[i+1] == '"' {
+					size++
+				} else if rest := b[i+1:]; len(rest) == 0 || rest[0] == '\n' || bytes.HasPrefix(rest, []byte("\r\n")) {
+					inQuote = false
+				} else if c, _ := utf8.DecodeRune(rest); c == r.comma {
+					inQuote = false
+				}
+			case inQuote:
+			case fieldStart && c == '"':
+				inQuote = true
+				fieldStart = false
+			case c == r.comma:
+				fieldStart = true
+			default:
+				fieldStart = false
+			}
+			i += size
+		}
+
+		if !inQuote || err != nil {
+			return raw, line, false, nil
+		}
+		fieldStart = false
+	}
+}
+
+// commentReader reads csv records like csv.Reader but hands lines starting
+// with the comment character to onComment instead of discarding them.
+type commentReader struct {
+	raw       *rawReader
+	lazy      bool
+	onComment func(line []byte) error
+
+	// cr parsed the last record, which started on line; last holds its
+	// raw bytes.
+	cr   *csv.Reader
+	line int
+	last []byte
+}
+
+func (r *commentReader) Read() ([]string, error) {
+	for {
+		raw, line, isComment, err := r.raw.next()
+		if err != nil {
+			return nil, err
+		}
+		if isComment {
+			if err := r.onComment(raw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		cr := csv.NewReader(bytes.NewReader(raw))
+		cr.Comma = r.raw.comma
+		cr.LazyQuotes = r.lazy
+		cr.FieldsPerRecord = -1
+		record, err := cr.Read()
+		if err == io.EOF {
+			// blank line
+			continue
+		}
+		r.cr, r.line, r.last = cr, line, raw
+		if pe, ok := err.(*csv.ParseError); ok {
+			pe.StartLine += line - 1
+			pe.Line += line - 1
+		}
+		return record, err
+	}
+}
+
+// FieldPos returns the line and column of the given field of the record
+// most recently returned by Read.
+func (r *commentReader) FieldPos(field int) (line, column int) {
+	line, column = r.cr.FieldPos(field)
+	return line + r.line - 1, column
+}
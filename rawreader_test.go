@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRawReader_next(t *testing.T) {
+	input := "a,b\n# note\n\"x\n# not a comment\",y\n\"q\"\"\",z\r\nlast\"quote,w"
+	r := newRawReader(strings.NewReader(input), ',', '#')
+
+	var raws []string
+	var comments []bool
+	for {
+		raw, _, isComment, err := r.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		raws = append(raws, string(raw))
+		comments = append(comments, isComment)
+	}
+
+	expected := []string{"a,b\n", "# note\n", "\"x\n# not a comment\",y\n", "\"q\"\"\",z\r\n", "last\"quote,w"}
+	if !reflect.DeepEqual(raws, expected) {
+		t.Errorf("expected %q to eq %q", raws, expected)
+	}
+	if !reflect.DeepEqual(comments, []bool{false, true, false, false, false}) {
+		t.Errorf("unexpected comment flags %v", comments)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexReplace is a -replace-regex or -rename-regex substitution.
+type regexReplace struct {
+	spec string
+	re   *regexp.Regexp
+	repl string
+}
+
+// parseRegexReplace parses PATTERN=REPLACEMENT of the flag, split at the
+// first '='; write \x3d for an '=' in the pattern.
+func parseRegexReplace(flag, spec string) (regexReplace, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 {
+		return regexReplace{}, fmt.Errorf("invalid -%s %q: expected PATTERN=REPLACEMENT", flag, spec)
+	}
+	re, err := regexp.Compile(spec[:i])
+	if err != nil {
+		return regexReplace{}, fmt.Errorf("invalid -%s %q: %s", flag, spec, err)
+	}
+	return regexReplace{spec: spec, re: re, repl: spec[i+1:]}, nil
+}
+
+// replaceRegex applies the substitutions to v in order.
+func replaceRegex(v string, replaces []regexReplace) string {
+	for _, r := range replaces {
+		v = r.re.ReplaceAllString(v, r.repl)
+	}
+	return v
+}
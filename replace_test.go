@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_replaceRegexFlag(t *testing.T) {
+	input := "id_code,note\nid_42,wait!!!\nid_7,a=b\n"
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{[]string{"-replace-regex", "!{2,}=!"}, ExitCodeOK, "id_code,note\nid_42,wait!\nid_7,a=b\n", ""},
+		{[]string{"-replace-regex", "^id_(.*)=$1", "-replace-regex", `\x3d=:`}, ExitCodeOK, "id_code,note\n42,wait!!!\n7,a:b\n", ""},
+		{[]string{"-replace-regex", "^id_(?P<n>.*)=${n}", "-replace-regex-cols", "note"}, ExitCodeOK, "id_code,note\nid_42,wait!!!\nid_7,a=b\n", ""},
+		{[]string{"-replace-regex", "[0-9]+=#", "-replace-regex-cols", "1", "-no-header"}, ExitCodeOK, "id_code,note\nid_#,wait!!!\nid_#,a=b\n", ""},
+		{[]string{"-replace-regex", "(=x"}, ExitCodeError, "", "invalid -replace-regex \"(=x\": error parsing regexp: missing closing ): `(`\n"},
+		{[]string{"-replace-regex", "x"}, ExitCodeError, "", "invalid -replace-regex \"x\": expected PATTERN=REPLACEMENT\n"},
+		{[]string{"-replace-regex-cols", "note"}, ExitCodeError, "", "-replace-regex-cols needs -replace-regex\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(append([]string{"./csvlint", "-quote", "minimal"}, test.args...))
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errMissingColumns is returned by transform when the header lacks some of
+// the -require-columns. Each of them has already been reported.
+var errMissingColumns = errors.New("missing required columns")
+
+// checkRequired reports the columns of required that header does not
+// have, and returns errMissingColumns if there are any.
+func checkRequired(name string, header []string, line int, required []string, diag *diagnostics) error {
+	index := headerIndex(header)
+	missing := false
+	for _, col := range required {
+		if _, ok := index[col]; !ok {
+			diag.report(Diagnostic{File: name, Line: line, Rule: "require-columns", Message: fmt.Sprintf("missing required column %q", col)})
+			missing = true
+		}
+	}
+	if missing {
+		return errMissingColumns
+	}
+	return nil
+}
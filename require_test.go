@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_requireColumnsFlag(t *testing.T) {
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{"./csvlint -require-columns name,id", ExitCodeOK, ""},
+		{"./csvlint -require-columns id,email,name,phone", ExitCodeError, "line 1: missing required column \"email\"\nline 1: missing required column \"phone\"\n"},
+	}
+
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("id,extra,name\n1,x,a\n"), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(tt.args, " "))
+		if status != tt.status {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, tt.status)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestRun_requireColumnsFlag_files(t *testing.T) {
+	files := writeFiles(t, "id,name\n1,a\n", "id\n2\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+
+	status := cli.Run(append([]string{"./csvlint", "-require-columns", "id,name"}, files...))
+	if status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	expected := files[1] + ": line 1: missing required column \"name\"\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
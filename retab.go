@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Modes of -retab.
+const (
+	// RetabTrim removes the spaces padding the fields of a tab separated
+	// input.
+	RetabTrim = "trim"
+	// RetabAlign also pads the fields of the -tsv output with spaces, so
+	// that its columns line up.
+	RetabAlign = "align"
+)
+
+// retabTrim removes the spaces around every field of record, which in a
+// hand edited tab separated file are there to align the columns.
+func retabTrim(record []string) {
+	for i, v := range record {
+		record[i] = strings.Trim(v, " ")
+	}
+}
+
+// tsvAligner keeps the rows of -retab align, as printTsv writes them, to
+// pad every field but the last of a row to the width of its column once
+// all are in.
+type tsvAligner struct {
+	mu     sync.Mutex
+	rows   [][]string
+	memory *memoryLimit
+}
+
+func (a *tsvAligner) add(record []string, opts *Options) error {
+	var b strings.Builder
+	if err := printTsv(&b, record, opts); err != nil {
+		return err
+	}
+	// printTsv escapes the tabs inside fields, so the rest are separators
+	cells := strings.Split(strings.TrimSuffix(b.String(), opts.lineEnding()), "\t")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rows = append(a.rows, cells)
+	return a.memory.grow("-retab", recordSize(cells))
+}
+
+func (a *tsvAligner) write(w io.Writer, opts *Options) error {
+	var widths []int
+	for _, row := range a.rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := runewidth.StringWidth(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	var b strings.Builder
+	for _, row := range a.rows {
+		for i, cell := range row {
+			if i < len(row)-1 {
+				b.WriteString(runewidth.FillRight(cell, widths[i]))
+				b.WriteByte('\t')
+			} else {
+				b.WriteString(cell)
+			}
+		}
+		b.WriteString(opts.lineEnding())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
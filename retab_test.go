@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_retabFlag(t *testing.T) {
+	input := "key   \tvalue\nname  \tcsvlint  \nversion\t1.0\n"
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{[]string{"-d", `\t`, "-retab", "trim", "-quote", "minimal"}, ExitCodeOK, "key,value\nname,csvlint\nversion,1.0\n", ""},
+		{[]string{"-d", `\t`, "-retab", "align", "-tsv"}, ExitCodeOK, "key    \tvalue\nname   \tcsvlint\nversion\t1.0\n", ""},
+		{[]string{"-d", `\t`, "-retab", "align", "-tsv", "-select", "value,key"}, ExitCodeOK, "value  \tkey\ncsvlint\tname\n1.0    \tversion\n", ""},
+		{[]string{"-d", `\t`, "-tsv"}, ExitCodeOK, "key   \tvalue\nname  \tcsvlint  \nversion\t1.0\n", ""},
+		{[]string{"-retab", "trim"}, ExitCodeError, "", "-retab trim needs a tab separated input, -delimiter '\\t'\n"},
+		{[]string{"-retab", "align"}, ExitCodeError, "", "-retab align needs -tsv\n"},
+		{[]string{"-retab", "tabs"}, ExitCodeError, "", "invalid -retab \"tabs\": must be trim or align\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		args := append([]string{"./csvlint"}, test.args...)
+		status := cli.Run(args)
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rowRange is an inclusive range of 1-based data row positions.
+type rowRange struct{ first, last int }
+
+// rowSet is the set of data rows picked by -rows.
+type rowSet []rowRange
+
+// parseRows parses a list like "3,7,10-12" into a sorted set.
+func parseRows(spec string) (rowSet, error) {
+	var set rowSet
+	for _, item := range strings.Split(spec, ",") {
+		first, last := item, item
+		if i := strings.Index(item, "-"); i >= 0 {
+			first, last = item[:i], item[i+1:]
+		}
+		a, err1 := strconv.Atoi(first)
+		b, err2 := strconv.Atoi(last)
+		if err1 != nil || err2 != nil || a < 1 || b < a {
+			return nil, fmt.Errorf("invalid rows %q: expected a 1-based row or a range like 10-12", item)
+		}
+		set = append(set, rowRange{a, b})
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].first < set[j].first })
+	return set, nil
+}
+
+func (s rowSet) contains(n int) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i].last >= n })
+	for ; i < len(s) && s[i].first <= n; i++ {
+		if n <= s[i].last {
+			return true
+		}
+	}
+	return false
+}
+
+// max returns the last row in the set.
+func (s rowSet) max() int {
+	m := 0
+	for _, r := range s {
+		if r.last > m {
+			m = r.last
+		}
+	}
+	return m
+}
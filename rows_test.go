@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_rowsFlag(t *testing.T) {
+	input := "id\n1\n2\n3\n4\n5\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -rows 2,4-5", ExitCodeOK, "id\n2\n4\n5\n", ""},
+		{"./csvlint -quote minimal -rows 4,1", ExitCodeOK, "id\n1\n4\n", ""},
+		{"./csvlint -quote minimal -rows 1,2 -skip-header", ExitCodeOK, "1\n2\n", ""},
+		{"./csvlint -quote minimal -rows 1 -no-header", ExitCodeOK, "id\n", ""},
+		{"./csvlint -quote minimal -rows 5-9", ExitCodeOK, "id\n5\n", ""},
+		{"./csvlint -quote minimal -rows 5-9 -strict", ExitCodeError, "id\n5\n", "row 9 was requested but there are only 5 data rows\n"},
+		{"./csvlint -rows 0", ExitCodeError, "", "invalid rows \"0\": expected a 1-based row or a range like 10-12\n"},
+		{"./csvlint -rows 3-2", ExitCodeError, "", "invalid rows \"3-2\": expected a 1-based row or a range like 10-12\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_rowsStopsReading(t *testing.T) {
+	// the second row does not parse, but reading stops before it
+	input := "id\n1\n\"2\n"
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+	if status := cli.Run([]string{"./csvlint", "-rows", "1", "-strict"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream)
+	}
+	if expected := "\"id\"\n\"1\"\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
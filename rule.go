@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// rule is a compiled -rule: when every condition holds for a row, target
+// is set to the value of expr. The grammar is
+//
+//	rule      = condition { "&&" condition } "=>" column "=" value
+//	condition = column ( "==" | "!=" ) string
+//	value     = string | column | func "(" ( string | column ) ")"
+//	func      = "upper" | "lower" | "trim"
+//
+// where a column is a header name made of letters, digits and '_', any
+// other name between backquotes, a doubled backquote standing for one, as
+// in `first name`, or a 1-based position with -no-header, and a string is
+// double quoted with Go escapes.
+type rule struct {
+	spec   string
+	conds  []condition
+	target string
+	fn     string
+	arg    operand
+}
+
+type condition struct {
+	column string
+	negate bool
+	value  string
+}
+
+// operand is a column reference or, when literal is set, a string.
+type operand struct {
+	column  string
+	value   string
+	literal bool
+}
+
+var ruleFuncs = map[string]func(string) string{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+// parseRule compiles a -rule expression.
+func parseRule(spec string) (rule, error) {
+	p := &ruleParser{src: spec}
+	r, err := p.rule()
+	if err != nil {
+		return rule{}, fmt.Errorf("invalid rule %q: %s", spec, err)
+	}
+	return r, nil
+}
+
+type ruleParser struct {
+	src string
+	pos int
+}
+
+func (p *ruleParser) rule() (rule, error) {
+	r := rule{spec: p.src}
+	for {
+		c, err := p.condition()
+		if err != nil {
+			return r, err
+		}
+		r.conds = append(r.conds, c)
+		if !p.accept("&&") {
+			break
+		}
+	}
+	if err := p.expect("=>"); err != nil {
+		return r, err
+	}
+	var err error
+	if r.target, err = p.column(); err != nil {
+		return r, err
+	}
+	if err := p.expect("="); err != nil {
+		return r, err
+	}
+	if r.fn, r.arg, err = p.value(); err != nil {
+		return r, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return r, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	return r, nil
+}
+
+func (p *ruleParser) condition() (condition, error) {
+	var c condition
+	var err error
+	if c.column, err = p.column(); err != nil {
+		return c, err
+	}
+	switch {
+	case p.accept("=="):
+	case p.accept("!="):
+		c.negate = true
+	default:
+		return c, p.errorf("expected == or !=")
+	}
+	c.value, err = p.str()
+	return c, err
+}
+
+func (p *ruleParser) value() (string, operand, error) {
+	if p.skipSpace(); p.pos < len(p.src) && p.src[p.pos] == '"' {
+		s, err := p.str()
+		return "", operand{value: s, literal: true}, err
+	}
+	name, err := p.column()
+	if err != nil {
+		return "", operand{}, err
+	}
+	if !p.accept("(") {
+		return "", operand{column: name}, nil
+	}
+	if _, ok := ruleFuncs[name]; !ok {
+		return "", operand{}, fmt.Errorf("unknown function %q", name)
+	}
+	var arg operand
+	if p.skipSpace(); p.pos < len(p.src) && p.src[p.pos] == '"' {
+		arg.literal = true
+		arg.value, err = p.str()
+	} else {
+		arg.column, err = p.column()
+	}
+	if err != nil {
+		return "", arg, err
+	}
+	return name, arg, p.expect(")")
+}
+
+func (p *ruleParser) column() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '`' {
+		return p.quotedColumn()
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a column")
+	}
+	return p.src[start:p.pos], nil
+}
+
+// quotedColumn reads a column name between backquotes.
+func (p *ruleParser) quotedColumn() (string, error) {
+	var name strings.Builder
+	for i := p.pos + 1; i < len(p.src); i++ {
+		if p.src[i] != '`' {
+			name.WriteByte(p.src[i])
+			continue
+		}
+		if i+1 < len(p.src) && p.src[i+1] == '`' {
+			name.WriteByte('`')
+			i++
+			continue
+		}
+		if name.Len() == 0 {
+			return "", p.errorf("empty column")
+		}
+		p.pos = i + 1
+		return name.String(), nil
+	}
+	return "", p.errorf("unterminated column")
+}
+
+func (p *ruleParser) str() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+		return "", p.errorf("expected a quoted string")
+	}
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '"':
+			s, err := strconv.Unquote(p.src[p.pos : i+1])
+			if err != nil {
+				return "", p.errorf("invalid string")
+			}
+			p.pos = i + 1
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *ruleParser) accept(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) expect(tok string) error {
+	if !p.accept(tok) {
+		return p.errorf("expected %s", tok)
+	}
+	return nil
+}
+
+func (p *ruleParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *ruleParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, a...))
+}
+
+// boundRule is a rule with its columns resolved against a header.
+type boundRule struct {
+	rule
+	condIdx   []int
+	targetIdx int
+	argIdx    int
+}
+
+// bindRules resolves the columns of rules.
+func bindRules(rules []rule, header []string, noHeader bool) ([]boundRule, error) {
+	index := headerIndex(header)
+	bound := make([]boundRule, len(rules))
+	for i, r := range rules {
+		b := boundRule{rule: r, argIdx: -1}
+		for _, c := range r.conds {
+			n, err := columnIndex(c.column, index, noHeader)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %s", r.spec, err)
+			}
+			b.condIdx = append(b.condIdx, n)
+		}
+		var err error
+		if b.targetIdx, err = columnIndex(r.target, index, noHeader); err != nil {
+			return nil, fmt.Errorf("rule %q: %s", r.spec, err)
+		}
+		if !r.arg.literal {
+			if b.argIdx, err = columnIndex(r.arg.column, index, noHeader); err != nil {
+				return nil, fmt.Errorf("rule %q: %s", r.spec, err)
+			}
+		}
+		bound[i] = b
+	}
+	return bound, nil
+}
+
+// applyRules runs rules over record in order, so a rule sees the changes
+// made by the ones before it.
+func applyRules(record []string, rules []boundRule) []string {
+	field := func(n int) string {
+		if n < len(record) {
+			return record[n]
+		}
+		return ""
+	}
+	for _, r := range rules {
+		match := true
+		for i, c := range r.conds {
+			if (field(r.condIdx[i]) == c.value) == c.negate {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		v := r.arg.value
+		if r.argIdx >= 0 {
+			v = field(r.argIdx)
+		}
+		if r.fn != "" {
+			v = ruleFuncs[r.fn](v)
+		}
+		for len(record) <= r.targetIdx {
+			record = append(record, "")
+		}
+		record[r.targetIdx] = v
+	}
+	return record
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_ruleFlag(t *testing.T) {
+	inStream := strings.NewReader("status,name,note\nactive,alice,x\ninactive,bob,y\nactive,carol,\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal",
+		"-rule", `status=="active" => name=upper(name)`,
+		"-rule", `status!="active" && note=="y" => note="seen"`,
+		"-rule", `name=="CAROL" => note=status`,
+	}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := "status,name,note\nactive,ALICE,x\ninactive,bob,seen\nactive,CAROL,active\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+// Names with spaces, hyphens, dots or backquotes are given between
+// backquotes.
+func TestRun_ruleFlagQuotedColumns(t *testing.T) {
+	inStream := strings.NewReader("first name,e-mail,v.1,a`b\nann,x,,q\nbob,y,,r\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal",
+		"-rule", "`first name`==\"ann\" && `e-mail`!=\"\" => `v.1`=upper(`e-mail`)",
+		"-rule", "`a``b`==\"r\" => `v.1`=`first name`",
+	}
+
+	if status := cli.Run(args); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	expected := "first name,e-mail,v.1,a`b\nann,x,X,q\nbob,y,bob,r\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestParseRule_invalid(t *testing.T) {
+	for _, spec := range []string{
+		`status=active => name=upper(name)`,
+		`status=="active" name=upper(name)`,
+		`status=="active" => name=shout(name)`,
+		`status=="active" => name=upper(name`,
+		`status=="active => name=name`,
+		`status=="active" => name=name extra`,
+		"`first name==\"a\" => name=name",
+		"``==\"a\" => name=name",
+	} {
+		if _, err := parseRule(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
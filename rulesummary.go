@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// ruleSummary counts the data rows checked for -rule-summary, which
+// writes them with the number of problems of every rule. Files processed
+// concurrently share it.
+type ruleSummary struct {
+	mu   sync.Mutex
+	file string
+	rows int
+}
+
+func (s *ruleSummary) add(rows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows += rows
+}
+
+// write writes the summary as a JSON document to the file, the rules
+// sorted by name.
+func (s *ruleSummary) write(perRule map[string]int) error {
+	v := struct {
+		Rows     int            `json:"rows"`
+		Problems int            `json:"problems"`
+		Rules    map[string]int `json:"rules"`
+	}{Rows: s.rows, Rules: perRule}
+	for _, n := range perRule {
+		v.Problems += n
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.file, append(b, '\n'), 0666)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_ruleSummaryFlag(t *testing.T) {
+	input := "id,age,email\n1,30,a@example.com\n2,-1,nope\n3,200,b@example.com\n4,x,c\n"
+	for _, workers := range []string{"1", "2"} {
+		files := writeFiles(t, input, input)
+		name := filepath.Join(t.TempDir(), "summary.json")
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{outStream: outStream, errStream: errStream}
+
+		args := append([]string{"./csvlint", "-range", "age=0:120", "-validate-email", "email", "-max-errors", "1", "-file-workers", workers, "-rule-summary", name}, files...)
+		if status := cli.Run(args); status != ExitCodeOK {
+			t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream)
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "{\n  \"rows\": 8,\n  \"problems\": 10,\n  \"rules\": {\n    \"email\": 4,\n    \"number\": 2,\n    \"range\": 4\n  }\n}\n"
+		if string(b) != expected {
+			t.Errorf("-file-workers %s: expected %q to eq %q", workers, string(b), expected)
+		}
+		if strings.Count(errStream.String(), "line ") != 1 {
+			t.Errorf("expected a single diagnostic to be shown, got %q", errStream)
+		}
+	}
+}
+
+func TestRun_ruleSummaryClean(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "summary.json")
+	cli := &CLI{inStream: strings.NewReader("id\n1\n"), outStream: new(bytes.Buffer), errStream: new(bytes.Buffer)}
+	if status := cli.Run([]string{"./csvlint", "-rule-summary", name}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\n  \"rows\": 1,\n  \"problems\": 0,\n  \"rules\": {}\n}\n"; string(b) != expected {
+		t.Errorf("expected %q to eq %q", string(b), expected)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// reservoir keeps a uniform random sample of at most n records in a single
+// pass, so memory stays bounded by n whatever the input size.
+type reservoir struct {
+	n     int
+	rng   *rand.Rand
+	seen  int
+	items []sampledRecord
+}
+
+type sampledRecord struct {
+	pos    int
+	record []string
+}
+
+func newReservoir(n int, seed int64) *reservoir {
+	return &reservoir{n: n, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (r *reservoir) add(record []string) {
+	r.seen++
+	if len(r.items) < r.n {
+		r.items = append(r.items, sampledRecord{r.seen, record})
+		return
+	}
+	if j := r.rng.Intn(r.seen); j < r.n {
+		r.items[j] = sampledRecord{r.seen, record}
+	}
+}
+
+// records returns the sample in input order.
+func (r *reservoir) records() [][]string {
+	sort.Slice(r.items, func(i, j int) bool { return r.items[i].pos < r.items[j].pos })
+	records := make([][]string, len(r.items))
+	for i, item := range r.items {
+		records[i] = item.record
+	}
+	return records
+}
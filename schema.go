@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const defaultSchemaRows = 100
+
+// schemaRowsValue is the number of rows of -preview-schema, which can be
+// given alone for defaultSchemaRows.
+type schemaRowsValue struct {
+	n int
+	// bare tells that the flag was given alone, so a number after it is
+	// an argument, not its value.
+	bare bool
+}
+
+func (p *schemaRowsValue) String() string {
+	return strconv.Itoa(p.n)
+}
+
+func (p *schemaRowsValue) Set(v string) error {
+	if v == "true" {
+		p.n, p.bare = defaultSchemaRows, true
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return fmt.Errorf("expected a number of rows, got %q", v)
+	}
+	p.n, p.bare = n, false
+	return nil
+}
+
+func (p *schemaRowsValue) IsBoolFlag() bool { return true }
+
+// schemaPreview infers the types of the columns from the first rows
+// written, like -ddl, and prints them to w as soon as it has them, or with
+// a json or sarif report adds them to the report of diag. With only set
+// nothing else is written and the input is read no further. Files
+// processed concurrently share it.
+type schemaPreview struct {
+	mu        sync.Mutex
+	w         io.Writer
+	diag      *diagnostics
+	rows      int
+	only      bool
+	nullToken string
+
+	types typeInference
+	// nulls tells, per column, whether a row had no value for it
+	nulls []bool
+	seen  int
+	done  bool
+}
+
+func (s *schemaPreview) add(record []string, isHeader bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil
+	}
+	if isHeader {
+		s.types.add(record, true)
+		return nil
+	}
+	values := record
+	if s.nullToken != "" {
+		values = make([]string, len(record))
+		for i, v := range record {
+			if v != s.nullToken {
+				values[i] = v
+			}
+		}
+	}
+	s.types.add(values, false)
+	for len(s.nulls) < len(values) {
+		// the rows before were too short to have it
+		s.nulls = append(s.nulls, s.seen > 0)
+	}
+	for i := range s.nulls {
+		if i >= len(values) || values[i] == "" {
+			s.nulls[i] = true
+		}
+	}
+	s.seen++
+	if s.seen == s.rows {
+		return s.print()
+	}
+	return nil
+}
+
+// finished tells whether the schema has been printed.
+func (s *schemaPreview) finished() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// flush prints the schema when the input had fewer rows than asked for.
+func (s *schemaPreview) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil
+	}
+	return s.print()
+}
+
+// previewedSchema is the schema in a json or sarif report.
+type previewedSchema struct {
+	Rows    int            `json:"rows"`
+	Columns []schemaColumn `json:"columns"`
+}
+
+type schemaColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// print writes a line per column with its name, its type and whether it
+// is nullable, the names aligned. A column without any value is a
+// nullable TEXT.
+func (s *schemaPreview) print() error {
+	s.done = true
+	names, types := s.types.columns()
+	if s.diag != nil && s.diag.format != "text" {
+		schema := &previewedSchema{Rows: s.seen, Columns: []schemaColumn{}}
+		for i, name := range names {
+			schema.Columns = append(schema.Columns, schemaColumn{name, types[i], i >= len(s.nulls) || s.nulls[i]})
+		}
+		s.diag.setSchema(schema)
+		return nil
+	}
+	width := 0
+	for _, name := range names {
+		if n := runewidth.StringWidth(name); n > width {
+			width = n
+		}
+	}
+	var b strings.Builder
+	noun := "rows"
+	if s.seen == 1 {
+		noun = "row"
+	}
+	fmt.Fprintf(&b, "schema of the first %d %s:\n", s.seen, noun)
+	for i, name := range names {
+		null := "not null"
+		if i >= len(s.nulls) || s.nulls[i] {
+			null = "nullable"
+		}
+		fmt.Fprintf(&b, "  %s  %-7s  %s\n", runewidth.FillRight(name, width), types[i], null)
+	}
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_previewSchemaFlag(t *testing.T) {
+	input := "id,name,amt,day\n1,a,1.5,2024-01-01\n2,,3,2024-02-01\n3,c\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -preview-schema=2", ExitCodeOK, input, "schema of the first 2 rows:\n  id    INTEGER  not null\n  name  TEXT     nullable\n  amt   NUMERIC  not null\n  day   DATE     not null\n"},
+		{"./csvlint -quote minimal -preview-schema", ExitCodeOK, input, "schema of the first 3 rows:\n  id    INTEGER  not null\n  name  TEXT     nullable\n  amt   NUMERIC  nullable\n  day   DATE     nullable\n"},
+		{"./csvlint -preview-schema-only -select id,amt", ExitCodeOK, "", "schema of the first 3 rows:\n  id   INTEGER  not null\n  amt  NUMERIC  nullable\n"},
+		{"./csvlint -preview-schema=1 -preview-schema-only -null-token a", ExitCodeOK, "", "schema of the first 1 row:\n  id    INTEGER  not null\n  name  TEXT     nullable\n  amt   NUMERIC  not null\n  day   DATE     not null\n"},
+		{"./csvlint -quote minimal -preview-schema-only -skip-header -select id,name", ExitCodeOK, "", "schema of the first 3 rows:\n  id    INTEGER  not null\n  name  TEXT     nullable\n"},
+		{"./csvlint -preview-schema 10", ExitCodeError, "", "-preview-schema takes the number of rows as -preview-schema=10; there is no input file \"10\"\n"},
+		{"./csvlint -preview-schema=0", ExitCodeError, "", ""},
+		{"./csvlint -preview-schema-only -count-by id", ExitCodeError, "", "-preview-schema-only writes no output and cannot be combined with -lint, -sample or other output options\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		// a flag error is followed by the usage
+		if test.errors != "" && errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestSchemaPreview_stopsReading(t *testing.T) {
+	input := "id\n1\n2\nx\n"
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-preview-schema=2", "-preview-schema-only", "-range", "id=0:9"}); status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream)
+	}
+	expected := "schema of the first 2 rows:\n  id  INTEGER  not null\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+// A column of integers and dates is TEXT, as -ddl infers it, and with a
+// json report the schema is part of the report.
+func TestRun_previewSchemaFlagReport(t *testing.T) {
+	input := "id,mixed\n1,2024-01-01\n2,3\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -preview-schema-only", "schema of the first 2 rows:\n  id     INTEGER  not null\n  mixed  TEXT     not null\n"},
+		{"./csvlint -preview-schema-only -report json", `{
+  "diagnostics": [],
+  "schema": {
+    "rows": 2,
+    "columns": [
+      {
+        "name": "id",
+        "type": "INTEGER",
+        "nullable": false
+      },
+      {
+        "name": "mixed",
+        "type": "TEXT",
+        "nullable": false
+      }
+    ]
+  }
+}
+`},
+	}
+	for _, test := range tests {
+		errStream := new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: new(bytes.Buffer), errStream: errStream}
+		if status := cli.Run(strings.Split(test.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", test.args, status, ExitCodeOK, errStream)
+		}
+		if errStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// smartChars maps the characters word processors substitute while typing
+// to the ASCII they usually stand for. No-break spaces are reported too but
+// are left to -nbsp-replacement.
+var smartChars = map[rune]string{
+	'‘':      "'",   // left single quotation mark
+	'’':      "'",   // right single quotation mark, also the apostrophe
+	'‚':      "'",   // single low-9 quotation mark
+	'‛':      "'",   // single high-reversed-9 quotation mark
+	'“':      `"`,   // left double quotation mark
+	'”':      `"`,   // right double quotation mark
+	'„':      `"`,   // double low-9 quotation mark
+	'‟':      `"`,   // double high-reversed-9 quotation mark
+	'–':      "-",   // en dash
+	'—':      "--",  // em dash
+	'…':      "...", // horizontal ellipsis
+	'\u00a0': "",    // no-break space
+}
+
+var smartCharReplacer = func() *strings.Replacer {
+	var pairs []string
+	for c, ascii := range smartChars {
+		if c != '\u00a0' {
+			pairs = append(pairs, string(c), ascii)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// findSmartChars returns the distinct smart characters in v, in the order
+// they first appear.
+func findSmartChars(v string) []rune {
+	var found []rune
+	for _, c := range v {
+		if _, ok := smartChars[c]; ok && !containsRune(found, c) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+func containsRune(rs []rune, r rune) bool {
+	for _, c := range rs {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
+
+// smartCharsMessage describes the characters found by findSmartChars.
+func smartCharsMessage(found []rune) string {
+	names := make([]string, len(found))
+	for i, c := range found {
+		names[i] = fmt.Sprintf("%U", c)
+	}
+	noun := "character"
+	if len(found) > 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("smart %s %s", noun, strings.Join(names, ", "))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_smartCharsFlags(t *testing.T) {
+	input := "name,note\n“Ada”,it’s 1990–1995…\nBob,a\u00a0b — c\n"
+	tests := []struct {
+		args     string
+		expected string
+		errors   string
+	}{
+		{
+			"./csvlint -quote minimal -check-smartchars",
+			"name,note\n“Ada”,it’s 1990–1995…\nBob,a b — c\n",
+			"line 2 column 1: smart characters U+201C, U+201D\nline 2 column 2: smart characters U+2019, U+2013, U+2026\nline 3 column 2: smart characters U+00A0, U+2014\n",
+		},
+		{
+			"./csvlint -quote minimal -fix-smartchars",
+			"name,note\n\"\"\"Ada\"\"\",it's 1990-1995...\nBob,a b -- c\n",
+			"",
+		},
+		{
+			"./csvlint -quote minimal -fix-smartchars -nbsp-replacement _ -no-transform-cols name",
+			"name,note\n“Ada”,it's 1990-1995...\nBob,a_b -- c\n",
+			"",
+		},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(test.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, ExitCodeOK)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sortKey is a column of -sort, compared as a number when numeric.
+type sortKey struct {
+	column  string
+	numeric bool
+}
+
+// parseSortKeys parses a -sort list of columns, each possibly followed by
+// ":n" to compare it as a number.
+func parseSortKeys(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, col := range strings.Split(spec, ",") {
+		k := sortKey{column: col}
+		if strings.HasSuffix(col, ":n") {
+			k = sortKey{column: strings.TrimSuffix(col, ":n"), numeric: true}
+		}
+		if k.column == "" {
+			return nil, fmt.Errorf("invalid -sort %q: empty column", spec)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// sorter keeps the data rows to write them sorted by the keys once the
+// input is done. With external set, the rows in memory are sorted and
+// spilled to a temporary file, a run, whenever they take more than memory
+// bytes, and the runs are merged at the end. Sorting is stable: rows with
+// equal keys keep their input order.
+type sorter struct {
+	keys     []sortKey
+	noHeader bool
+	external bool
+	memory   int64
+
+	indices []int
+	rows    [][]string
+	size    int64
+
+	dir    string
+	runs   []string
+	merged int
+}
+
+func newSorter(keys []sortKey, noHeader, external bool, memory int64) (*sorter, error) {
+	s := &sorter{keys: keys, noHeader: noHeader, external: external, memory: memory}
+	if external {
+		var err error
+		if s.dir, err = os.MkdirTemp("", "csvlint-sort-"); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// bind resolves the sort keys in the header of the output.
+func (s *sorter) bind(header []string) error {
+	index := headerIndex(header)
+	s.indices = make([]int, len(s.keys))
+	for i, k := range s.keys {
+		n, err := columnIndex(k.column, index, s.noHeader)
+		if err != nil {
+			return fmt.Errorf("-sort: %s", err)
+		}
+		s.indices[i] = n
+	}
+	return nil
+}
+
+func (s *sorter) add(record []string) error {
+	if s.indices == nil {
+		if err := s.bind(nil); err != nil {
+			return err
+		}
+	}
+	s.rows = append(s.rows, append([]string(nil), record...))
+	s.size += recordSize(record)
+	if s.external && s.size > s.memory {
+		return s.spill()
+	}
+	return nil
+}
+
+// less compares two rows by the sort keys. Decimal numbers sort before
+// values that are not, which sort as text.
+func (s *sorter) less(a, b []string) bool {
+	for i, k := range s.keys {
+		x, y := field(a, s.indices[i]), field(b, s.indices[i])
+		if k.numeric {
+			// only decimal numbers, so that NaN, which is not ordered,
+			// sorts as text
+			fx, okx := parseFloat(x)
+			fy, oky := parseFloat(y)
+			switch {
+			case okx && oky:
+				if fx != fy {
+					return fx < fy
+				}
+				continue
+			case okx:
+				return true
+			case oky:
+				return false
+			}
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+// spill writes the rows in memory, sorted, to a new run.
+func (s *sorter) spill() error {
+	sort.SliceStable(s.rows, func(i, j int) bool { return s.less(s.rows[i], s.rows[j]) })
+
+	name := filepath.Join(s.dir, fmt.Sprintf("run%d", len(s.runs)))
+	fp, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, name)
+	w := bufio.NewWriter(fp)
+	for _, row := range s.rows {
+		if err := writeRunRecord(w, row); err != nil {
+			fp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	s.rows, s.size = nil, 0
+	if len(s.runs) == maxSortRuns {
+		return s.compact()
+	}
+	return nil
+}
+
+// maxSortRuns is the most runs kept before they are merged into one, which
+// bounds the files open at once while merging.
+const maxSortRuns = 64
+
+// compact merges the runs into a single one, which keeps the place of the
+// first so that the sort stays stable.
+func (s *sorter) compact() error {
+	runs, closeRuns, err := s.openRuns()
+	if err != nil {
+		return err
+	}
+	defer closeRuns()
+
+	name := filepath.Join(s.dir, fmt.Sprintf("merged%d", s.merged))
+	s.merged++
+	fp, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fp)
+	if _, err := s.merge(runs, func(row []string) error { return writeRunRecord(w, row) }); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	closeRuns()
+	for _, run := range s.runs {
+		os.Remove(run)
+	}
+	s.runs = []string{name}
+	return nil
+}
+
+// Runs hold every record as its number of fields followed by the length
+// and bytes of each field, so that they are read back exactly.
+func writeRunRecord(w *bufio.Writer, record []string) error {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(record)))])
+	for _, v := range record {
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(v)))])
+		if _, err := w.WriteString(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRunRecord(r *bufio.Reader) ([]string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	record := make([]string, n)
+	for i := range record {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		record[i] = string(b)
+	}
+	return record, nil
+}
+
+// run is a sorted sequence of rows being merged.
+type run struct {
+	next func() ([]string, error)
+	row  []string
+	// order is the position of the run in the input, which breaks ties.
+	order int
+}
+
+// runHeap orders the runs by their current row.
+type runHeap struct {
+	runs []*run
+	s    *sorter
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.runs[i], h.runs[j]
+	if h.s.less(a.row, b.row) {
+		return true
+	} else if h.s.less(b.row, a.row) {
+		return false
+	}
+	return a.order < b.order
+}
+func (h *runHeap) Swap(i, j int)      { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap) Push(x interface{}) { h.runs = append(h.runs, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	r := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return r
+}
+
+// write writes the rows in order with print, merging the runs with the
+// rows still in memory. It returns the number of rows written.
+func (s *sorter) write(print func([]string) error) (int, error) {
+	sort.SliceStable(s.rows, func(i, j int) bool { return s.less(s.rows[i], s.rows[j]) })
+	if len(s.runs) == 0 {
+		for _, row := range s.rows {
+			if err := print(row); err != nil {
+				return 0, err
+			}
+		}
+		return len(s.rows), nil
+	}
+
+	runs, closeRuns, err := s.openRuns()
+	if err != nil {
+		return 0, err
+	}
+	defer closeRuns()
+	rows := s.rows
+	runs = append(runs, &run{next: func() ([]string, error) {
+		if len(rows) == 0 {
+			return nil, io.EOF
+		}
+		row := rows[0]
+		rows = rows[1:]
+		return row, nil
+	}, order: len(s.runs)})
+	return s.merge(runs, print)
+}
+
+// openRuns opens the runs in their order. The returned function closes
+// them, and may be called more than once.
+func (s *sorter) openRuns() ([]*run, func(), error) {
+	var files []*os.File
+	closeRuns := func() {
+		for _, fp := range files {
+			fp.Close()
+		}
+		files = nil
+	}
+	var runs []*run
+	for i, name := range s.runs {
+		fp, err := os.Open(name)
+		if err != nil {
+			closeRuns()
+			return nil, nil, err
+		}
+		files = append(files, fp)
+		r := bufio.NewReader(fp)
+		runs = append(runs, &run{next: func() ([]string, error) { return readRunRecord(r) }, order: i})
+	}
+	return runs, closeRuns, nil
+}
+
+// merge writes the rows of the runs in order with print. It returns the
+// number of rows written.
+func (s *sorter) merge(runs []*run, print func([]string) error) (int, error) {
+	h := &runHeap{s: s}
+	// drop the runs that are already empty
+	for _, r := range runs {
+		var err error
+		if r.row, err = r.next(); err == nil {
+			h.runs = append(h.runs, r)
+		} else if err != io.EOF {
+			return 0, err
+		}
+	}
+	heap.Init(h)
+
+	written := 0
+	for h.Len() > 0 {
+		r := h.runs[0]
+		if err := print(r.row); err != nil {
+			return written, err
+		}
+		written++
+		var err error
+		if r.row, err = r.next(); err == io.EOF {
+			heap.Pop(h)
+		} else if err != nil {
+			return written, err
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return written, nil
+}
+
+// cleanup removes the runs.
+func (s *sorter) cleanup() {
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRun_sortFlag(t *testing.T) {
+	input := "id,name,n\n3,b,10\n1,\"x\ny\",9\n2,a,x\n1,c,100\n3,a,2\n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"-sort id", "id,name,n\n1,\"x\ny\",9\n1,c,100\n2,a,x\n3,b,10\n3,a,2\n"},
+		{"-sort name,id", "id,name,n\n2,a,x\n3,a,2\n3,b,10\n1,c,100\n1,\"x\ny\",9\n"},
+		{"-sort n:n", "id,name,n\n3,a,2\n1,\"x\ny\",9\n3,b,10\n1,c,100\n2,a,x\n"},
+		{"-sort n", "id,name,n\n3,b,10\n1,c,100\n3,a,2\n1,\"x\ny\",9\n2,a,x\n"},
+		{"-no-header -select 2,1 -sort 2", "\"x\ny\",1\nc,1\na,2\nb,3\na,3\nname,id\n"},
+		{"-sort id -skip-header", "1,\"x\ny\",9\n1,c,100\n2,a,x\n3,b,10\n3,a,2\n"},
+	}
+	for _, test := range tests {
+		for _, external := range []string{"", " -sort-external -sort-memory 100B"} {
+			dir := t.TempDir()
+			t.Setenv("TMPDIR", dir)
+			args := "./csvlint -quote minimal -field-newline keep " + test.args + external
+			outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+			cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+			if status := cli.Run(strings.Split(args, " ")); status != ExitCodeOK {
+				t.Errorf("%s: expected %d to eq %d: %s", args, status, ExitCodeOK, errStream)
+			}
+			if outStream.String() != test.expected {
+				t.Errorf("%s: expected %q to eq %q", args, outStream.String(), test.expected)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("%s: expected the runs to be removed, found %v", args, entries)
+			}
+		}
+	}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func TestSorter_external(t *testing.T) {
+	s, err := newSorter([]sortKey{{column: "1", numeric: true}}, true, true, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.cleanup()
+	for i := 0; i < 500; i++ {
+		if err := s.add([]string{fmt.Sprint((i * 37) % 100), fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.runs) < 2 {
+		t.Fatalf("expected several runs, got %d", len(s.runs))
+	}
+	var prev []string
+	n, err := s.write(func(row []string) error {
+		if prev != nil && s.less(row, prev) {
+			t.Errorf("%v is written after %v", row, prev)
+		}
+		// equal keys keep their input order
+		if prev != nil && row[0] == prev[0] && atoi(row[1]) < atoi(prev[1]) {
+			t.Errorf("%v is written after %v", row, prev)
+		}
+		prev = row
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 500 {
+		t.Errorf("expected %d to eq %d", n, 500)
+	}
+}
+
+// NaN, Inf and hex sort as text, after the decimal numbers.
+func TestSorter_lessNumeric(t *testing.T) {
+	s := &sorter{keys: []sortKey{{column: "1", numeric: true}}, indices: []int{0}}
+	rows := [][]string{{"NaN"}, {"2"}, {"0x10"}, {"Inf"}, {"-1.5"}, {"NaN"}, {"1e2"}}
+	sort.SliceStable(rows, func(i, j int) bool { return s.less(rows[i], rows[j]) })
+	expected := "[[-1.5] [2] [1e2] [0x10] [Inf] [NaN] [NaN]]"
+	if fmt.Sprint(rows) != expected {
+		t.Errorf("expected %v to eq %s", rows, expected)
+	}
+}
+
+// Past maxSortRuns runs, the runs are merged into one, and the sort stays
+// stable.
+func TestSorter_compact(t *testing.T) {
+	s, err := newSorter([]sortKey{{column: "1", numeric: true}}, true, true, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.cleanup()
+	for i := 0; i < 2000; i++ {
+		if err := s.add([]string{fmt.Sprint((i * 37) % 100), fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.merged == 0 || len(s.runs) > maxSortRuns {
+		t.Fatalf("expected the runs to be merged, got %d runs and %d merges", len(s.runs), s.merged)
+	}
+	var prev []string
+	n, err := s.write(func(row []string) error {
+		if prev != nil && (s.less(row, prev) || row[0] == prev[0] && atoi(row[1]) < atoi(prev[1])) {
+			t.Errorf("%v is written after %v", row, prev)
+		}
+		prev = row
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2000 {
+		t.Errorf("expected %d to eq %d", n, 2000)
+	}
+}
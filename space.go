@@ -0,0 +1,33 @@
+package main
+
+// spaceOps are the white space transforms -trim-cols and -collapse-cols
+// apply to a column.
+type spaceOps struct {
+	trim, collapse bool
+}
+
+// resolveSpaceCols maps the -trim-cols and -collapse-cols columns to their
+// index.
+func resolveSpaceCols(trim, collapse []string, header []string, noHeader bool) (map[int]spaceOps, error) {
+	index := headerIndex(header)
+	cols := make(map[int]spaceOps, len(trim)+len(collapse))
+	for _, names := range []struct {
+		list     []string
+		collapse bool
+	}{{trim, false}, {collapse, true}} {
+		for _, name := range names.list {
+			n, err := columnIndex(name, index, noHeader)
+			if err != nil {
+				return nil, err
+			}
+			ops := cols[n]
+			if names.collapse {
+				ops.collapse = true
+			} else {
+				ops.trim = true
+			}
+			cols[n] = ops
+		}
+	}
+	return cols, nil
+}
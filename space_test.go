@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_trimCollapseColsFlags(t *testing.T) {
+	input := "address,hash,note\n\"  1  Main   St \", ab  cd , x  y \n"
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -quote minimal -collapse-cols address", "address,hash,note\n\" 1 Main St \",\" ab  cd \",\" x  y \"\n"},
+		{"./csvlint -quote minimal -trim-cols address,note", "address,hash,note\n1  Main   St,\" ab  cd \",x  y\n"},
+		{"./csvlint -quote minimal -trim-cols address -collapse-cols address", "address,hash,note\n1 Main St,\" ab  cd \",\" x  y \"\n"},
+		{"./csvlint -quote minimal -remove-space -no-transform-cols hash", "address,hash,note\n1 Main St,\" ab  cd \",x y\n"},
+		{"./csvlint -quote minimal -select note:n,address -trim-cols note", "n,address\nx  y,\"  1  Main   St \"\n"},
+		{"./csvlint -quote minimal -no-header -collapse-cols 2", "address,hash,note\n\"  1  Main   St \",\" ab cd \",\" x  y \"\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+	}
+}
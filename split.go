@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// splitWriter spreads output lines over numbered chunk files, starting a
+// new chunk once the current one holds maxRows data lines or would grow
+// past maxBytes. The header line, when there is one, is repeated at the
+// top of every chunk. It relies on every output record being one line,
+// which holds because newlines inside fields are escaped or removed: keeping
+// them is not accepted with -split-rows and -split-bytes.
+type splitWriter struct {
+	open      func(i int) (*output, error)
+	maxRows   int
+	maxBytes  int64
+	hasHeader bool
+
+	header  []byte
+	partial []byte
+	cur     *output
+	rows    int
+	bytes   int64
+	chunks  int
+}
+
+// siblingName inserts tag before the extension of base, so out.csv
+// becomes out.tag.csv and out.csv.gz becomes out.tag.csv.gz.
+func siblingName(base, tag string) string {
+	gz := ""
+	if strings.HasSuffix(base, ".gz") {
+		base, gz = strings.TrimSuffix(base, ".gz"), ".gz"
+	}
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + tag + ext + gz
+}
+
+// chunkName returns the name of the i-th chunk of base.
+func chunkName(base string, i int) string {
+	return siblingName(base, fmt.Sprintf("%03d", i))
+}
+
+func (s *splitWriter) Write(p []byte) (int, error) {
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := s.partial[:i+1]
+		if err := s.writeLine(line); err != nil {
+			return 0, err
+		}
+		s.partial = s.partial[i+1:]
+	}
+}
+
+func (s *splitWriter) writeLine(line []byte) error {
+	if s.hasHeader && s.header == nil {
+		s.header = append([]byte{}, line...)
+		return nil
+	}
+
+	if s.cur != nil && s.rows > 0 &&
+		(s.maxRows > 0 && s.rows >= s.maxRows ||
+			s.maxBytes > 0 && s.bytes+int64(len(line)) > s.maxBytes) {
+		if err := s.cur.Close(); err != nil {
+			return err
+		}
+		s.cur = nil
+	}
+	if s.cur == nil {
+		if err := s.next(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.cur.Write(line); err != nil {
+		return err
+	}
+	s.rows++
+	s.bytes += int64(len(line))
+	return nil
+}
+
+// next opens the following chunk and writes the header to it.
+func (s *splitWriter) next() error {
+	o, err := s.open(s.chunks)
+	if err != nil {
+		return err
+	}
+	s.cur, s.rows, s.bytes = o, 0, 0
+	s.chunks++
+	if s.header != nil {
+		if _, err := o.Write(s.header); err != nil {
+			return err
+		}
+		s.bytes += int64(len(s.header))
+	}
+	return nil
+}
+
+// Close writes any unterminated last line and closes the current chunk. An
+// input without data rows still produces one chunk.
+func (s *splitWriter) Close() error {
+	if len(s.partial) > 0 {
+		line := s.partial
+		s.partial = nil
+		if err := s.writeLine(line); err != nil {
+			return err
+		}
+	}
+	if s.chunks == 0 {
+		if err := s.next(); err != nil {
+			return err
+		}
+	}
+	if s.cur == nil {
+		return nil
+	}
+	err := s.cur.Close()
+	s.cur = nil
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_splitRowsFlag(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.csv")
+	inStream := strings.NewReader("h\n1\n2\n3\n4\n5\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal", "-split-rows", "2", "-o", out}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	expected := []string{"h\n1\n2\n", "h\n3\n4\n", "h\n5\n"}
+	for i, e := range expected {
+		b, err := os.ReadFile(chunkName(out, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != e {
+			t.Errorf("chunk %d: expected %q to eq %q", i, b, e)
+		}
+	}
+	if _, err := os.Stat(chunkName(out, len(expected))); !os.IsNotExist(err) {
+		t.Errorf("expected only %d chunks", len(expected))
+	}
+
+	if !strings.Contains(errStream.String(), "chunks written: 3") {
+		t.Errorf("expected %q to report the chunk count", errStream.String())
+	}
+}
+
+func TestRun_splitBytesFlag(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.csv")
+	inStream := strings.NewReader("aaaa\nbbbb\ncccc\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: inStream, outStream: outStream, errStream: errStream}
+	args := []string{"./csvlint", "-quote", "minimal", "-no-header", "-split-bytes", "10", "-o", out}
+
+	status := cli.Run(args)
+	if status != ExitCodeOK {
+		t.Fatalf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+
+	for i, e := range []string{"aaaa\nbbbb\n", "cccc\n"} {
+		b, err := os.ReadFile(chunkName(out, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != e {
+			t.Errorf("chunk %d: expected %q to eq %q", i, b, e)
+		}
+	}
+}
+
+func TestChunkName(t *testing.T) {
+	tests := map[string]string{
+		"out.csv":      "out.007.csv",
+		"out.csv.gz":   "out.007.csv.gz",
+		"dir/out":      "dir/out.007",
+		"a.b/data.tsv": "a.b/data.007.tsv",
+	}
+	for base, expected := range tests {
+		if name := chunkName(base, 7); name != expected {
+			t.Errorf("expected %q to eq %q", name, expected)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":   512,
+		"64K":   64 << 10,
+		"10MB":  10 << 20,
+		"1GiB":  1 << 30,
+		"2 gb ": 2 << 30,
+	}
+	for s, expected := range tests {
+		n, err := parseSize(s)
+		if err != nil || n != expected {
+			t.Errorf("parseSize(%q) = %d, %v; expected %d", s, n, err, expected)
+		}
+	}
+	if _, err := parseSize("ten"); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitSpec is one -split: a column whose fields are split into new
+// columns of the given names.
+type splitSpec struct {
+	column string
+	names  []string
+	spec   string
+}
+
+// splitsValue collects repeatable col=a,b,c flags.
+type splitsValue []splitSpec
+
+func (s *splitsValue) String() string {
+	var specs []string
+	for _, sp := range *s {
+		specs = append(specs, sp.spec)
+	}
+	return strings.Join(specs, " ")
+}
+
+func (s *splitsValue) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i <= 0 || i == len(v)-1 {
+		return fmt.Errorf("expected col=a,b,c, got %q", v)
+	}
+	sp := splitSpec{column: v[:i], names: strings.Split(v[i+1:], ","), spec: v}
+	for _, name := range sp.names {
+		if name == "" {
+			return fmt.Errorf("invalid -split %q: empty column name", v)
+		}
+	}
+	*s = append(*s, sp)
+	return nil
+}
+
+// defaultSplitSep is the -split-sep used when none is given: the other of
+// a comma and a semicolon from the input delimiter.
+func defaultSplitSep(comma rune) string {
+	if comma == 0 || comma == ',' {
+		return ";"
+	}
+	return ","
+}
+
+// boundSplit is a -split resolved in the header it applies to.
+type boundSplit struct {
+	source int
+	column string
+	names  []string
+	keep   bool
+	// width is that of the header, to which short rows are padded so
+	// that the new columns line up. It is 0 without a header.
+	width int
+}
+
+// bindSplits resolves the splits one after the other, each in the header
+// left by those before it, and returns that of the last one. Without a
+// header the columns are numbers and the returned header is nil.
+func bindSplits(splits []splitSpec, header []string, noHeader, keep bool) ([]boundSplit, []string, error) {
+	bound := make([]boundSplit, len(splits))
+	for i, sp := range splits {
+		n, err := columnIndex(sp.column, headerIndex(header), noHeader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("-split: %s", err)
+		}
+		b := boundSplit{source: n, column: sp.column, names: sp.names, keep: keep, width: len(header)}
+		if !noHeader {
+			header, _ = b.apply(header, "", true)
+			for _, name := range sp.names {
+				count := 0
+				for _, h := range header {
+					if h == name {
+						count++
+					}
+				}
+				if count > 1 {
+					return nil, nil, fmt.Errorf("-split: the header already has a column %q", name)
+				}
+			}
+		}
+		bound[i] = b
+	}
+	return bound, header, nil
+}
+
+// apply returns record with the source replaced by its parts split on sep,
+// or followed by them when it is kept, and the names in the header. Parts
+// missing are empty; when there are more parts than names, the last name
+// gets the rest, and apply returns their number.
+func (b boundSplit) apply(record []string, sep string, isHeader bool) ([]string, int) {
+	for len(record) < b.width || len(record) <= b.source {
+		record = append(record, "")
+	}
+	parts, extra := b.names, 0
+	if !isHeader {
+		parts = strings.SplitN(record[b.source], sep, len(b.names))
+		if n := len(strings.Split(record[b.source], sep)); n > len(b.names) {
+			extra = n
+		}
+		for len(parts) < len(b.names) {
+			parts = append(parts, "")
+		}
+	}
+	at := b.source
+	if b.keep {
+		at++
+	}
+	out := make([]string, 0, len(record)+len(parts))
+	out = append(out, record[:at]...)
+	out = append(out, parts...)
+	return append(out, record[b.source+1:]...), extra
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_splitFlag(t *testing.T) {
+	input := "id,name,pos\n1,Ada Lovelace,\"51.5,-0.1\"\n2,Alan Mathison Turing\n"
+	tests := []struct {
+		args     []string
+		expected string
+		errors   string
+	}{
+		{
+			[]string{"./csvlint", "-quote", "minimal", "-split", "pos=lat,lng", "-split-sep", ","},
+			"id,name,lat,lng\n1,Ada Lovelace,51.5,-0.1\n2,Alan Mathison Turing,,\n", "",
+		},
+		{
+			[]string{"./csvlint", "-quote", "minimal", "-split", "name=first,last", "-split-sep", " ", "-split-keep"},
+			"id,name,first,last,pos\n1,Ada Lovelace,Ada,Lovelace,\"51.5,-0.1\"\n2,Alan Mathison Turing,Alan,Mathison Turing,\n",
+			"line 3: name: 3 parts for 2 columns, the last one holds the rest\n",
+		},
+		{
+			[]string{"./csvlint", "-quote", "minimal", "-split", "name=first,last", "-split-sep", " ", "-merge", "last,first=key", "-merge-sep", ",", "-merge-drop"},
+			"id,pos,key\n1,\"51.5,-0.1\",\"Lovelace,Ada\"\n2,,\"Mathison Turing,Alan\"\n",
+			"line 3: name: 3 parts for 2 columns, the last one holds the rest\n",
+		},
+		{
+			[]string{"./csvlint", "-quote", "minimal", "-no-header", "-split", "3=a,b,c", "-split-sep", "."},
+			"id,name,pos,,\n1,Ada Lovelace,51,\"5,-0\",1\n2,Alan Mathison Turing,,,\n", "",
+		},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(tt.args); status != ExitCodeOK {
+			t.Errorf("%s: expected %d to eq %d: %s", tt.args, status, ExitCodeOK, errStream.String())
+		}
+		if outStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, outStream.String(), tt.expected)
+		}
+		if errStream.String() != tt.errors {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.errors)
+		}
+	}
+}
+
+func TestRun_splitFlagErrors(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"./csvlint -split name=id,last", "-split: the header already has a column \"id\"\n"},
+		{"./csvlint -split nick=a,b", "-split: unknown column \"nick\"\n"},
+		{"./csvlint -split-keep", "-split-sep and -split-keep need -split\n"},
+	}
+	for _, tt := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader("id,name\n1,Ada Lovelace\n"), outStream: outStream, errStream: errStream}
+
+		if status := cli.Run(strings.Split(tt.args, " ")); status != ExitCodeError {
+			t.Errorf("%s: expected %d to eq %d", tt.args, status, ExitCodeError)
+		}
+		if errStream.String() != tt.expected {
+			t.Errorf("%s: expected %q to eq %q", tt.args, errStream.String(), tt.expected)
+		}
+	}
+}
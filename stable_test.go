@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// The outputs built from maps are run many times, so that an order left to
+// map iteration shows up as a difference between runs.
+func TestRun_stableOutput(t *testing.T) {
+	input := "id,c,j\n1,x,\"{\"\"b\"\":1,\"\"a\"\":2}\"\n2,y,\"{\"\"c\"\":3}\"\n3,x,\"{\"\"a\"\":4}\"\n4,z,\n5,y,\"{\"\"d\"\":5,\"\"b\"\":6}\"\n"
+	tests := [][]string{
+		{"-values", "c"},
+		{"-values", "c", "-json"},
+		{"-count-by", "c"},
+		{"-explode-json", "j"},
+		{"-fill", "nope=1", "-fill", "other=2", "-fill", "c=3"},
+		{"-report", "json", "-range", "id=2:3", "-field-histogram"},
+		{"-report", "sarif", "-range", "id=2:3", "-field-histogram"},
+		{"-range", "id=2:3", "-field-histogram", "-pad", "-fill", "c=-"},
+	}
+	for _, args := range tests {
+		args = append([]string{"./csvlint", "-quote", "minimal"}, args...)
+		var first string
+		for i := 0; i < 20; i++ {
+			outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+			cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+			cli.Run(args)
+			got := outStream.String() + "\x00" + errStream.String()
+			if i == 0 {
+				first = got
+			} else if got != first {
+				t.Errorf("%v: run %d gave %q, the first %q", args, i+1, got, first)
+				break
+			}
+		}
+	}
+}
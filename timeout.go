@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// errStdinTimeout is returned by transform when stdin gives no data for
+// -stdin-timeout, which Run exits on with ExitCodeTimeout.
+var errStdinTimeout = errors.New("stdin timed out")
+
+// timeoutReader fails a read that waits longer than timeout, as when the
+// program writing to stdin is stuck. Each read runs in a goroutine that is
+// abandoned when it times out; the program exits soon after.
+type timeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	buf     []byte
+	result  chan readResult
+	err     error
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// newTimeoutReader returns r with reads bounded by timeout, unless r is a
+// regular file, which cannot stall.
+func newTimeoutReader(r io.Reader, timeout time.Duration) io.Reader {
+	if f, ok := r.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			return r
+		}
+	}
+	return &timeoutReader{r: r, timeout: timeout, result: make(chan readResult, 1)}
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	// p is not handed to the goroutine, which may outlive the call
+	if cap(t.buf) < len(p) {
+		t.buf = make([]byte, len(p))
+	}
+	buf := t.buf[:len(p)]
+	go func() {
+		n, err := t.r.Read(buf)
+		t.result <- readResult{n, err}
+	}()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	select {
+	case res := <-t.result:
+		return copy(p, buf[:res.n]), res.err
+	case <-timer.C:
+		t.err = errStdinTimeout
+		return 0, t.err
+	}
+}
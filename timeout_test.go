@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRun_stdinTimeoutFlag(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	go io.WriteString(w, "id\n1\n")
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: r, outStream: outStream, errStream: errStream}
+	status := cli.Run([]string{"./csvlint", "-quote", "minimal", "-stdin-timeout", "50ms"})
+	if status != ExitCodeTimeout {
+		t.Errorf("expected %d to eq %d", status, ExitCodeTimeout)
+	}
+	if expected := "id\n1\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	if expected := "no input on stdin for 50ms (-stdin-timeout)\n"; errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_stdinTimeoutDone(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, "id\n1\n")
+		w.Close()
+	}()
+
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: r, outStream: outStream, errStream: errStream}
+	if status := cli.Run([]string{"./csvlint", "-quote", "minimal", "-stdin-timeout", "10s"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream)
+	}
+	if expected := "id\n1\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
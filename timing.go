@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// byteCounter counts the bytes read from every input, for -timing.
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) wrap(r io.Reader) io.Reader {
+	return &countingReader{r: r, c: c}
+}
+
+type countingReader struct {
+	r io.Reader
+	c *byteCounter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(&r.c.n, int64(n))
+	return n, err
+}
+
+// throughput describes a run that read bytes and wrote records in elapsed.
+func throughput(bytes int64, records int, elapsed time.Duration) string {
+	secs := elapsed.Seconds()
+	if secs <= 0 {
+		secs = 1e-9
+	}
+	mb := float64(bytes) / 1e6
+	return fmt.Sprintf("%d records, %.1f MB in %s: %.1f MB/s, %.0f records/s",
+		records, mb, elapsed.Round(time.Millisecond), mb/secs, float64(records)/secs)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_timingFlag(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader("a,b\n1,2\n3,4\n"), outStream: outStream, errStream: errStream}
+
+	if status := cli.Run([]string{"./csvlint", "-timing"}); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d", status, ExitCodeOK)
+	}
+	re := regexp.MustCompile(`^3 records, 0\.0 MB in \S+: [0-9.]+ MB/s, [0-9]+ records/s\n$`)
+	if !re.MatchString(errStream.String()) {
+		t.Errorf("expected %q to match %s", errStream.String(), re)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	expected := "1000 records, 2.5 MB in 2s: 1.2 MB/s, 500 records/s"
+	if s := throughput(2500000, 1000, 2*time.Second); s != expected {
+		t.Errorf("expected %q to eq %q", s, expected)
+	}
+}
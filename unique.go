@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// keySet holds the -unique-key keys seen so far with where their first row
+// was read. It is shared by all the inputs, so that the constraint holds
+// on the output they are written to together.
+type keySet struct {
+	mu   sync.Mutex
+	seen map[string]rowOrigin
+}
+
+func newKeySet() *keySet {
+	return &keySet{seen: map[string]rowOrigin{}}
+}
+
+// uniqueKeys checks the -unique-key constraint on the rows of an input: no
+// two data rows may have the same fields in all the key columns. Every key
+// is kept once, so memory grows with the number of rows.
+type uniqueKeys struct {
+	columns []string
+	indices []int
+	keys    *keySet
+	memory  *memoryLimit
+}
+
+func newUniqueKeys(columns []string, keys *keySet, memory *memoryLimit) *uniqueKeys {
+	return &uniqueKeys{columns: columns, keys: keys, memory: memory}
+}
+
+// bind resolves the key columns in header.
+func (u *uniqueKeys) bind(header []string, noHeader bool) error {
+	var err error
+	u.indices, err = columnIndices("-unique-key", u.columns, header, noHeader)
+	return err
+}
+
+// check reports record when a row before it, in this input or an earlier
+// one, has the same key, with where that first row was read.
+func (u *uniqueKeys) check(name string, record []string, reader recordReader, diag *diagnostics) error {
+	values := project(record, u.indices)
+	key := compositeKey(values)
+	line, _ := reader.FieldPos(0)
+
+	u.keys.mu.Lock()
+	first, ok := u.keys.seen[key]
+	if !ok {
+		u.keys.seen[key] = rowOrigin{name, line}
+	}
+	u.keys.mu.Unlock()
+	if !ok {
+		return u.memory.grow("-unique-key", int64(len(key))+fieldOverhead)
+	}
+
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%s=%q", u.columns[i], v)
+	}
+	where := fmt.Sprintf("line %d", first.line)
+	if first.name != name {
+		where += " of " + first.name
+	}
+	diag.report(Diagnostic{File: name, Line: line, Rule: "unique-key", Message: fmt.Sprintf("duplicate key %s, first on %s", strings.Join(parts, ", "), where)})
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_uniqueKeyFlag(t *testing.T) {
+	input := "id,region,name\n1,eu,Ada\n2,eu,Bob\n1,us,Cy\n1,eu,Dee\n2,eu,Eve\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+		errors   string
+	}{
+		{"./csvlint -quote minimal -unique-key id", ExitCodeOK, input, "line 4: duplicate key id=\"1\", first on line 2\nline 5: duplicate key id=\"1\", first on line 2\nline 6: duplicate key id=\"2\", first on line 3\n"},
+		{"./csvlint -quote minimal -unique-key id,region", ExitCodeOK, input, "line 5: duplicate key id=\"1\", region=\"eu\", first on line 2\nline 6: duplicate key id=\"2\", region=\"eu\", first on line 3\n"},
+		{"./csvlint -quote minimal -unique-key 1,2 -no-header", ExitCodeOK, input, "line 5: duplicate key 1=\"1\", 2=\"eu\", first on line 2\nline 6: duplicate key 1=\"2\", 2=\"eu\", first on line 3\n"},
+		{"./csvlint -quote minimal -unique-key region,name", ExitCodeOK, input, ""},
+		{"./csvlint -quote minimal -unique-key id,region -strict", ExitCodeError, input, "line 5: duplicate key id=\"1\", region=\"eu\", first on line 2\nline 6: duplicate key id=\"2\", region=\"eu\", first on line 3\n"},
+		{"./csvlint -unique-key code", ExitCodeError, "", "-unique-key: unknown column \"code\"\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%s: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}
+
+func TestRun_uniqueKeyFiles(t *testing.T) {
+	files := writeFiles(t, "id,name\n1,Ada\n2,Bob\n", "id,name\n3,Cy\n1,Dee\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+
+	args := []string{"./csvlint", "-quote", "minimal", "-unique-key", "id", "-strict", files[0], files[1]}
+	if status := cli.Run(args); status != ExitCodeError {
+		t.Errorf("expected %d to eq %d", status, ExitCodeError)
+	}
+	expected := files[1] + ": line 3: duplicate key id=\"1\", first on line 2 of " + files[0] + "\n"
+	if errStream.String() != expected {
+		t.Errorf("expected %q to eq %q", errStream.String(), expected)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlWriter writes rows as items of a YAML sequence of mappings keyed by
+// the header. Every row is encoded on its own, so nothing is held back,
+// unless typed is set.
+type yamlWriter struct {
+	keys []string
+	// arrays, set by -array-dupes, writes the fields of the columns
+	// sharing a name as a sequence under the one key.
+	arrays bool
+	groups [][]int
+	// typed, set by -yaml-types, holds the rows back until the type of
+	// every column is known.
+	typed *yamlTypes
+}
+
+// yaml11Scalar matches the plain scalars that YAML 1.1 parsers, still
+// common, read as booleans or base 60 numbers although YAML 1.2 does not.
+var yaml11Scalar = regexp.MustCompile(`^(?:y|Y|yes|Yes|YES|n|N|no|No|NO|on|On|ON|off|Off|OFF|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+(?:\.[0-9_]*)?)$`)
+
+func (y *yamlWriter) write(w io.Writer, name string, record []string, isHeader bool, line int, diag *diagnostics) error {
+	if isHeader {
+		if y.keys == nil {
+			y.keys = append([]string(nil), record...)
+			if y.arrays {
+				y.groups = groupColumns(y.keys)
+			}
+		}
+		return nil
+	}
+	if y.typed != nil {
+		return y.typed.add(name, record, line, y.keys, diag)
+	}
+	return y.encode(w, record)
+}
+
+// flush writes the rows held back by -yaml-types.
+func (y *yamlWriter) flush(w io.Writer) error {
+	if y.typed == nil {
+		return nil
+	}
+	for _, record := range y.typed.rows {
+		if err := y.encode(w, record); err != nil {
+			return err
+		}
+	}
+	y.typed.rows = nil
+	return nil
+}
+
+func (y *yamlWriter) encode(w io.Writer, record []string) error {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	if y.groups != nil {
+		for _, group := range y.groups {
+			var values []*yaml.Node
+			for _, i := range group {
+				if i < len(record) {
+					values = append(values, y.scalar(i, record[i]))
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			value := values[0]
+			if len(group) > 1 {
+				value = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: values}
+			}
+			m.Content = append(m.Content, yamlString(y.keys[group[0]]), value)
+		}
+	}
+	for i, v := range record {
+		key := strconv.Itoa(i + 1)
+		if i < len(y.keys) {
+			if y.groups != nil {
+				continue
+			}
+			key = y.keys[i]
+		}
+		m.Content = append(m.Content, yamlString(key), y.scalar(i, v))
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{m}}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// scalar returns the node of v, the field of column i.
+func (y *yamlWriter) scalar(i int, v string) *yaml.Node {
+	if y.typed == nil {
+		return yamlString(v)
+	}
+	return y.typed.scalar(i, v)
+}
+
+// yamlString returns a string scalar, which the encoder quotes when it
+// would otherwise read as another type.
+func yamlString(v string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	if yaml11Scalar.MatchString(v) {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
+
+// groupColumns returns the positions of the columns by name, in the order
+// the names first appear in header.
+func groupColumns(header []string) [][]int {
+	var groups [][]int
+	index := map[string]int{}
+	for i, name := range header {
+		if g, ok := index[name]; ok {
+			groups[g] = append(groups[g], i)
+			continue
+		}
+		index[name] = len(groups)
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
+
+// Types of -yaml-types, from the most to the least specific.
+const (
+	YAMLInt    = "int"
+	YAMLFloat  = "float"
+	YAMLBool   = "bool"
+	YAMLString = "string"
+)
+
+var yamlKinds = []string{YAMLInt, YAMLFloat, YAMLBool, YAMLString}
+
+// fitsYAMLKind reports whether v, which is not null, is a value of kind:
+// a value -cast reads that is written as JSON writes it, so that values
+// such as 007, +1 or yes, which a loader would not give back as they are,
+// stay strings.
+func fitsYAMLKind(v, kind string) bool {
+	switch kind {
+	case YAMLInt:
+		_, ok := parseInt(v)
+		return ok && isJSONNumber(v)
+	case YAMLFloat:
+		_, ok := parseFloat(v)
+		return ok && isJSONNumber(v)
+	case YAMLBool:
+		b, ok := parseBool(v)
+		return ok && v == strconv.FormatBool(b)
+	}
+	return true
+}
+
+// isJSONNumber reports whether v is a number literal of JSON.
+func isJSONNumber(v string) bool {
+	return v != "" && (v[0] == '-' || v[0] >= '0' && v[0] <= '9') && json.Valid([]byte(v))
+}
+
+// yamlTypes keeps the rows of -yaml-types and the kinds every column can
+// still be. A column is written as numbers or booleans only when all its
+// values are of that kind, or when -yaml-col-type says so; empty fields
+// and those equal to the null token are written as null.
+type yamlTypes struct {
+	nullToken string
+	// columns are the -yaml-col-type kinds by column, and forced the
+	// same bound in the header.
+	columns  map[string]string
+	noHeader bool
+	forced   map[int]string
+	// fits holds, per column, whether all its values so far fit each of
+	// yamlKinds.
+	fits   [][]bool
+	rows   [][]string
+	memory *memoryLimit
+}
+
+func (t *yamlTypes) null(v string) bool {
+	return v == "" || t.nullToken != "" && v == t.nullToken
+}
+
+func (t *yamlTypes) bind(header []string) error {
+	index := headerIndex(header)
+	t.forced = map[int]string{}
+	for col, kind := range t.columns {
+		n, err := columnIndex(col, index, t.noHeader)
+		if err != nil {
+			return fmt.Errorf("-yaml-col-type: %s", err)
+		}
+		t.forced[n] = kind
+	}
+	return nil
+}
+
+func (t *yamlTypes) add(name string, record []string, line int, header []string, diag *diagnostics) error {
+	if t.forced == nil {
+		if err := t.bind(header); err != nil {
+			return err
+		}
+	}
+	for len(t.fits) < len(record) {
+		t.fits = append(t.fits, []bool{true, true, true, true})
+	}
+	for i, v := range record {
+		if t.null(v) {
+			continue
+		}
+		if kind, ok := t.forced[i]; ok {
+			if !fitsYAMLKind(v, kind) {
+				col := strconv.Itoa(i + 1)
+				if i < len(header) {
+					col = header[i]
+				}
+				diag.report(Diagnostic{File: name, Line: line, Rule: "yaml-types", Message: fmt.Sprintf("%s: %q is not a valid %s, written as a string", col, v, kind)})
+			}
+			continue
+		}
+		for k, kind := range yamlKinds {
+			t.fits[i][k] = t.fits[i][k] && fitsYAMLKind(v, kind)
+		}
+	}
+	t.rows = append(t.rows, append([]string(nil), record...))
+	return t.memory.grow("-yaml-types", recordSize(record))
+}
+
+// kind returns the type column i is written as.
+func (t *yamlTypes) kind(i int) string {
+	if kind, ok := t.forced[i]; ok {
+		return kind
+	}
+	if i < len(t.fits) {
+		for k, kind := range yamlKinds {
+			if t.fits[i][k] {
+				return kind
+			}
+		}
+	}
+	return YAMLString
+}
+
+func (t *yamlTypes) scalar(i int, v string) *yaml.Node {
+	if t.null(v) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	kind := t.kind(i)
+	if kind == YAMLString || !fitsYAMLKind(v, kind) {
+		return yamlString(v)
+	}
+	if kind == YAMLFloat && fitsYAMLKind(v, YAMLInt) {
+		// the same number, without the explicit tag a float 2 needs
+		kind = YAMLInt
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!" + kind, Value: v}
+}
+
+// validYAMLKind reports whether kind is accepted by -yaml-col-type.
+func validYAMLKind(kind string) bool {
+	for _, k := range yamlKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
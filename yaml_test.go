@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun_yamlFlag(t *testing.T) {
+	input := "id,flag,note\n012345,yes,plain\n1.5,1:20,\"a: b\"\n7,,x,extra\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{
+			"./csvlint -yaml",
+			ExitCodeOK,
+			"- id: \"012345\"\n  flag: \"yes\"\n  note: plain\n- id: \"1.5\"\n  flag: \"1:20\"\n  note: 'a: b'\n- id: \"7\"\n  flag: \"\"\n  note: x\n  \"4\": extra\n",
+		},
+		{
+			"./csvlint -yaml -skip-header -select note:n",
+			ExitCodeOK,
+			"- \"n\": plain\n- \"n\": 'a: b'\n- \"n\": x\n",
+		},
+		{
+			"./csvlint -yaml -no-header -rows 1",
+			ExitCodeOK,
+			"- \"1\": id\n  \"2\": flag\n  \"3\": note\n",
+		},
+		{"./csvlint -yaml -tsv", ExitCodeError, ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d: %s", test.args, status, test.status, errStream.String())
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+	}
+}
+
+func TestRun_yamlFlag_files(t *testing.T) {
+	files := writeFiles(t, "id\nyes\n", "id\nno\n")
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{outStream: outStream, errStream: errStream}
+
+	if status := cli.Run(append([]string{"./csvlint", "-yaml"}, files...)); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	if expected := "- id: \"yes\"\n- id: \"no\"\n"; outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+}
+
+func TestRun_arrayDupesFlag(t *testing.T) {
+	input := "id,tag,name,tag\n1,a,x,b\n2,yes\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{"./csvlint -yaml -array-dupes", ExitCodeOK, "- id: \"1\"\n  tag: [a, b]\n  name: x\n- id: \"2\"\n  tag: [\"yes\"]\n"},
+		{"./csvlint -yaml -array-dupes -select id,name:tag,tag", ExitCodeOK, "- id: \"1\"\n  tag: [x, a]\n- id: \"2\"\n  tag: [\"\", \"yes\"]\n"},
+		{"./csvlint -yaml -array-dupes -no-header -rows 1", ExitCodeOK, "- \"1\": id\n  \"2\": tag\n  \"3\": name\n  \"4\": tag\n"},
+		{"./csvlint -array-dupes", ExitCodeError, ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d: %s", test.args, status, test.status, errStream.String())
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+	}
+}
+
+func TestRun_yamlTypesFlag(t *testing.T) {
+	input := "id,zip,price,ok,note\n1,007,1.5,true,\n2,10001,2,false,NA\n3,,-1e3,,x\n"
+	tests := []struct {
+		args     string
+		status   int
+		expected string
+	}{
+		{
+			"./csvlint -yaml -yaml-types",
+			ExitCodeOK,
+			"- id: 1\n  zip: \"007\"\n  price: 1.5\n  ok: true\n  note: null\n" +
+				"- id: 2\n  zip: \"10001\"\n  price: 2\n  ok: false\n  note: NA\n" +
+				"- id: 3\n  zip: null\n  price: -1e3\n  ok: null\n  note: x\n",
+		},
+		{
+			"./csvlint -yaml -yaml-types -null-token NA -yaml-col-type id=string -rows 1-2",
+			ExitCodeOK,
+			"- id: \"1\"\n  zip: \"007\"\n  price: 1.5\n  ok: true\n  note: null\n" +
+				"- id: \"2\"\n  zip: \"10001\"\n  price: 2\n  ok: false\n  note: null\n",
+		},
+		{"./csvlint -yaml -yaml-types -yaml-col-type nope=int", ExitCodeError, ""},
+		{"./csvlint -yaml -yaml-types -yaml-col-type id=date", ExitCodeError, ""},
+		{"./csvlint -yaml -yaml-col-type id=int", ExitCodeError, ""},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(input), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(strings.Split(test.args, " "))
+		if status != test.status {
+			t.Errorf("%s: expected %d to eq %d: %s", test.args, status, test.status, errStream.String())
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%s: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+	}
+}
+
+func TestRun_yamlColTypeFlag_invalid(t *testing.T) {
+	outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+	cli := &CLI{inStream: strings.NewReader("zip\n007\n10001\n"), outStream: outStream, errStream: errStream}
+
+	if status := cli.Run(strings.Split("./csvlint -yaml -yaml-types -yaml-col-type zip=int", " ")); status != ExitCodeOK {
+		t.Errorf("expected %d to eq %d: %s", status, ExitCodeOK, errStream.String())
+	}
+	expected := "- zip: \"007\"\n- zip: 10001\n"
+	if outStream.String() != expected {
+		t.Errorf("expected %q to eq %q", outStream.String(), expected)
+	}
+	errors := "line 2: zip: \"007\" is not a valid int, written as a string\n"
+	if errStream.String() != errors {
+		t.Errorf("expected %q to eq %q", errStream.String(), errors)
+	}
+}
+
+func TestFitsYAMLKind(t *testing.T) {
+	tests := []struct {
+		value    string
+		kind     string
+		expected bool
+	}{
+		{"-12", YAMLInt, true},
+		{"007", YAMLInt, false},
+		{"+1", YAMLInt, false},
+		{"1.5", YAMLInt, false},
+		{"1.5e3", YAMLFloat, true},
+		{".5", YAMLFloat, false},
+		{"NaN", YAMLFloat, false},
+		{"true", YAMLBool, true},
+		{"True", YAMLBool, false},
+		{"yes", YAMLBool, false},
+	}
+	for _, test := range tests {
+		if got := fitsYAMLKind(test.value, test.kind); got != test.expected {
+			t.Errorf("%q %s: expected %v to eq %v", test.value, test.kind, got, test.expected)
+		}
+	}
+}
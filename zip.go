@@ -0,0 +1,74 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// isZip reports whether the input file name is a zip archive, whose entry
+// is read instead of the file itself.
+func isZip(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
+
+// zipEntry is an entry of a zip archive being read, which closes the
+// archive along with the entry.
+type zipEntry struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (e *zipEntry) Close() error {
+	err := e.ReadCloser.Close()
+	if cerr := e.archive.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// openZipEntry opens the named entry of the zip archive, or its only file
+// when entry is empty. Directories and the __MACOSX metadata added by
+// macOS are not counted.
+func openZipEntry(name, entry string) (io.ReadCloser, error) {
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*zip.File
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		if entry == "" || f.Name == entry {
+			files = append(files, f)
+		}
+	}
+
+	switch {
+	case len(files) == 0 && entry != "":
+		archive.Close()
+		return nil, fmt.Errorf("zip archive has no entry %q", entry)
+	case len(files) == 0:
+		archive.Close()
+		return nil, errors.New("zip archive has no entries")
+	case len(files) > 1:
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		archive.Close()
+		return nil, fmt.Errorf("zip archive has %d entries, choose one with -zip-entry: %s", len(files), strings.Join(names, ", "))
+	}
+
+	r, err := files[0].Open()
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+	return &zipEntry{ReadCloser: r, archive: archive}, nil
+}
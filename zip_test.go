@@ -0,0 +1,72 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip writes an archive of the given entries, in order, and returns
+// its name.
+func writeZip(t *testing.T, entries ...string) string {
+	name := filepath.Join(t.TempDir(), "export.zip")
+	fp, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(fp)
+	for i := 0; i < len(entries); i += 2 {
+		w, err := zw.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entries[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestRun_zipEntryFlag(t *testing.T) {
+	single := writeZip(t, "data/", "", "data/a.csv", "id\n1\n", "__MACOSX/data/._a.csv", "\x00")
+	multiple := writeZip(t, "a.csv", "id\n1\n", "b.csv", "id\n2\n")
+	plain := writeFiles(t, "id\n3\n")[0]
+
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+		errors   string
+	}{
+		{[]string{single}, ExitCodeOK, "id\n1\n", ""},
+		{[]string{"-zip-entry", "b.csv", multiple, plain}, ExitCodeOK, "id\n2\n3\n", ""},
+		{[]string{multiple}, ExitCodeError, "", multiple + ": zip archive has 2 entries, choose one with -zip-entry: a.csv, b.csv\n"},
+		{[]string{"-zip-entry", "c.csv", multiple}, ExitCodeError, "", multiple + ": zip archive has no entry \"c.csv\"\n"},
+		{[]string{"-zip-entry", "a.csv", plain}, ExitCodeError, "", "-zip-entry needs an input file ending in .zip\n"},
+		{[]string{"-in-place", single}, ExitCodeError, "", "-in-place cannot rewrite an entry of a zip archive\n"},
+	}
+	for _, test := range tests {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		cli := &CLI{inStream: strings.NewReader(""), outStream: outStream, errStream: errStream}
+
+		status := cli.Run(append([]string{"./csvlint", "-quote", "minimal"}, test.args...))
+		if status != test.status {
+			t.Errorf("%v: expected %d to eq %d", test.args, status, test.status)
+		}
+		if outStream.String() != test.expected {
+			t.Errorf("%v: expected %q to eq %q", test.args, outStream.String(), test.expected)
+		}
+		if errStream.String() != test.errors {
+			t.Errorf("%v: expected %q to eq %q", test.args, errStream.String(), test.errors)
+		}
+	}
+}